@@ -0,0 +1,460 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+const (
+	applyDesc = `
+'apply' command creates or updates the NineClusters in a file of the cluster specs or the NineCluster manifests.
+A cluster spec is a concise document of kind Cluster with the same fields as the create flags,
+the documents of kind NineCluster are applied as they are.`
+	applyExample = `1. Apply the NineClusters in a file
+   $ kubectl nine apply -f clusters.yaml
+
+2. Show the server side diff without applying
+   $ kubectl nine apply -f clusters.yaml --dry-run=server
+
+3. Render the NineCluster objects of the file
+   $ kubectl nine apply -f clusters.yaml --dry-run=client -o yaml
+
+A cluster spec looks like:
+   kind: Cluster
+   name: c1
+   namespace: c1-ns
+   profile: small
+   dataVolume: 64
+   olap: doris
+   olapExecutors: 3
+   components:
+     doris-be:
+       resources:
+         requests:
+           cpu: "4"
+           memory: 16Gi`
+)
+
+const (
+	NineClusterKind = "NineCluster"
+	ClusterSpecKind = "Cluster"
+	DryRunNone      = "none"
+	DryRunClient    = "client"
+	DryRunServer    = "server"
+	OutputYaml      = "yaml"
+	OutputJson      = "json"
+)
+
+var (
+	dryRunSupported = []string{DryRunNone, DryRunClient, DryRunServer}
+	outputSupported = []string{OutputYaml, OutputJson}
+)
+
+// ClusterSpec is the concise spec of a NineCluster,the fields are the same as the create flags
+type ClusterSpec struct {
+	Kind                 string                   `json:"kind,omitempty"`
+	Name                 string                   `json:"name"`
+	Namespace            string                   `json:"namespace"`
+	DataVolume           *int                     `json:"dataVolume,omitempty"`
+	MainStorage          string                   `json:"mainStorage,omitempty"`
+	StoragePool          string                   `json:"storagePool,omitempty"`
+	Olap                 string                   `json:"olap,omitempty"`
+	OlapVolume           *int                     `json:"olapVolume,omitempty"`
+	OlapExecutors        *int32                   `json:"olapExecutors,omitempty"`
+	OlapStoragePool      string                   `json:"olapStoragePool,omitempty"`
+	MetastoreStoragePool string                   `json:"metastoreStoragePool,omitempty"`
+	EnableKyuubiHA       bool                     `json:"enableKyuubiHA,omitempty"`
+	EnablePostgresqlCDC  bool                     `json:"enablePostgresqlCDC,omitempty"`
+	Profile              string                   `json:"profile,omitempty"`
+	Components           map[string]ComponentSpec `json:"components,omitempty"`
+}
+
+type applyCmd struct {
+	out         io.Writer
+	errOut      io.Writer
+	file        string
+	profileFile string
+	dryRun      string
+	output      string
+	force       bool
+}
+
+// ValidateDryRunAndOutput checks the --dry-run and the --output flags
+func ValidateDryRunAndOutput(dryRun string, output string) error {
+	supported := false
+	for _, d := range dryRunSupported {
+		supported = supported || d == dryRun
+	}
+	if !supported {
+		return fmt.Errorf("invalid --dry-run %s,support [%s]", dryRun, strings.Join(dryRunSupported, ","))
+	}
+	if output == "" {
+		return nil
+	}
+	if output != OutputYaml && output != OutputJson {
+		return fmt.Errorf("invalid output format %s,support [%s]", output, strings.Join(outputSupported, ","))
+	}
+	if dryRun == DryRunNone {
+		return errors.New("the output format is supported with --dry-run only")
+	}
+	return nil
+}
+
+// ClusterOptions returns the options of the cluster spec with the defaults of the create flags,
+// the profile is taken for the fields not specified
+func (s *ClusterSpec) ClusterOptions(profileFile string) (*ClusterOptions, error) {
+	opts := &ClusterOptions{
+		Name:                 s.Name,
+		NS:                   s.Namespace,
+		DataVolume:           DefaultClusterDataVolume,
+		MainStorage:          FeaturesStorageValueMinio,
+		StoragePool:          s.StoragePool,
+		Olap:                 s.Olap,
+		OlapVolume:           DefaultOlapVolume,
+		OlapExecutors:        DefaultOlapExecutors,
+		OlapStoragePool:      s.OlapStoragePool,
+		MetastoreStoragePool: s.MetastoreStoragePool,
+		EnableKyuubiHA:       s.EnableKyuubiHA,
+		EnablePostgresqlCDC:  s.EnablePostgresqlCDC,
+		ProfileName:          s.Profile,
+		ProfileFile:          profileFile,
+	}
+	if s.MainStorage != "" {
+		opts.MainStorage = s.MainStorage
+	}
+	changed := make(map[string]bool)
+	if s.DataVolume != nil {
+		opts.DataVolume = *s.DataVolume
+		changed["data-volume"] = true
+	}
+	if s.OlapVolume != nil {
+		opts.OlapVolume = *s.OlapVolume
+		changed["olap-volume"] = true
+	}
+	if s.OlapExecutors != nil {
+		opts.OlapExecutors = *s.OlapExecutors
+		changed["olap-executors"] = true
+	}
+	if err := CheckValidClusterName(opts.Name); err != nil {
+		return nil, err
+	}
+	if opts.NS == "" {
+		return nil, fmt.Errorf("namespace of the ninecluster %s is required", opts.Name)
+	}
+	if err := opts.ApplyProfile(changed); err != nil {
+		return nil, err
+	}
+	if len(s.Components) != 0 {
+		opts.ComponentSpecs = make(map[string]*ComponentSpec)
+		for component, spec := range s.Components {
+			cs := spec
+			opts.ComponentSpecs[component] = &cs
+		}
+		if err := ValidateComponentSpecs(opts.ComponentSpecs); err != nil {
+			return nil, err
+		}
+	}
+	return opts, opts.Validate()
+}
+
+// ValidateNineCluster checks the NineCluster manifest as the create command does for its flags
+func ValidateNineCluster(nc *nineinfrav1alpha1.NineCluster) error {
+	if err := CheckValidClusterName(nc.Name); err != nil {
+		return err
+	}
+	if nc.Namespace == "" {
+		return fmt.Errorf("namespace of the ninecluster %s is required", nc.Name)
+	}
+	if nc.Spec.DataVolume <= 0 {
+		return fmt.Errorf("invalid dataVolume %d of the ninecluster %s", nc.Spec.DataVolume, nc.Name)
+	}
+	if olap, ok := nc.Spec.Features[FeaturesOlapKey]; ok && !strings.Contains(OlapsSupported, olap) {
+		return fmt.Errorf("invalid olap:%s of the ninecluster %s,support [%s]", olap, nc.Name, OlapsSupported)
+	}
+	if storage, ok := nc.Spec.Features[FeaturesStorageKey]; ok && !CheckMainStorageValid(storage) {
+		return fmt.Errorf("main storage %s of the ninecluster %s is not supported,support [%s]", storage, nc.Name, strings.Join(MainStorageSupported, ","))
+	}
+	for _, ci := range nc.Spec.ClusterSet {
+		if ci.Type == "" {
+			return fmt.Errorf("type of a cluster of the ninecluster %s is required", nc.Name)
+		}
+		if ci.Resource.Replicas < 0 {
+			return fmt.Errorf("invalid replicas %d of %s of the ninecluster %s", ci.Resource.Replicas, ci.Type, nc.Name)
+		}
+		if ci.Resource.StorageClass != "" && !CheckStoragePoolValid(ci.Resource.StorageClass) {
+			return fmt.Errorf("storage pool %s of %s may be not exist", ci.Resource.StorageClass, ci.Type)
+		}
+	}
+	return nil
+}
+
+// readFileOrStdin returns the content of the file,- for the stdin
+func readFileOrStdin(file string) ([]byte, error) {
+	if file == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(file)
+}
+
+// LoadNineClusterFile returns the validated NineClusters of the documents in the file,
+// a document is either a cluster spec or a NineCluster manifest
+func LoadNineClusterFile(file string, profileFile string) ([]*nineinfrav1alpha1.NineCluster, error) {
+	data, err := readFileOrStdin(file)
+	if err != nil {
+		return nil, err
+	}
+	var clusters []*nineinfrav1alpha1.NineCluster
+	seen := make(map[string]bool)
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for i := 1; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid document %d of %s,err:%v", i, file, err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		meta := &metav1.TypeMeta{}
+		if err := yaml.Unmarshal(doc, meta); err != nil {
+			return nil, fmt.Errorf("invalid document %d of %s,err:%v", i, file, err)
+		}
+		var nc *nineinfrav1alpha1.NineCluster
+		switch meta.Kind {
+		case NineClusterKind:
+			nc = &nineinfrav1alpha1.NineCluster{}
+			if err := yaml.UnmarshalStrict(doc, nc); err != nil {
+				return nil, fmt.Errorf("invalid NineCluster in document %d of %s,err:%v", i, file, err)
+			}
+			if nc.APIVersion != nineinfrav1alpha1.GroupVersion.String() {
+				return nil, fmt.Errorf("unsupported apiVersion %s of document %d of %s,support [%s]", nc.APIVersion, i, file, nineinfrav1alpha1.GroupVersion.String())
+			}
+			if err := ValidateNineCluster(nc); err != nil {
+				return nil, err
+			}
+		case ClusterSpecKind, "":
+			spec := &ClusterSpec{}
+			if err := yaml.UnmarshalStrict(doc, spec); err != nil {
+				return nil, fmt.Errorf("invalid cluster spec in document %d of %s,err:%v", i, file, err)
+			}
+			opts, err := spec.ClusterOptions(profileFile)
+			if err != nil {
+				return nil, err
+			}
+			nc, err = opts.NineCluster()
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported kind %s of document %d of %s,support [%s,%s]", meta.Kind, i, file, ClusterSpecKind, NineClusterKind)
+		}
+		key := nc.Namespace + "/" + nc.Name
+		if seen[key] {
+			return nil, fmt.Errorf("NineCluster:%s in namespace:%s is duplicated in %s", nc.Name, nc.Namespace, file)
+		}
+		seen[key] = true
+		clusters = append(clusters, nc)
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no ninecluster found in %s", file)
+	}
+	return clusters, nil
+}
+
+// PrintNineClusters prints the NineClusters in the output format,the yaml documents are separated by ---
+func PrintNineClusters(out io.Writer, clusters []*nineinfrav1alpha1.NineCluster, output string) error {
+	for i, c := range clusters {
+		// the objects returned by the api server have no type meta
+		nc := c.DeepCopy()
+		nc.APIVersion = nineinfrav1alpha1.GroupVersion.String()
+		nc.Kind = NineClusterKind
+		var data []byte
+		var err error
+		switch output {
+		case OutputJson:
+			data, err = json.MarshalIndent(nc, "", "    ")
+			data = append(data, '\n')
+		default:
+			data, err = yaml.Marshal(nc)
+			if i > 0 {
+				fmt.Fprintln(out, "---")
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newClusterApplyCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &applyCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "apply -f <FILE>",
+		Short:   "Create or update the NineClusters in a file",
+		Long:    applyDesc,
+		Example: applyExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("apply command takes no arguments, e.g. 'kubectl nine apply -f clusters.yaml'")
+			}
+			return ValidateDryRunAndOutput(c.dryRun, c.output)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.file, "filename", "f", "", "file of the cluster specs or the NineCluster manifests,- for the stdin")
+	f.StringVar(&c.profileFile, "profile-file", DefaultProfileFile(), "file of the user defined profiles")
+	f.StringVar(&c.dryRun, "dry-run", DryRunNone, fmt.Sprintf("only print the changes,support [%s]", strings.Join(dryRunSupported, ",")))
+	f.Lookup("dry-run").NoOptDefVal = DryRunClient
+	f.BoolVar(&c.force, "force", false, "only warn if the storage pools could not fit the created nineclusters")
+	f.StringVarP(&c.output, "output", "o", "", fmt.Sprintf("output format of the NineClusters,support [%s]", strings.Join(outputSupported, ",")))
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// apply creates the NineCluster if not exists,otherwise updates its spec with a server side dry run first for the diff
+func (c *applyCmd) apply(desired *nineinfrav1alpha1.NineCluster) (*nineinfrav1alpha1.NineCluster, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return nil, err
+	}
+	ncs := client.NineinfraV1alpha1().NineClusters(desired.Namespace)
+	existing, err := ncs.Get(context.TODO(), desired.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		if err := CheckCapacity(NineClusterStorageDemands(desired), c.force); err != nil {
+			return nil, err
+		}
+		opts := metav1.CreateOptions{}
+		if c.dryRun == DryRunServer {
+			opts.DryRun = []string{metav1.DryRunAll}
+		}
+		result, err := ncs.Create(context.TODO(), desired, opts)
+		if err != nil {
+			return nil, err
+		}
+		if c.dryRun == DryRunNone {
+			RecordRevision(result, nil, "apply")
+		}
+		if c.output == "" {
+			fmt.Println("NineCluster:" + desired.Name + " in namespace:" + desired.Namespace + " is created" + dryRunSuffix(c.dryRun))
+		}
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckInPlaceChange(existing, &desired.Spec); err != nil {
+		return nil, fmt.Errorf("NineCluster:%s in namespace:%s,%v", desired.Name, desired.Namespace, err)
+	}
+	if err := CheckCapacity(GrowthDemands(NineClusterStorageDemands(existing), NineClusterStorageDemands(desired)), c.force); err != nil {
+		return nil, err
+	}
+	obj := existing.DeepCopy()
+	obj.Spec = desired.Spec
+	for k, v := range desired.Labels {
+		if obj.Labels == nil {
+			obj.Labels = make(map[string]string)
+		}
+		obj.Labels[k] = v
+	}
+	for k, v := range desired.Annotations {
+		if obj.Annotations == nil {
+			obj.Annotations = make(map[string]string)
+		}
+		obj.Annotations[k] = v
+	}
+	preview, err := ncs.Update(context.TODO(), obj, metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+	if err != nil {
+		return nil, err
+	}
+	// the output is only allowed with the server side dry run
+	if c.output != "" {
+		return preview, nil
+	}
+	fmt.Println("NineCluster:" + desired.Name + " in namespace:" + desired.Namespace + ":")
+	changed, err := PrintSpecDiff(&existing.Spec, &preview.Spec)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		fmt.Println("NineCluster:" + desired.Name + " in namespace:" + desired.Namespace + " is unchanged")
+		return preview, nil
+	}
+	if c.dryRun == DryRunServer {
+		fmt.Println("NineCluster:" + desired.Name + " in namespace:" + desired.Namespace + " is configured" + dryRunSuffix(c.dryRun))
+		return preview, nil
+	}
+	result, err := ncs.Update(context.TODO(), obj, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	RecordRevision(result, &existing.Spec, "apply")
+	fmt.Println("NineCluster:" + desired.Name + " in namespace:" + desired.Namespace + " is configured")
+	return result, nil
+}
+
+// dryRunSuffix returns the suffix of the messages in the dry run
+func dryRunSuffix(dryRun string) string {
+	if dryRun == DryRunServer {
+		return " (server dry run)"
+	}
+	return ""
+}
+
+func (c *applyCmd) run() error {
+	clusters, err := LoadNineClusterFile(c.file, c.profileFile)
+	if err != nil {
+		return err
+	}
+	if c.dryRun == DryRunClient {
+		return PrintNineClusters(c.out, clusters, c.output)
+	}
+
+	var applied []*nineinfrav1alpha1.NineCluster
+	for _, desired := range clusters {
+		if DEBUG {
+			fmt.Printf("Start to apply the nine cluster,detail info:%v\n", desired)
+		}
+		result, err := c.apply(desired)
+		if err != nil {
+			return fmt.Errorf("apply NineCluster:%s in namespace:%s failed,err:%v", desired.Name, desired.Namespace, err)
+		}
+		applied = append(applied, result)
+	}
+	if c.output != "" {
+		return PrintNineClusters(c.out, applied, c.output)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultNineHomeDir     = ".nine"
+	DefaultNineBinDir      = "bin"
+	DefaultDownloadTimeout = 10 * time.Minute
+	ToolArchiveTypeBinary  = "binary"
+	ToolArchiveTypeTarGzip = "tar.gz"
+)
+
+// ToolRelease describes a pinned release of a command line tool used by the nine
+type ToolRelease struct {
+	// Name of the executable in the tool cache
+	Name    string
+	Version string
+	// URL template of the release artifact,{version},{os} and {arch} are replaced
+	URL         string
+	ArchiveType string
+	// Path of the executable inside the archive,only used by archives
+	ArchivePath string
+	// Pinned sha256 digests of the release artifacts keyed by os/arch,a platform without a digest is refused
+	Checksums map[string]string
+}
+
+// ToolManifest pins the versions of all the tools managed in the tool cache
+var ToolManifest = map[string]ToolRelease{
+	DefaultCMDHelm: {
+		Name:        DefaultCMDHelm,
+		Version:     "3.13.2",
+		URL:         "https://get.helm.sh/helm-v{version}-{os}-{arch}.tar.gz",
+		ArchiveType: ToolArchiveTypeTarGzip,
+		ArchivePath: "{os}-{arch}/helm",
+		Checksums:   pinnedToolChecksums[DefaultCMDHelm],
+	},
+	DefaultCMDDirectPV: {
+		Name:        DefaultCMDDirectPV,
+		Version:     "4.0.9",
+		URL:         "https://github.com/minio/directpv/releases/download/v{version}/kubectl-directpv_{version}_{os}_{arch}",
+		ArchiveType: ToolArchiveTypeBinary,
+		Checksums:   pinnedToolChecksums[DefaultCMDDirectPV],
+	},
+}
+
+var (
+	// DefaultToolImportDir is the local dir to import the tools from instead of downloading
+	DefaultToolImportDir = ""
+)
+
+var ToolPlatformsSupported = []string{"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64"}
+
+// ToolCacheDir returns the directory of the managed tool cache,~/.nine/bin by default
+func ToolCacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, DefaultNineHomeDir, DefaultNineBinDir), nil
+}
+
+// AddToolCacheToPath puts the tool cache in front of PATH,so the cached tools are
+// found by exec.LookPath and by kubectl when it looks up its plugins
+func AddToolCacheToPath() {
+	dir, err := ToolCacheDir()
+	if err != nil {
+		return
+	}
+	envPath := os.Getenv("PATH")
+	for _, p := range filepath.SplitList(envPath) {
+		if p == dir {
+			return
+		}
+	}
+	_ = os.Setenv("PATH", dir+string(os.PathListSeparator)+envPath)
+}
+
+func ToolPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+func CheckToolPlatformSupported() error {
+	for _, p := range ToolPlatformsSupported {
+		if p == ToolPlatform() {
+			return nil
+		}
+	}
+	return fmt.Errorf("platform %s is not supported,support [%s]", ToolPlatform(), strings.Join(ToolPlatformsSupported, ","))
+}
+
+func (r ToolRelease) render(tmpl string) string {
+	return strings.NewReplacer(
+		"{version}", r.Version,
+		"{os}", runtime.GOOS,
+		"{arch}", runtime.GOARCH,
+	).Replace(tmpl)
+}
+
+// ArtifactName returns the file name of the release artifact for the current platform
+func (r ToolRelease) ArtifactName() string {
+	return filepath.Base(r.render(r.URL))
+}
+
+func newToolHttpClient() *http.Client {
+	return &http.Client{
+		Timeout: DefaultDownloadTimeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		},
+	}
+}
+
+func httpGet(client *http.Client, url string, w io.Writer) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s failed,status:%s", url, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// expectedChecksum returns the pinned sha256 digest of the release artifact for the current platform
+func (r ToolRelease) expectedChecksum() (string, error) {
+	sum := r.Checksums[ToolPlatform()]
+	if len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("no pinned checksum for %s v%s on %s in the tool manifest,pin them by 'make tool-checksums'", r.Name, r.Version, ToolPlatform())
+	}
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", fmt.Errorf("invalid pinned checksum for %s v%s on %s,err:%v", r.Name, r.Version, ToolPlatform(), err)
+	}
+	return sum, nil
+}
+
+func fileSha256(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func extractFromTarGz(archive string, member string, dst string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s not found in %s", member, archive)
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Name != member {
+			continue
+		}
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	}
+}
+
+// installArtifact verifies the artifact and installs the executable into the tool cache
+func (r ToolRelease) installArtifact(artifact string, checksum string) error {
+	actual, err := fileSha256(artifact)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, checksum) {
+		return fmt.Errorf("checksum mismatch for %s,expected:%s,actual:%s", filepath.Base(artifact), checksum, actual)
+	}
+	dir, err := ToolCacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "."+r.Name+".tmp")
+	defer os.Remove(tmp)
+	switch r.ArchiveType {
+	case ToolArchiveTypeTarGzip:
+		if err := extractFromTarGz(artifact, r.render(r.ArchivePath), tmp); err != nil {
+			return err
+		}
+	default:
+		data, err := os.ReadFile(artifact)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(tmp, data, 0755); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, filepath.Join(dir, r.Name))
+}
+
+// DownloadTool downloads the release of the tool for the current platform into the tool cache
+func DownloadTool(name string) error {
+	r, ok := ToolManifest[name]
+	if !ok {
+		return fmt.Errorf("tool %s is not in the manifest", name)
+	}
+	if err := CheckToolPlatformSupported(); err != nil {
+		return err
+	}
+	checksum, err := r.expectedChecksum()
+	if err != nil {
+		return err
+	}
+	client := newToolHttpClient()
+	tmpDir, err := os.MkdirTemp("", "kubectl-nine-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	artifact := filepath.Join(tmpDir, r.ArtifactName())
+	f, err := os.Create(artifact)
+	if err != nil {
+		return err
+	}
+	err = httpGet(client, r.render(r.URL), f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := r.installArtifact(artifact, checksum); err != nil {
+		return err
+	}
+	fmt.Printf("Install %s v%s for %s successfully!\n", r.Name, r.Version, ToolPlatform())
+	return nil
+}
+
+// ImportTool installs the tool from the release artifact in a local dir,
+// which is used by the hosts without internet access
+func ImportTool(name string, localDir string) error {
+	r, ok := ToolManifest[name]
+	if !ok {
+		return fmt.Errorf("tool %s is not in the manifest", name)
+	}
+	artifact := filepath.Join(localDir, r.ArtifactName())
+	if _, err := os.Stat(artifact); err != nil {
+		return fmt.Errorf("artifact %s of %s v%s not found in %s", r.ArtifactName(), r.Name, r.Version, localDir)
+	}
+	checksum, err := r.expectedChecksum()
+	if err != nil {
+		return err
+	}
+	if err := r.installArtifact(artifact, checksum); err != nil {
+		return err
+	}
+	fmt.Printf("Import %s v%s for %s from %s successfully!\n", r.Name, r.Version, ToolPlatform(), localDir)
+	return nil
+}
+
+// InstallTool installs the tool into the tool cache,from the local dir if given
+func InstallTool(name string, localDir string) error {
+	if localDir != "" {
+		return ImportTool(name, localDir)
+	}
+	err := DownloadTool(name)
+	if err != nil {
+		return fmt.Errorf("install %s failed,you can download it on another host and import it by --from-dir,err:%v", name, err)
+	}
+	return nil
+}
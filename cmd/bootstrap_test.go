@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpectedChecksum(t *testing.T) {
+	valid := strings.Repeat("0123456789abcdef", 4)
+	tests := []struct {
+		name      string
+		checksums map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{"pinned", map[string]string{ToolPlatform(): valid}, valid, false},
+		{"no checksums", nil, "", true},
+		{"other platform only", map[string]string{"plan9/386": valid}, "", true},
+		{"empty", map[string]string{ToolPlatform(): ""}, "", true},
+		{"too short", map[string]string{ToolPlatform(): valid[:63]}, "", true},
+		{"not hex", map[string]string{ToolPlatform(): strings.Repeat("z", 64)}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ToolRelease{Name: "tool", Version: "1.0.0", Checksums: tt.checksums}
+			got, err := r.expectedChecksum()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expectedChecksum() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expectedChecksum() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/manifoldco/promptui"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"golang.org/x/crypto/pbkdf2"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+// The layout of the bundle of a NineCluster,a gzipped tar
+const (
+	BundleVersion          = "v1"
+	BundleManifestFile     = "bundle.yaml"
+	BundleNineClusterFile  = "ninecluster.yaml"
+	BundleReleasesDir      = "releases"
+	BundleConfigMapsFile   = "configmaps.yaml"
+	BundleSecretsFile      = "secrets.yaml"
+	BundleSecretsEncFile   = "secrets.yaml.enc"
+	BundleDagsFile         = "dags.tar"
+	LastAppliedAnnoKey     = "kubectl.kubernetes.io/last-applied-configuration"
+	bundleEncMagic         = "NINEENC1"
+	bundleSaltSize         = 16
+	bundleKeySize          = 32
+	bundlePBKDF2Iterations = 200000
+)
+
+// BundleRelease is a helm release of the tools in the bundle
+type BundleRelease struct {
+	Name    string `json:"name"`
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+}
+
+// BundleManifest describes the contents of the bundle
+type BundleManifest struct {
+	Version    string          `json:"version"`
+	Cluster    string          `json:"cluster"`
+	Namespace  string          `json:"namespace"`
+	ExportedAt string          `json:"exportedAt"`
+	Releases   []BundleRelease `json:"releases,omitempty"`
+	ConfigMaps []string        `json:"configMaps,omitempty"`
+	Secrets    []string        `json:"secrets,omitempty"`
+	Dags       bool            `json:"dags"`
+	Encrypted  bool            `json:"encrypted"`
+}
+
+// Bundle is the portable definitions of a NineCluster,the values are the user supplied values of the releases
+type Bundle struct {
+	Manifest    BundleManifest
+	NineCluster *nineinfrav1alpha1.NineCluster
+	Values      map[string][]byte
+	ConfigMaps  []corev1.ConfigMap
+	Secrets     []corev1.Secret
+	Dags        []byte
+}
+
+// PortableObjectMeta returns the metadata without the runtime fields
+func PortableObjectMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	portable := metav1.ObjectMeta{
+		Name:      meta.Name,
+		Namespace: meta.Namespace,
+		Labels:    meta.Labels,
+	}
+	for k, v := range meta.Annotations {
+		if k == LastAppliedAnnoKey {
+			continue
+		}
+		if portable.Annotations == nil {
+			portable.Annotations = make(map[string]string)
+		}
+		portable.Annotations[k] = v
+	}
+	return portable
+}
+
+// bundleKey derives the key from the passphrase by the PBKDF2 with the HMAC-SHA256
+func bundleKey(passphrase string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, bundlePBKDF2Iterations, bundleKeySize, sha256.New)
+}
+
+// EncryptWithPassphrase encrypts the data by the AES-GCM with the key derived from the passphrase
+func EncryptWithPassphrase(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, bundleSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(bundleKey(passphrase, salt))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte(bundleEncMagic), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, []byte(bundleEncMagic)), nil
+}
+
+// DecryptWithPassphrase decrypts the data encrypted by EncryptWithPassphrase
+func DecryptWithPassphrase(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(bundleEncMagic)) || len(data) < len(bundleEncMagic)+bundleSaltSize {
+		return nil, errors.New("invalid encrypted data")
+	}
+	data = data[len(bundleEncMagic):]
+	salt, data := data[:bundleSaltSize], data[bundleSaltSize:]
+	block, err := aes.NewCipher(bundleKey(passphrase, salt))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("invalid encrypted data")
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, data, []byte(bundleEncMagic))
+	if err != nil {
+		return nil, errors.New("decrypt failed,the passphrase may be wrong")
+	}
+	return plain, nil
+}
+
+// ReadPassphrase reads the passphrase from the file,or prompts for it if the file is not specified
+func ReadPassphrase(file string, confirm bool) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		passphrase := strings.TrimRight(string(data), "\r\n")
+		if passphrase == "" {
+			return "", fmt.Errorf("passphrase file %s is empty", file)
+		}
+		return passphrase, nil
+	}
+	prompt := promptui.Prompt{Label: "Passphrase", Mask: '*'}
+	passphrase, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("passphrase should not be empty")
+	}
+	if confirm {
+		prompt = promptui.Prompt{Label: "Confirm the passphrase", Mask: '*'}
+		again, err := prompt.Run()
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("the passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// WriteBundle writes the bundle into the file,the secrets are encrypted if the passphrase is not empty
+func WriteBundle(file string, b *Bundle, passphrase string) error {
+	files := make(map[string][]byte)
+	b.Manifest.Encrypted = passphrase != "" && len(b.Secrets) != 0
+	b.Manifest.Dags = len(b.Dags) != 0
+	var err error
+	if files[BundleManifestFile], err = yaml.Marshal(b.Manifest); err != nil {
+		return err
+	}
+	if files[BundleNineClusterFile], err = yaml.Marshal(b.NineCluster); err != nil {
+		return err
+	}
+	for name, values := range b.Values {
+		files[path.Join(BundleReleasesDir, name+".yaml")] = values
+	}
+	if len(b.ConfigMaps) != 0 {
+		list := &corev1.ConfigMapList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"}, Items: b.ConfigMaps}
+		if files[BundleConfigMapsFile], err = yaml.Marshal(list); err != nil {
+			return err
+		}
+	}
+	if len(b.Secrets) != 0 {
+		list := &corev1.SecretList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"}, Items: b.Secrets}
+		data, err := yaml.Marshal(list)
+		if err != nil {
+			return err
+		}
+		if b.Manifest.Encrypted {
+			if files[BundleSecretsEncFile], err = EncryptWithPassphrase(data, passphrase); err != nil {
+				return err
+			}
+		} else {
+			files[BundleSecretsFile] = data
+		}
+	}
+	if b.Manifest.Dags {
+		files[BundleDagsFile] = b.Dags
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	// the manifest goes first so that it could be read without the whole bundle
+	if err := writeTarFile(tw, BundleManifestFile, files[BundleManifestFile]); err != nil {
+		return err
+	}
+	for _, name := range SortedKeys(files) {
+		if name == BundleManifestFile {
+			continue
+		}
+		if err := writeTarFile(tw, name, files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0600)
+}
+
+// ReadBundle reads the bundle from the file,the passphrase is asked only if the secrets are encrypted
+func ReadBundle(file string, passphrase func() (string, error)) (*Bundle, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle %s,err:%v", file, err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle %s,err:%v", file, err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+
+	b := &Bundle{Values: make(map[string][]byte)}
+	data, ok := files[BundleManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("invalid bundle %s,%s not found", file, BundleManifestFile)
+	}
+	if err := yaml.Unmarshal(data, &b.Manifest); err != nil {
+		return nil, err
+	}
+	if b.Manifest.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %s,support [%s]", b.Manifest.Version, BundleVersion)
+	}
+	b.NineCluster = &nineinfrav1alpha1.NineCluster{}
+	if err := yaml.Unmarshal(files[BundleNineClusterFile], b.NineCluster); err != nil {
+		return nil, err
+	}
+	for _, r := range b.Manifest.Releases {
+		values, ok := files[path.Join(BundleReleasesDir, r.Name+".yaml")]
+		if !ok {
+			return nil, fmt.Errorf("invalid bundle %s,values of the release %s not found", file, r.Name)
+		}
+		b.Values[r.Name] = values
+	}
+	if data, ok := files[BundleConfigMapsFile]; ok {
+		list := &corev1.ConfigMapList{}
+		if err := yaml.Unmarshal(data, list); err != nil {
+			return nil, err
+		}
+		b.ConfigMaps = list.Items
+	}
+	data, ok = files[BundleSecretsFile]
+	if b.Manifest.Encrypted {
+		encrypted, found := files[BundleSecretsEncFile]
+		if !found {
+			return nil, fmt.Errorf("invalid bundle %s,%s not found", file, BundleSecretsEncFile)
+		}
+		p, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		if data, err = DecryptWithPassphrase(encrypted, p); err != nil {
+			return nil, err
+		}
+		ok = true
+	}
+	if ok {
+		list := &corev1.SecretList{}
+		if err := yaml.Unmarshal(data, list); err != nil {
+			return nil, err
+		}
+		b.Secrets = list.Items
+	}
+	b.Dags = files[BundleDagsFile]
+	return b, nil
+}
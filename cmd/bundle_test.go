@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"text", []byte("apiVersion: v1\nkind: Secret\n")},
+		{"binary", bytes.Repeat([]byte{0, 1, 2, 255}, 1024)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := EncryptWithPassphrase(tt.data, "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.data) != 0 && bytes.Contains(enc, tt.data) {
+				t.Fatal("the encrypted data contains the plain data")
+			}
+			plain, err := DecryptWithPassphrase(enc, "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(plain, tt.data) {
+				t.Errorf("DecryptWithPassphrase() = %q, want %q", plain, tt.data)
+			}
+			if _, err := DecryptWithPassphrase(enc, "wrong"); err == nil {
+				t.Error("DecryptWithPassphrase() with a wrong passphrase should fail")
+			}
+			tampered := append([]byte{}, enc...)
+			tampered[len(tampered)-1] ^= 1
+			if _, err := DecryptWithPassphrase(tampered, "secret"); err == nil {
+				t.Error("DecryptWithPassphrase() of the tampered data should fail")
+			}
+		})
+	}
+
+	enc1, _ := EncryptWithPassphrase([]byte("data"), "secret")
+	enc2, _ := EncryptWithPassphrase([]byte("data"), "secret")
+	if bytes.Equal(enc1, enc2) {
+		t.Error("EncryptWithPassphrase() should use a random salt and nonce")
+	}
+	for _, invalid := range [][]byte{nil, []byte("NINEENC1"), []byte("plain data not encrypted at all")} {
+		if _, err := DecryptWithPassphrase(invalid, "secret"); err == nil {
+			t.Errorf("DecryptWithPassphrase(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestBundleKey(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key := bundleKey("secret", salt)
+	if len(key) != bundleKeySize {
+		t.Fatalf("len(bundleKey()) = %d, want %d", len(key), bundleKeySize)
+	}
+	if !bytes.Equal(key, bundleKey("secret", salt)) {
+		t.Error("bundleKey() should be deterministic")
+	}
+	if bytes.Equal(key, bundleKey("secret", []byte("fedcba9876543210"))) {
+		t.Error("bundleKey() should depend on the salt")
+	}
+	if bytes.Equal(key, bundleKey("secret2", salt)) {
+		t.Error("bundleKey() should depend on the passphrase")
+	}
+}
+
+func TestPortableValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		tool   string
+		values string
+		want   string
+	}{
+		{"no environment values", DefaultToolAirflowName, "a: 1\n", "a: 1\n"},
+		{"datasources removed", DefaultToolSupersetName,
+			"extraConfigs:\n  import_datasources: 'hive://10.0.0.1:10009'\n  other: x\nservice:\n  type: NodePort\n",
+			"extraConfigs:\n  other: x\nservice:\n  type: NodePort\n"},
+		{"empty parent removed", DefaultToolSupersetName,
+			"extraConfigs:\n  import_datasources: 'hive://10.0.0.1:10009'\nservice:\n  type: NodePort\n",
+			"service:\n  type: NodePort\n"},
+		{"not set", DefaultToolSupersetName, "service:\n  type: NodePort\n", "service:\n  type: NodePort\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PortableValues(tt.tool, []byte(tt.values))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("PortableValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
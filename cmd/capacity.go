@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sort"
+	"strings"
+)
+
+// The storage layout of the components estimated from the defaults of the operators,
+// the resources of the cluster set take precedence over them
+var (
+	DefaultMinioServers          int32 = 1
+	DefaultHdfsDataNodes         int32 = 3
+	DefaultHdfsJournalNodes      int32 = 3
+	DefaultHdfsJournalNodePVSize       = 1
+	DefaultZookeeperPVSize             = 1
+	DefaultDataBaseReplicas      int32 = 1
+	DefaultDataBaseStoragePVSize       = 10
+	DefaultDorisFEReplicas       int32 = 1
+	MainStorageReplication             = map[string]int{FeaturesStorageValueMinio: 1, FeaturesStorageValueHdfs: 3}
+)
+
+// StorageDemand is the storage required by the replicas of a component,
+// the replicas are spread over the nodes by the anti-affinity,one volume of the size per replica
+type StorageDemand struct {
+	Component    string
+	StorageClass string
+	Replicas     int32
+	Size         int64
+}
+
+// storageDemandOf returns the demand of the component with the replicas,the storage class and the storage request
+// of its cluster info taking precedence over the defaults
+func storageDemandOf(nc *nineinfrav1alpha1.NineCluster, component string, clusterType nineinfrav1alpha1.ClusterType, replicas int32, size int64) StorageDemand {
+	demand := StorageDemand{
+		Component:    component,
+		StorageClass: DefaultStorageClass,
+		Replicas:     replicas,
+		Size:         size,
+	}
+	if i := FindClusterInfo(nc.Spec.ClusterSet, clusterType); i >= 0 {
+		res := nc.Spec.ClusterSet[i].Resource
+		if res.StorageClass != "" {
+			demand.StorageClass = res.StorageClass
+		}
+		if res.Replicas > 0 {
+			demand.Replicas = res.Replicas
+		}
+		if q, ok := res.ResourceRequirements.Requests["storage"]; ok && q.Value() > 0 {
+			demand.Size = q.Value()
+		}
+	}
+	return demand
+}
+
+// NineClusterStorageDemands returns the storage demands of the NineCluster,the data volume is multiplied by the
+// replication of the main storage and spread over its servers
+func NineClusterStorageDemands(nc *nineinfrav1alpha1.NineCluster) []StorageDemand {
+	var demands []StorageDemand
+	storageType := FeaturesStorageValueMinio
+	if v, ok := nc.Spec.Features[FeaturesStorageKey]; ok {
+		storageType = strings.ToLower(v)
+	}
+	dataVolume := int64(nc.Spec.DataVolume*MainStorageReplication[storageType]) * GiMultiplier
+	if storageType == FeaturesStorageValueHdfs {
+		datanodes := storageDemandOf(nc, "datanode", nineinfrav1alpha1.HdfsClusterType, DefaultHdfsDataNodes, 0)
+		datanodes.Size = dataVolume / int64(datanodes.Replicas)
+		demands = append(demands, datanodes)
+		journalnodes := datanodes
+		journalnodes.Component = "journalnode"
+		journalnodes.Replicas = DefaultHdfsJournalNodes
+		journalnodes.Size = int64(DefaultHdfsJournalNodePVSize) * GiMultiplier
+		demands = append(demands, journalnodes)
+		zookeeper := storageDemandOf(nc, "zookeeper", nineinfrav1alpha1.ZookeeperClusterType, int32(DefaultZookeeperReplicas), int64(DefaultZookeeperPVSize)*GiMultiplier)
+		demands = append(demands, zookeeper)
+	} else {
+		minio := storageDemandOf(nc, "minio", nineinfrav1alpha1.MinioClusterType, DefaultMinioServers, 0)
+		minio.Size = dataVolume / int64(minio.Replicas)
+		demands = append(demands, minio)
+	}
+	demands = append(demands, storageDemandOf(nc, "database", nineinfrav1alpha1.DatabaseClusterType,
+		DefaultDataBaseReplicas, int64(DefaultDataBaseStoragePVSize)*GiMultiplier))
+	if _, ok := nc.Spec.Features[FeaturesOlapKey]; ok {
+		demands = append(demands, storageDemandOf(nc, "doris-fe", nineinfrav1alpha1.DorisFEClusterType,
+			DefaultDorisFEReplicas, int64(DefaultDorisFEStoragePVSize)*GiMultiplier))
+		demands = append(demands, storageDemandOf(nc, "doris-be", nineinfrav1alpha1.DorisBEClusterType,
+			DefaultOlapExecutors, int64(DefaultDorisBEStoragePVSize)*GiMultiplier))
+	}
+	return demands
+}
+
+// PrintStorageDemands prints the storage demands
+func PrintStorageDemands(demands []StorageDemand) {
+	fmt.Printf(PrintFmtStrStorageDemand, "COMPONENT", "STORAGEPOOL", "REPLICAS", "SIZE")
+	for _, d := range demands {
+		fmt.Printf(PrintFmtStrStorageDemand, d.Component, d.StorageClass, fmt.Sprint(d.Replicas),
+			resource.NewQuantity(d.Size, resource.BinarySI).String())
+	}
+}
+
+// fitStorageDemands places the replicas of the demands on the nodes greedily,the larger volumes first,
+// the replicas of a component are placed on the different nodes
+func fitStorageDemands(demands []StorageDemand, nodes map[string]int64) error {
+	sorted := append([]StorageDemand{}, demands...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Size > sorted[j].Size
+	})
+	free := make(map[string]int64, len(nodes))
+	for node, capacity := range nodes {
+		free[node] = capacity
+	}
+	for _, d := range sorted {
+		candidates := make([]string, 0, len(free))
+		for node, capacity := range free {
+			if capacity >= d.Size {
+				candidates = append(candidates, node)
+			}
+		}
+		if int32(len(candidates)) < d.Replicas {
+			return fmt.Errorf("%s requires %d nodes with %s free in storage pool %s but only %d found",
+				d.Component, d.Replicas, resource.NewQuantity(d.Size, resource.BinarySI).String(), d.StorageClass, len(candidates))
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			if free[candidates[i]] == free[candidates[j]] {
+				return candidates[i] < candidates[j]
+			}
+			return free[candidates[i]] > free[candidates[j]]
+		})
+		for _, node := range candidates[:d.Replicas] {
+			free[node] -= d.Size
+		}
+	}
+	return nil
+}
+
+// GrowthDemands returns the storage required to change the demands before into the demands after,the new replicas
+// require the whole volumes and the existing replicas only their growth
+func GrowthDemands(before []StorageDemand, after []StorageDemand) []StorageDemand {
+	existing := make(map[string]StorageDemand)
+	for _, d := range before {
+		existing[d.Component] = d
+	}
+	var growth []StorageDemand
+	for _, d := range after {
+		e, ok := existing[d.Component]
+		if !ok || e.StorageClass != d.StorageClass {
+			growth = append(growth, d)
+			continue
+		}
+		if d.Size > e.Size {
+			grown := d
+			grown.Replicas = e.Replicas
+			if d.Replicas < e.Replicas {
+				grown.Replicas = d.Replicas
+			}
+			grown.Size = d.Size - e.Size
+			growth = append(growth, grown)
+		}
+		if d.Replicas > e.Replicas {
+			added := d
+			added.Replicas = d.Replicas - e.Replicas
+			growth = append(growth, added)
+		}
+	}
+	return growth
+}
+
+// CheckStorageFit checks the storage demands fit the free capacity of the nodes of their storage pools,
+// the pools not backed by directpv are skipped
+func CheckStorageFit(demands []StorageDemand) error {
+	pools := make(map[string][]StorageDemand)
+	for _, d := range demands {
+		if d.Replicas <= 0 || d.Size <= 0 {
+			continue
+		}
+		pools[d.StorageClass] = append(pools[d.StorageClass], d)
+	}
+	for _, sc := range SortedKeys(pools) {
+		nodes, err := GetStoragePoolNodeFreeCapacity(sc)
+		if err != nil {
+			fmt.Printf("Skip the capacity check of the storage pool %s,err:%v\n", sc, err)
+			continue
+		}
+		if err := fitStorageDemands(pools[sc], nodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckCapacity checks the storage demands fit,only warns if forced
+func CheckCapacity(demands []StorageDemand, force bool) error {
+	err := CheckStorageFit(demands)
+	if err == nil {
+		return nil
+	}
+	if force {
+		fmt.Printf("Warning: %v,the volumes may be pending\n", err)
+		return nil
+	}
+	PrintStorageDemands(demands)
+	return fmt.Errorf("%v,use --force to continue anyway", err)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFitStorageDemands(t *testing.T) {
+	const gi = int64(GiMultiplier)
+	demand := func(component string, replicas int32, size int64) StorageDemand {
+		return StorageDemand{Component: component, StorageClass: "nineinfra-default", Replicas: replicas, Size: size * gi}
+	}
+	tests := []struct {
+		name    string
+		demands []StorageDemand
+		nodes   map[string]int64
+		wantErr bool
+	}{
+		{"no demands", nil, map[string]int64{"n1": 0}, false},
+		{"fits one node", []StorageDemand{demand("minio", 1, 10)}, map[string]int64{"n1": 10 * gi}, false},
+		{"too large for any node", []StorageDemand{demand("minio", 1, 11)}, map[string]int64{"n1": 10 * gi, "n2": 10 * gi}, true},
+		{"replicas on different nodes", []StorageDemand{demand("datanode", 3, 10)},
+			map[string]int64{"n1": 100 * gi, "n2": 100 * gi}, true},
+		{"replicas spread", []StorageDemand{demand("datanode", 3, 10)},
+			map[string]int64{"n1": 10 * gi, "n2": 10 * gi, "n3": 10 * gi}, false},
+		{"components share a node", []StorageDemand{demand("minio", 1, 10), demand("database", 1, 10)},
+			map[string]int64{"n1": 20 * gi}, false},
+		{"components exceed a node", []StorageDemand{demand("minio", 1, 10), demand("database", 1, 10)},
+			map[string]int64{"n1": 19 * gi}, true},
+		{"larger volumes placed first", []StorageDemand{demand("database", 1, 5), demand("minio", 1, 20)},
+			map[string]int64{"n1": 20 * gi, "n2": 5 * gi}, false},
+		{"most free nodes used first", []StorageDemand{demand("datanode", 2, 10), demand("zookeeper", 2, 10)},
+			map[string]int64{"n1": 20 * gi, "n2": 20 * gi, "n3": 10 * gi}, false},
+		{"no nodes", []StorageDemand{demand("minio", 1, 1)}, map[string]int64{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fitStorageDemands(tt.demands, tt.nodes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("fitStorageDemands() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	nodes := map[string]int64{"n1": 10 * gi}
+	if err := fitStorageDemands([]StorageDemand{demand("minio", 1, 10)}, nodes); err != nil {
+		t.Fatal(err)
+	}
+	if nodes["n1"] != 10*gi {
+		t.Error("fitStorageDemands() should not change the free capacity of the nodes")
+	}
+}
+
+func TestGrowthDemands(t *testing.T) {
+	demand := func(component string, sc string, replicas int32, size int64) StorageDemand {
+		return StorageDemand{Component: component, StorageClass: sc, Replicas: replicas, Size: size}
+	}
+	tests := []struct {
+		name   string
+		before []StorageDemand
+		after  []StorageDemand
+		want   []StorageDemand
+	}{
+		{"unchanged", []StorageDemand{demand("minio", "p1", 1, 10)}, []StorageDemand{demand("minio", "p1", 1, 10)}, nil},
+		{"new component", nil, []StorageDemand{demand("doris-be", "p1", 3, 10)}, []StorageDemand{demand("doris-be", "p1", 3, 10)}},
+		{"grown volumes", []StorageDemand{demand("minio", "p1", 2, 10)}, []StorageDemand{demand("minio", "p1", 2, 15)},
+			[]StorageDemand{demand("minio", "p1", 2, 5)}},
+		{"added replicas", []StorageDemand{demand("doris-be", "p1", 3, 10)}, []StorageDemand{demand("doris-be", "p1", 5, 10)},
+			[]StorageDemand{demand("doris-be", "p1", 2, 10)}},
+		{"grown and added", []StorageDemand{demand("doris-be", "p1", 3, 10)}, []StorageDemand{demand("doris-be", "p1", 4, 12)},
+			[]StorageDemand{demand("doris-be", "p1", 3, 2), demand("doris-be", "p1", 1, 12)}},
+		{"shrunk", []StorageDemand{demand("doris-be", "p1", 3, 10)}, []StorageDemand{demand("doris-be", "p1", 2, 10)}, nil},
+		{"other storage pool", []StorageDemand{demand("doris-be", "p1", 3, 10)}, []StorageDemand{demand("doris-be", "p2", 3, 10)},
+			[]StorageDemand{demand("doris-be", "p2", 3, 10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GrowthDemands(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GrowthDemands() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
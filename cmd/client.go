@@ -5,10 +5,15 @@ import (
 	pgoperatorv1 "github.com/cloudnative-pg/client/clientset/versioned"
 	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
 	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"os"
+	"sort"
+	"sync"
 )
 
 func GetKubeClient(path string) (*kubernetes.Clientset, error) {
@@ -16,7 +21,7 @@ func GetKubeClient(path string) (*kubernetes.Clientset, error) {
 	if path != "" {
 		loadingRules.ExplicitPath = path
 	}
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 	config, err := kubeConfig.ClientConfig()
 	if err != nil {
@@ -35,7 +40,7 @@ func GetKubeDynamicClient(path string) (*dynamic.DynamicClient, error) {
 	if path != "" {
 		loadingRules.ExplicitPath = path
 	}
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 	config, err := kubeConfig.ClientConfig()
 	if err != nil {
@@ -54,7 +59,7 @@ func GetKubeClientWithConfig(path string) (*kubernetes.Clientset, *restclient.Co
 	if path != "" {
 		loadingRules.ExplicitPath = path
 	}
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 	config, err := kubeConfig.ClientConfig()
 	if err != nil {
@@ -74,7 +79,7 @@ func GetKubeConfig() (*restclient.Config, error) {
 	if path != "" {
 		loadingRules.ExplicitPath = path
 	}
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 	config, err := kubeConfig.ClientConfig()
 	if err != nil {
@@ -88,7 +93,7 @@ func GetKubeHost(path string) (string, error) {
 	if path != "" {
 		loadingRules.ExplicitPath = path
 	}
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 	config, err := kubeConfig.ClientConfig()
 	if err != nil {
@@ -107,7 +112,7 @@ func GetNineInfraClient(path string) (*nineinfrav1alpha1.Clientset, error) {
 	if path != "" {
 		loadingRules.ExplicitPath = path
 	}
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 
 	config, err := kubeConfig.ClientConfig()
@@ -127,7 +132,7 @@ func GetPGOperatorClient(path string) (*pgoperatorv1.Clientset, error) {
 	if path != "" {
 		loadingRules.ExplicitPath = path
 	}
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 
 	config, err := kubeConfig.ClientConfig()
@@ -142,12 +147,19 @@ func GetPGOperatorClient(path string) (*pgoperatorv1.Clientset, error) {
 	return kubeClientset, nil
 }
 
+// directPVSchemeOnce registers the directpv types for the parameters of the requests,e.g. the list options
+var directPVSchemeOnce sync.Once
+
 func GetDirectPVClient(path string) (*directpvv1beta1.DirectpvV1beta1Client, error) {
+	directPVSchemeOnce.Do(func() {
+		metav1.AddToGroupVersion(directpvv1beta1.Scheme, directpvv1beta1.SchemeGroupVersion)
+		utilruntime.Must(directpvv1beta1.AddToScheme(directpvv1beta1.Scheme))
+	})
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	if path != "" {
 		loadingRules.ExplicitPath = path
 	}
-	configOverrides := &clientcmd.ConfigOverrides{}
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
 	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 
 	config, err := kubeConfig.ClientConfig()
@@ -161,3 +173,53 @@ func GetDirectPVClient(path string) (*directpvv1beta1.DirectpvV1beta1Client, err
 	}
 	return kubeClientset, nil
 }
+
+// GetKubeContexts returns the names of the contexts in the kubeconfig,sorted
+func GetKubeContexts(path string) ([]string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path != "" {
+		loadingRules.ExplicitPath = path
+	}
+	rawConfig, err := loadingRules.Load()
+	if err != nil {
+		return nil, err
+	}
+	contexts := make([]string, 0, len(rawConfig.Contexts))
+	for name := range rawConfig.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
+// ContextKubeconfig returns a kubeconfig with the --context of the nine as its current context for the commands
+// without a context flag,e.g. the kubectl plugins.The path is returned as it is without the --context,the cleanup
+// removes the temporary kubeconfig
+func ContextKubeconfig(path string) (string, func(), error) {
+	if kubeContext == "" {
+		return path, func() {}, nil
+	}
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path != "" {
+		loadingRules.ExplicitPath = path
+	}
+	config, err := loadingRules.Load()
+	if err != nil {
+		return "", func() {}, err
+	}
+	if _, ok := config.Contexts[kubeContext]; !ok {
+		return "", func() {}, fmt.Errorf("context %s is not found in the kubeconfig", kubeContext)
+	}
+	config.CurrentContext = kubeContext
+	f, err := os.CreateTemp("", "kubectl-nine-*-kubeconfig")
+	if err != nil {
+		return "", func() {}, err
+	}
+	_ = f.Close()
+	cleanup := func() { _ = os.Remove(f.Name()) }
+	if err := clientcmd.WriteToFile(*config, f.Name()); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return f.Name(), cleanup, nil
+}
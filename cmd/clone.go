@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+const (
+	cloneDesc = `
+'clone' command creates a new NineCluster with the spec of an existing one,the data volume,the features and
+the cluster set are copied and the storage pools and the sizes could be overridden.With --with-metadata,the
+metastore database of the source is dumped and restored into the new NineCluster after it is ready,so the new
+one sees the same table definitions.The data of the tables is not copied,the tables are empty in the new one.`
+	cloneExample = `1. Clone a NineCluster into another namespace
+   $ kubectl nine clone c1 c2 --source-namespace c1-ns -n c2-ns
+
+2. Clone a NineCluster with a smaller data volume on another storage pool
+   $ kubectl nine clone c1 c1-test --data-volume 16 --storage-pool nineinfra-default -n c1-ns
+
+3. Clone a NineCluster with the table definitions
+   $ kubectl nine clone c1 c2 --source-namespace c1-ns -n c2-ns --with-metadata
+
+4. Show the NineCluster to be created
+   $ kubectl nine clone c1 c2 --source-namespace c1-ns -n c2-ns --dry-run --output yaml`
+)
+
+const (
+	DefaultCloneTimeout  = 20 * time.Minute
+	PGPrimaryPodSelector = "cnpg.io/cluster=%s,role=primary"
+)
+
+// cloneOverrideFlags are the flags overriding the spec of the source
+var cloneOverrideFlags = []string{"data-volume", "storage-pool", "olap-storage-pool", "metastore-storage-pool", "olap-volume", "olap-executors"}
+
+type CloneOptions struct {
+	Source               string
+	SourceNS             string
+	Name                 string
+	NS                   string
+	DataVolume           int
+	StoragePool          string
+	OlapStoragePool      string
+	MetastoreStoragePool string
+	OlapVolume           int
+	OlapExecutors        int32
+	WithMetadata         bool
+	MetastoreDatabase    string
+}
+
+type cloneCmd struct {
+	out       io.Writer
+	errOut    io.Writer
+	cloneOpts CloneOptions
+	changed   map[string]bool
+	dryRun    string
+	output    string
+	force     bool
+	yes       bool
+	timeout   time.Duration
+}
+
+func newClusterCloneCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &cloneCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "clone <SOURCE> <NINECLUSTERNAME>",
+		Short:   "Clone a NineCluster",
+		Long:    cloneDesc,
+		Example: cloneExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			c.changed = make(map[string]bool)
+			for _, flag := range cloneOverrideFlags {
+				c.changed[flag] = cmd.Flags().Changed(flag)
+			}
+			return c.validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.cloneOpts.NS, "namespace", "n", "", "namespace of the new NineCluster")
+	f.StringVar(&c.cloneOpts.SourceNS, "source-namespace", "", "namespace of the source NineCluster,defaults to the namespace of the new one")
+	f.IntVarP(&c.cloneOpts.DataVolume, "data-volume", "v", 0, "total raw data volumes of the new ninecluster,the unit is Gi, e.g. 64")
+	f.StringVarP(&c.cloneOpts.StoragePool, "storage-pool", "s", "", "storage pool for the new ninecluster")
+	f.StringVarP(&c.cloneOpts.OlapStoragePool, "olap-storage-pool", "o", "", "storage pool for olap")
+	f.StringVarP(&c.cloneOpts.MetastoreStoragePool, "metastore-storage-pool", "m", "", "storage pool for metastore")
+	f.IntVar(&c.cloneOpts.OlapVolume, "olap-volume", 0, "olap storage volume size")
+	f.Int32VarP(&c.cloneOpts.OlapExecutors, "olap-executors", "r", 0, "num of the olap executors")
+	f.BoolVar(&c.cloneOpts.WithMetadata, "with-metadata", false, "restore the metastore database of the source into the new ninecluster")
+	f.StringVar(&c.cloneOpts.MetastoreDatabase, "metastore-database", "", "name of the metastore database,detected from the source if not specified")
+	f.StringVar(&c.dryRun, "dry-run", DryRunNone, fmt.Sprintf("only print the NineCluster to be created,support [%s]", strings.Join(dryRunSupported, ",")))
+	f.Lookup("dry-run").NoOptDefVal = DryRunClient
+	f.StringVar(&c.output, "output", "", fmt.Sprintf("output format of the NineCluster,support [%s]", strings.Join(outputSupported, ",")))
+	f.BoolVar(&c.force, "force", false, "only warn if the storage pools could not fit the new ninecluster")
+	f.BoolVarP(&c.yes, "yes", "y", false, "skip the confirmation prompt")
+	f.DurationVar(&c.timeout, "timeout", DefaultCloneTimeout, "time to wait for the new ninecluster to be ready before restoring the metadata")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	return cmd
+}
+
+func (c *cloneCmd) validate(args []string) error {
+	if len(args) != 2 {
+		return errors.New("clone command requires specifying the source and the new NineCluster names as arguments")
+	}
+	c.cloneOpts.Source = args[0]
+	c.cloneOpts.Name = args[1]
+	if c.cloneOpts.SourceNS == "" {
+		c.cloneOpts.SourceNS = c.cloneOpts.NS
+	}
+	if c.cloneOpts.Source == c.cloneOpts.Name && c.cloneOpts.SourceNS == c.cloneOpts.NS {
+		return errors.New("the new NineCluster should not be the same as the source")
+	}
+	if err := CheckValidClusterName(c.cloneOpts.Name); err != nil {
+		return err
+	}
+	if c.cloneOpts.DataVolume < 0 || c.cloneOpts.OlapVolume < 0 || c.cloneOpts.OlapExecutors < 0 {
+		return errors.New("the sizes to override should not be negative")
+	}
+	if c.cloneOpts.WithMetadata && c.dryRun != DryRunNone {
+		return errors.New("--with-metadata is not supported with --dry-run")
+	}
+	return ValidateDryRunAndOutput(c.dryRun, c.output)
+}
+
+// desiredNineCluster returns the new NineCluster with the spec of the source and the overrides
+func (c *cloneCmd) desiredNineCluster(source *nineinfrav1alpha1.NineCluster) (*nineinfrav1alpha1.NineCluster, error) {
+	opts := c.cloneOpts
+	nc := &nineinfrav1alpha1.NineCluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: nineinfrav1alpha1.GroupVersion.String(),
+			Kind:       NineClusterKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      opts.Name,
+			Namespace: opts.NS,
+		},
+		Spec: *source.Spec.DeepCopy(),
+	}
+	if c.changed["data-volume"] {
+		if opts.DataVolume == 0 {
+			return nil, errors.New("--data-volume should be greater than 0")
+		}
+		nc.Spec.DataVolume = opts.DataVolume
+	}
+	_, hasOlap := nc.Spec.Features[FeaturesOlapKey]
+	if !hasOlap && (c.changed["olap-storage-pool"] || c.changed["olap-volume"] || c.changed["olap-executors"]) {
+		return nil, fmt.Errorf("NineCluster %s has no olap,the olap flags are not supported", source.Name)
+	}
+	if c.changed["storage-pool"] {
+		storageType := nc.Spec.Features[FeaturesStorageKey]
+		if storageType != "" && !strings.EqualFold(storageType, FeaturesStorageValueMinio) {
+			return nil, fmt.Errorf("the main storage of NineCluster %s is %s,--storage-pool is not supported", source.Name, storageType)
+		}
+		nc.Spec.ClusterSet[EnsureClusterInfo(nc, nineinfrav1alpha1.MinioClusterType)].Resource.StorageClass = opts.StoragePool
+	}
+	if c.changed["metastore-storage-pool"] {
+		nc.Spec.ClusterSet[EnsureClusterInfo(nc, nineinfrav1alpha1.DatabaseClusterType)].Resource.StorageClass = opts.MetastoreStoragePool
+	}
+	if c.changed["olap-storage-pool"] {
+		nc.Spec.ClusterSet[EnsureClusterInfo(nc, nineinfrav1alpha1.DorisFEClusterType)].Resource.StorageClass = opts.OlapStoragePool
+		nc.Spec.ClusterSet[EnsureClusterInfo(nc, nineinfrav1alpha1.DorisBEClusterType)].Resource.StorageClass = opts.OlapStoragePool
+	}
+	if c.changed["olap-volume"] || c.changed["olap-executors"] {
+		be := &nc.Spec.ClusterSet[EnsureClusterInfo(nc, nineinfrav1alpha1.DorisBEClusterType)].Resource
+		if c.changed["olap-volume"] {
+			if be.ResourceRequirements.Requests == nil {
+				be.ResourceRequirements.Requests = make(corev1.ResourceList)
+			}
+			be.ResourceRequirements.Requests["storage"] = *resource.NewQuantity(int64(opts.OlapVolume*GiMultiplier), resource.BinarySI)
+		}
+		if c.changed["olap-executors"] {
+			be.Replicas = opts.OlapExecutors
+		}
+	}
+	if err := ValidateNineCluster(nc); err != nil {
+		return nil, err
+	}
+	return nc, nil
+}
+
+// pgQuery runs the query by the psql in the pod and returns the rows unaligned
+func pgQuery(pod string, namespace string, database string, query string) ([]string, error) {
+	out, err := RunExecCommandWithStdin(pod, "", namespace, []string{"psql", "-U", "postgres", "-d", database, "-At", "-c", query}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var rows []string
+	for _, row := range strings.Split(strings.TrimSpace(out), "\n") {
+		if row != "" {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// pgPrimaryPod returns the pod of the primary instance of the postgresql of the NineCluster
+func pgPrimaryPod(name string, namespace string) (string, error) {
+	pgName := NineWorkLoadName(name, "postgresql")
+	pods, err := GetPodNames(fmt.Sprintf(PGPrimaryPodSelector, pgName), namespace)
+	if err != nil {
+		return "", err
+	}
+	if len(pods) == 0 {
+		return "", fmt.Errorf("no primary of the postgresql %s found in namespace %s", pgName, namespace)
+	}
+	return pods[0], nil
+}
+
+// metastoreDatabase returns the metastore database in the postgresql,it is the only database
+// other than the postgres if not specified
+func (c *cloneCmd) metastoreDatabase(pod string) (string, error) {
+	if c.cloneOpts.MetastoreDatabase != "" {
+		return c.cloneOpts.MetastoreDatabase, nil
+	}
+	databases, err := pgQuery(pod, c.cloneOpts.SourceNS, "postgres",
+		"SELECT datname FROM pg_database WHERE NOT datistemplate AND datname <> 'postgres' ORDER BY datname")
+	if err != nil {
+		return "", err
+	}
+	if len(databases) != 1 {
+		return "", fmt.Errorf("could not detect the metastore database from [%s],specify it with --metastore-database", strings.Join(databases, ","))
+	}
+	return databases[0], nil
+}
+
+// restoreMetadata dumps the metastore database of the source and restores it into the new NineCluster,
+// the metastore of the new one is stopped during the restoring
+func (c *cloneCmd) restoreMetadata() error {
+	opts := c.cloneOpts
+	pg := PausedWorkload{Kind: PauseKindPG, Name: NineWorkLoadName(opts.Name, "postgresql")}
+	fmt.Printf("Waiting for the postgresql %s to be ready\n", pg.Name)
+	if err := waitWorkloadReady(pg, opts.NS, c.timeout); err != nil {
+		return err
+	}
+	metastore := PausedWorkload{Kind: PauseKindSts, Name: NineWorkLoadName(opts.Name, "metastore"), Replicas: -1}
+	fmt.Printf("Waiting for the metastore %s to be ready\n", metastore.Name)
+	// the metastore initializes the schema of the database before it is ready
+	if err := waitWorkloadReady(metastore, opts.NS, c.timeout); err != nil {
+		return err
+	}
+
+	srcPod, err := pgPrimaryPod(opts.Source, opts.SourceNS)
+	if err != nil {
+		return err
+	}
+	dstPod, err := pgPrimaryPod(opts.Name, opts.NS)
+	if err != nil {
+		return err
+	}
+	database, err := c.metastoreDatabase(srcPod)
+	if err != nil {
+		return err
+	}
+	owners, err := pgQuery(dstPod, opts.NS, "postgres",
+		fmt.Sprintf("SELECT pg_catalog.pg_get_userbyid(datdba) FROM pg_database WHERE datname = '%s'", database))
+	if err != nil {
+		return err
+	}
+	if len(owners) == 0 {
+		return fmt.Errorf("database %s not found in the postgresql of NineCluster %s", database, opts.Name)
+	}
+
+	fmt.Printf("Dumping the database %s of NineCluster %s\n", database, opts.Source)
+	dump, err := RunExecCommandWithStdin(srcPod, "", opts.SourceNS,
+		[]string{"pg_dump", "-U", "postgres", "-d", database, "--no-owner", "--no-privileges", "--clean", "--if-exists"}, nil)
+	if err != nil {
+		return fmt.Errorf("dump the database %s failed,err:%v", database, err)
+	}
+
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	sts, err := client.AppsV1().StatefulSets(opts.NS).Get(context.TODO(), metastore.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	metastore.Replicas = *sts.Spec.Replicas
+	if err := scaleWorkload(metastore, opts.NS, 0); err != nil {
+		return err
+	}
+	fmt.Printf("Restoring the database %s into NineCluster %s\n", database, opts.Name)
+	// the objects restored are owned by the owner of the database as the metastore created them
+	script := fmt.Sprintf("SET ROLE \"%s\";\n%s", owners[0], dump)
+	_, restoreErr := RunExecCommandWithStdin(dstPod, "", opts.NS,
+		[]string{"psql", "-U", "postgres", "-d", database, "-q", "-v", "ON_ERROR_STOP=1"}, strings.NewReader(script))
+	if err := scaleWorkload(metastore, opts.NS, metastore.Replicas); err != nil {
+		return err
+	}
+	if restoreErr != nil {
+		return fmt.Errorf("restore the database %s failed,err:%v", database, restoreErr)
+	}
+	return waitWorkloadReady(metastore, opts.NS, c.timeout)
+}
+
+func (c *cloneCmd) run() error {
+	opts := c.cloneOpts
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	source, err := client.NineinfraV1alpha1().NineClusters(opts.SourceNS).Get(context.TODO(), opts.Source, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	desired, err := c.desiredNineCluster(source)
+	if err != nil {
+		return err
+	}
+	if c.dryRun == DryRunClient {
+		return PrintNineClusters(c.out, []*nineinfrav1alpha1.NineCluster{desired}, c.output)
+	}
+	if exists, _ := CheckNineClusterExist(desired.Name, desired.Namespace); exists {
+		return errors.New("NineCluster:" + desired.Name + " already exists in namespace:" + desired.Namespace + "!")
+	}
+	if err := CheckCapacity(NineClusterStorageDemands(desired), c.force); err != nil {
+		return err
+	}
+	if c.dryRun == DryRunNone && !c.yes {
+		if _, err := PrintSpecDiff(&source.Spec, &desired.Spec); err != nil {
+			return err
+		}
+		if !Ask("NineCluster:" + desired.Name + " will be created in namespace:" + desired.Namespace + " from NineCluster:" +
+			source.Name + ", are you sure you want to continue") {
+			return errors.New("aborting NineCluster clone")
+		}
+	}
+
+	createOpts := metav1.CreateOptions{}
+	if c.dryRun == DryRunServer {
+		createOpts.DryRun = []string{metav1.DryRunAll}
+	}
+	result, err := client.NineinfraV1alpha1().NineClusters(desired.Namespace).Create(context.TODO(), desired, createOpts)
+	if err != nil {
+		return err
+	}
+	if c.dryRun == DryRunServer {
+		if c.output != "" {
+			return PrintNineClusters(c.out, []*nineinfrav1alpha1.NineCluster{result}, c.output)
+		}
+		fmt.Println("NineCluster:" + result.Name + " in namespace:" + result.Namespace + " is created" + dryRunSuffix(c.dryRun))
+		return nil
+	}
+	RecordRevision(result, nil, "clone")
+	fmt.Println("NineCluster:" + result.Name + " in namespace:" + result.Namespace + " is cloned from NineCluster:" + source.Name + " successfully!")
+
+	if opts.WithMetadata {
+		if err := c.restoreMetadata(); err != nil {
+			return fmt.Errorf("restore the metadata failed,the NineCluster is created without the metadata,err:%v", err)
+		}
+		fmt.Println("The metadata of NineCluster:" + source.Name + " is restored into NineCluster:" + result.Name)
+		return nil
+	}
+	fmt.Println("It may take a few minutes for it to be ready")
+	fmt.Println("You can check its status using the following command：")
+	fmt.Println("kubectl nine show " + result.Name + " -n " + result.Namespace)
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/manifoldco/promptui"
 	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
 	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"io"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -29,9 +30,11 @@ import (
 )
 
 const (
-	PrintFmtStrClusterList        = "%-20s\t%-10s\t%-10s\t%-10s\t%-10s\n"
+	PrintFmtStrClusterList        = "%-20s\t%-10s\t%-10s\t%-10s\t%-10s\t%-10s\n"
 	PrintFmtStrToolList           = "%-20s\t%-10s\t%-10s\t%-10s\t%-10s\n"
 	PrintFmtStrClusterProjectList = "%-40s\t%-10s\t%-10s\t%-10s\t%-10s\n"
+	PrintFmtStrChartProgress      = "%-20s\t%-14s\t%-10s\n"
+	PrintFmtStrStorageDemand      = "%-12s\t%-20s\t%-10s\t%-10s\n"
 )
 
 var Err2Suggestions = map[string]string{
@@ -49,6 +52,20 @@ func runCommandWithOSIO(command string, args ...string) error {
 	return cmd.Run()
 }
 
+// kubectlArgs returns the args of the kubectl with the --kubeconfig and the --context of the nine ahead,
+// so the kubectl works on the same cluster as the clients
+func kubectlArgs(args ...string) []string {
+	var global []string
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	if path != "" {
+		global = append(global, "--kubeconfig", path)
+	}
+	if kubeContext != "" {
+		global = append(global, "--context", kubeContext)
+	}
+	return append(global, args...)
+}
+
 func runCommand(command string, args ...string) (string, string, error) {
 	cmd := exec.Command(command, args...)
 
@@ -137,6 +154,47 @@ func RunExecCommand(pdName string, namespace string, tty bool, cmd []string) (st
 	}
 }
 
+// RunExecCommandWithStdin runs the command in the container of the pod with the stdin from the reader,
+// the default container is used if not specified,the stderr is returned with the error
+func RunExecCommandWithStdin(pdName string, container string, namespace string, cmd []string, stdin io.Reader) (string, error) {
+	if DEBUG {
+		fmt.Printf("RunExecCommandWithStdin %s through pod %s in %s\n", cmd, pdName, namespace)
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, config, err := GetKubeClientWithConfig(path)
+	if err != nil {
+		return "", err
+	}
+	execReq := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pdName).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+			TTY:       false}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", execReq.URL())
+	if err != nil {
+		return "", err
+	}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	err = executor.StreamWithContext(context.TODO(), remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Tty:    false,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%v,%s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
 func CheckNineClusterExist(name string, namespace string) (bool, *nineinfrav1alpha1.NineClusterList) {
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
 	nc, err := GetNineInfraClient(path)
@@ -173,6 +231,16 @@ func Ask(label string) bool {
 	return err == nil
 }
 
+// SortedKeys returns the keys of the map in order
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func HumanDuration(t time.Time) string {
 	return duration.HumanDuration(metav1.Now().Sub(t))
 }
@@ -381,13 +449,41 @@ func PrintToolList(clusters *nineinfrav1alpha1.NineClusterList) {
 	}
 }
 
-func PrintClusterList(clusters *nineinfrav1alpha1.NineClusterList) {
-	fmt.Printf(PrintFmtStrClusterList, "NAME", "DATAVOLUME", "READY", "NAMESPACE", "AGE")
+// ClusterListItem is a NineCluster in the list
+type ClusterListItem struct {
+	Context    string `json:"context,omitempty"`
+	Name       string `json:"name"`
+	DataVolume string `json:"dataVolume"`
+	Ready      string `json:"ready"`
+	Namespace  string `json:"namespace"`
+	Age        string `json:"age"`
+	Expires    string `json:"expires"`
+}
+
+// ClusterListItems returns the NineClusters in the list with their readiness
+func ClusterListItems(clusters *nineinfrav1alpha1.NineClusterList) []ClusterListItem {
+	items := make([]ClusterListItem, 0, len(clusters.Items))
 	for _, cluster := range clusters.Items {
-		ready := fmt.Sprintf("%t", CheckClusterIfReady(cluster.Name, cluster.Namespace))
-		age := fmt.Sprintf("%s", HumanDuration(cluster.CreationTimestamp.Time))
-		datavolume := fmt.Sprintf("%dGi", cluster.Spec.DataVolume)
-		fmt.Printf(PrintFmtStrClusterList, cluster.Name, datavolume, ready, cluster.Namespace, age)
+		ready := ClusterStatePaused
+		if !IsNineClusterPaused(&cluster) {
+			ready = fmt.Sprintf("%t", CheckClusterIfReady(cluster.Name, cluster.Namespace))
+		}
+		items = append(items, ClusterListItem{
+			Name:       cluster.Name,
+			DataVolume: fmt.Sprintf("%dGi", cluster.Spec.DataVolume),
+			Ready:      ready,
+			Namespace:  cluster.Namespace,
+			Age:        HumanDuration(cluster.CreationTimestamp.Time),
+			Expires:    NineClusterExpires(&cluster),
+		})
+	}
+	return items
+}
+
+func PrintClusterList(clusters *nineinfrav1alpha1.NineClusterList) {
+	fmt.Printf(PrintFmtStrClusterList, "NAME", "DATAVOLUME", "READY", "NAMESPACE", "AGE", "EXPIRES")
+	for _, item := range ClusterListItems(clusters) {
+		fmt.Printf(PrintFmtStrClusterList, item.Name, item.DataVolume, item.Ready, item.Namespace, item.Age, item.Expires)
 	}
 }
 
@@ -652,6 +748,113 @@ func CheckStoragePoolValid(sp string) bool {
 	return true
 }
 
+// GetStoragePoolFreeCapacity returns the free capacity in bytes of the schedulable directpv drives of the storage class,
+// an error is returned if the storage class is not backed by a directpv storage pool
+func GetStoragePoolFreeCapacity(sc string) (int64, error) {
+	nodes, err := GetStoragePoolNodeFreeCapacity(sc)
+	if err != nil {
+		return 0, err
+	}
+	var free int64
+	for _, capacity := range nodes {
+		free += capacity
+	}
+	return free, nil
+}
+
+// GetStoragePoolNodeFreeCapacity returns the free capacity of the ready and schedulable drives of the directpv
+// storage pool behind the storage class,summed per node
+func GetStoragePoolNodeFreeCapacity(sc string) (map[string]int64, error) {
+	if sc == "" {
+		sc = DefaultStorageClass
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	storageClass, err := client.StorageV1().StorageClasses().Get(context.TODO(), sc, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pool, ok := storageClass.Parameters[DefaultStoragePoolLabelKey]
+	if !ok {
+		return nil, fmt.Errorf("storage class %s is not a directpv storage pool", sc)
+	}
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return nil, err
+	}
+	selector := labels.Set(map[string]string{
+		DefaultStoragePoolLabelKey: pool,
+	}).AsSelector()
+	drives, err := dpclient.DirectPVDrives().List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]int64)
+	for _, drive := range drives.Items {
+		if drive.Status.Status == directpvv1beta1.DriveStatusReady && !drive.IsUnschedulable() {
+			nodes[string(drive.GetNodeID())] += drive.Status.FreeCapacity
+		}
+	}
+	return nodes, nil
+}
+
+// WaitStsReady waits for the statefulset to have the replicas all ready and updated,
+// the replicas less than 0 means the replicas are not changed
+func WaitStsReady(name string, namespace string, replicas int32, timeout time.Duration) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		sts, err := client.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && (replicas < 0 || *sts.Spec.Replicas == replicas) &&
+			sts.Status.ObservedGeneration >= sts.Generation &&
+			sts.Status.ReadyReplicas == *sts.Spec.Replicas &&
+			sts.Status.UpdatedReplicas == *sts.Spec.Replicas {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("statefulset %s in namespace %s is not ready in %s", name, namespace, timeout)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// WaitDeployReady waits for the deployment to have the replicas all ready and updated,
+// a negative replicas means any
+func WaitDeployReady(name string, namespace string, replicas int32, timeout time.Duration) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		deploy, err := client.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && (replicas < 0 || *deploy.Spec.Replicas == replicas) &&
+			deploy.Status.ObservedGeneration >= deploy.Generation &&
+			deploy.Status.ReadyReplicas == *deploy.Spec.Replicas &&
+			deploy.Status.UpdatedReplicas == *deploy.Spec.Replicas {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("deployment %s in namespace %s is not ready in %s", name, namespace, timeout)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
 func CheckMainStorageValid(ms string) bool {
 	if ms != "" {
 		for _, v := range MainStorageSupported {
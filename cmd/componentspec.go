@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// ComponentSpec is the resources of a component of the NineCluster
+type ComponentSpec struct {
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ComponentSpecFile is the file of the component specs,the components are keyed by the cluster types
+type ComponentSpecFile struct {
+	Components map[string]ComponentSpec `json:"components"`
+}
+
+// ComponentSpecArgs are the flags of the component specs
+type ComponentSpecArgs struct {
+	File     string
+	Requests []string
+	Limits   []string
+}
+
+// splitComponentArg splits the component=value flag
+func splitComponentArg(flag string, arg string) (string, string, error) {
+	component, value, found := strings.Cut(arg, "=")
+	if !found || component == "" || value == "" {
+		return "", "", fmt.Errorf("invalid --%s %s,should be component=value", flag, arg)
+	}
+	if !ClusterComponentSupported(component) {
+		return "", "", fmt.Errorf("unsupported component %s in --%s %s", component, flag, arg)
+	}
+	return component, value, nil
+}
+
+// parseResourceList parses cpu=2,memory=4Gi
+func parseResourceList(value string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for _, kv := range strings.Split(value, ",") {
+		k, v, found := strings.Cut(kv, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid resource %s,should be name=quantity", kv)
+		}
+		if k != string(corev1.ResourceCPU) && k != string(corev1.ResourceMemory) {
+			return nil, fmt.Errorf("unsupported resource %s,support [cpu,memory]", k)
+		}
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %s of %s", v, k)
+		}
+		list[corev1.ResourceName(k)] = q
+	}
+	return list, nil
+}
+
+// LoadComponentSpecs returns the component specs of the file with the flags merged on top
+func LoadComponentSpecs(args ComponentSpecArgs) (map[string]*ComponentSpec, error) {
+	specs := make(map[string]*ComponentSpec)
+	get := func(component string) *ComponentSpec {
+		if _, ok := specs[component]; !ok {
+			specs[component] = &ComponentSpec{}
+		}
+		return specs[component]
+	}
+	if args.File != "" {
+		data, err := os.ReadFile(args.File)
+		if err != nil {
+			return nil, err
+		}
+		file := &ComponentSpecFile{}
+		if err := yaml.UnmarshalStrict(data, file); err != nil {
+			return nil, fmt.Errorf("invalid component spec file %s,err:%v", args.File, err)
+		}
+		for component, spec := range file.Components {
+			if !ClusterComponentSupported(component) {
+				return nil, fmt.Errorf("unsupported component %s in %s", component, args.File)
+			}
+			s := spec
+			specs[component] = &s
+		}
+	}
+	for _, arg := range args.Requests {
+		component, value, err := splitComponentArg("requests", arg)
+		if err != nil {
+			return nil, err
+		}
+		list, err := parseResourceList(value)
+		if err != nil {
+			return nil, err
+		}
+		spec := get(component)
+		if spec.Resources.Requests == nil {
+			spec.Resources.Requests = corev1.ResourceList{}
+		}
+		for k, v := range list {
+			spec.Resources.Requests[k] = v
+		}
+	}
+	for _, arg := range args.Limits {
+		component, value, err := splitComponentArg("limits", arg)
+		if err != nil {
+			return nil, err
+		}
+		list, err := parseResourceList(value)
+		if err != nil {
+			return nil, err
+		}
+		spec := get(component)
+		if spec.Resources.Limits == nil {
+			spec.Resources.Limits = corev1.ResourceList{}
+		}
+		for k, v := range list {
+			spec.Resources.Limits[k] = v
+		}
+	}
+	if err := ValidateComponentSpecs(specs); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// ValidateComponentSpecs checks the components are supported and the requests are not greater than the limits
+func ValidateComponentSpecs(specs map[string]*ComponentSpec) error {
+	for component, spec := range specs {
+		if !ClusterComponentSupported(component) {
+			return fmt.Errorf("unsupported component %s", component)
+		}
+		for name, limit := range spec.Resources.Limits {
+			if request, ok := spec.Resources.Requests[name]; ok && request.Cmp(limit) > 0 {
+				return fmt.Errorf("the %s request %s of %s is greater than its limit %s", name, request.String(), component, limit.String())
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyComponentSpecs maps the component specs into the resources of the cluster set
+func ApplyComponentSpecs(clusterSet []nineinfrav1alpha1.ClusterInfo, features map[string]string, specs map[string]*ComponentSpec) ([]nineinfrav1alpha1.ClusterInfo, error) {
+	for _, component := range SortedKeys(specs) {
+		if !clusterComponentEnabled(component, features) {
+			return nil, fmt.Errorf("component %s is not a part of the ninecluster", component)
+		}
+		spec := specs[component]
+		var i int
+		clusterSet, i = ensureClusterInfo(clusterSet, features, nineinfrav1alpha1.ClusterType(component))
+		res := &clusterSet[i].Resource.ResourceRequirements
+		if len(spec.Resources.Requests) != 0 && res.Requests == nil {
+			res.Requests = corev1.ResourceList{}
+		}
+		for k, v := range spec.Resources.Requests {
+			res.Requests[k] = v
+		}
+		if len(spec.Resources.Limits) != 0 && res.Limits == nil {
+			res.Limits = corev1.ResourceList{}
+		}
+		for k, v := range spec.Resources.Limits {
+			res.Limits[k] = v
+		}
+	}
+	return clusterSet, nil
+}
@@ -16,6 +16,12 @@ const (
 	DefaultSqlAccessPassword = "kyuubi"
 	DefaultClusterSign       = "nine"
 )
+
+const (
+	DefaultStoragePoolLabelKey       = "directpv.min.io/storage-pool"
+	DefaultDirectPVCreatedByLabelKey = "directpv.min.io/created-by"
+	DefaultDirectPVCreatedByValue    = "kubectl-nine"
+)
 const (
 	DefaultPGRWSVCNameSuffix             = DefaultNineSuffix + "-pg-rw"
 	DefaultPGRWPortName                  = "postgres"
@@ -88,36 +94,47 @@ var (
 )
 
 var (
-	DEBUG                        = false
-	DefaultToolNifiSvcNodePort   = 31333
-	DefaultToolSupersetSvcType   = "NodePort"
-	DefaultToolNifiSvcType       = "NodePort"
-	DefaultToolAirflowSvcType    = "NodePort"
-	DefaultToolAirflowRepository = "nineinfra/airflow"
-	DefaultToolAirflowTag        = "2.7.3"
-	DefaultStorageClass          = "nineinfra-default"
-	DefaultToolNifiSideCarTag    = "1.36.1"
-	DefaultAccessHost            = ""
-	DefaultDorisAdminUser        = "root"
-	DefaultDorisAdminPassword    = ""
-	DefaultDorisDatabaseName     = "nineinfra"
-	DefaultDorisFERepo           = "selectdb/doris.fe-ubuntu"
-	DefaultDorisFEVersion        = "2.0.2"
-	DefaultDorisFERepoPullPolicy = "IfNotPresent"
-	DefaultDorisFEStoragePVSize  = 20
-	DefaultDorisBERepo           = "selectdb/doris.be-ubuntu"
-	DefaultDorisBEVersion        = "2.0.2"
-	DefaultDorisBERepoPullPolicy = "IfNotPresent"
-	DefaultDorisBEStoragePVSize  = 100
-	DefaultKyuubiUserName        = "hive"
-	DefaultKyuubiVersion         = "1.8.0"
-	DefaultScalaVersion          = "2.12"
-	DefaultMinioRepo             = "minio/minio"
-	DefaultMinioVersion          = "RELEASE.2023-09-07T02-05-02Z"
-	DefaultMinioRepoPullPolicy   = "IfNotPresent"
-	DefaultDataBaseVersion       = "v16.0.0"
+	DEBUG                              = false
+	DefaultToolNifiSvcNodePort         = 31333
+	DefaultToolSupersetSvcType         = "NodePort"
+	DefaultToolNifiSvcType             = "NodePort"
+	DefaultToolAirflowSvcType          = "NodePort"
+	DefaultToolAirflowRepository       = "nineinfra/airflow"
+	DefaultToolAirflowTag              = "2.7.3"
+	DefaultStorageClass                = "nineinfra-default"
+	DefaultToolNifiSideCarTag          = "1.36.1"
+	DefaultAccessHost                  = ""
+	DefaultDorisAdminUser              = "root"
+	DefaultDorisAdminPassword          = ""
+	DefaultDorisDatabaseName           = "nineinfra"
+	DefaultDorisFERepo                 = "selectdb/doris.fe-ubuntu"
+	DefaultDorisFEVersion              = "2.0.2"
+	DefaultDorisFERepoPullPolicy       = "IfNotPresent"
+	DefaultDorisFEStoragePVSize        = 20
+	DefaultDorisBERepo                 = "selectdb/doris.be-ubuntu"
+	DefaultDorisBEVersion              = "2.0.2"
+	DefaultDorisBERepoPullPolicy       = "IfNotPresent"
+	DefaultDorisBEStoragePVSize        = 100
+	DefaultKyuubiUserName              = "hive"
+	DefaultKyuubiVersion               = "1.8.0"
+	DefaultScalaVersion                = "2.12"
+	DefaultMinioRepo                   = "minio/minio"
+	DefaultMinioVersion                = "RELEASE.2023-09-07T02-05-02Z"
+	DefaultMinioRepoPullPolicy         = "IfNotPresent"
+	DefaultDataBaseVersion             = "v16.0.0"
+	DefaultClusterDataVolume           = 32
+	DefaultOlapVolume                  = 100
+	DefaultOlapExecutors         int32 = 3
 )
 
+// NineClusterComponentVersions is the compatibility matrix of the components could be upgraded,
+// the versions are in ascending order
+var NineClusterComponentVersions = map[string][]string{
+	"doris":      {"2.0.2", "2.0.3", "2.0.4", "2.1.0"},
+	"minio":      {"RELEASE.2023-09-07T02-05-02Z", "RELEASE.2023-11-20T22-40-07Z", "RELEASE.2024-01-16T16-07-38Z"},
+	"postgresql": {"v16.0.0", "v16.1.0", "v16.2.0"},
+}
+
 var (
 	OlapsSupported       = "doris"
 	MainStorageSupported = []string{FeaturesStorageValueHdfs, FeaturesStorageValueMinio}
@@ -153,6 +170,20 @@ var DefaultChartList = map[string]string{
 	"nineinfra":          "0.7.0",
 }
 
+// NineInfraChartDependencies are the charts must be ready before installing the chart,
+// the charts not listed can be installed in parallel
+var NineInfraChartDependencies = map[string][]string{
+	"nineinfra": {
+		"cloudnative-pg",
+		"kyuubi-operator",
+		"metastore-operator",
+		"zookeeper-operator",
+		"hdfs-operator",
+		"minio-operator",
+		"doris-operator",
+	},
+}
+
 var DefaultToolsChartList = map[string]string{
 	"airflow":   "1.12.0",
 	"superset":  "0.11.2",
@@ -12,11 +12,35 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	createDesc    = `'create' command create a NineCluster by the NineInfra`
-	createExample = ` kubectl nine create c1 --datavolume 16 --namespace c1-ns`
+	createExample = `1. Create a NineCluster
+   $ kubectl nine create c1 --data-volume 16 --namespace c1-ns
+
+2. Create a small NineCluster with the olap,the olap executors of the profile are overridden
+   $ kubectl nine create c1 --profile small --olap doris --olap-executors 2 --namespace c1-ns
+
+3. Create a NineCluster with a profile defined in ~/.nine/profiles.yaml
+   $ kubectl nine create c1 --profile my-profile --namespace c1-ns
+
+4. Create a NineCluster with the resources of the kyuubi and the doris bes
+   $ kubectl nine create c1 --olap doris --requests kyuubi=cpu=2,memory=4Gi \
+     --requests doris-be=cpu=4,memory=16Gi --limits doris-be=cpu=8,memory=32Gi --namespace c1-ns
+
+5. Create a NineCluster with the resources of the components in a file
+   $ kubectl nine create c1 --olap doris --component-spec components.yaml --namespace c1-ns
+
+6. Create the NineClusters in a file of the cluster specs or the NineCluster manifests
+   $ kubectl nine create -f clusters.yaml
+
+7. Render the NineCluster without creating it
+   $ kubectl nine create c1 --olap doris --namespace c1-ns --dry-run=client --output yaml
+
+8. Create a throwaway NineCluster which is deleted by the 'gc' command after 8 hours
+   $ kubectl nine create c1 --ttl 8h --namespace c1-ns`
 )
 
 var (
@@ -87,16 +111,27 @@ type ClusterOptions struct {
 	OlapStoragePool      string
 	OlapExecutors        int32
 	EnableKyuubiHA       bool
+	EnablePostgresqlCDC  bool
 	MainStorage          string
 	MetastoreStoragePool string
 	Olap                 string
+	ProfileName          string
+	ProfileFile          string
+	Profile              *ClusterProfile
+	ComponentSpecArgs    ComponentSpecArgs
+	ComponentSpecs       map[string]*ComponentSpec
+	TTL                  time.Duration
 }
 
 type createCmd struct {
 	out         io.Writer
 	errOut      io.Writer
-	output      bool
+	output      string
+	file        string
+	dryRun      string
+	force       bool
 	clusterOpts ClusterOptions
+	changed     map[string]bool
 }
 
 // Validate NineCluster Options
@@ -125,6 +160,9 @@ func (t ClusterOptions) Validate() error {
 			return errors.New(fmt.Sprintf("metastore storage pool %s may be not exist", t.MetastoreStoragePool))
 		}
 	}
+	if t.TTL < 0 {
+		return errors.New("--ttl should not be negative")
+	}
 	if t.MainStorage != "" {
 		if !CheckMainStorageValid(t.MainStorage) {
 			return errors.New(fmt.Sprintf("main storage pool %s is not supported,support [%s]", t.MainStorage, strings.Join(MainStorageSupported, ",")))
@@ -133,15 +171,123 @@ func (t ClusterOptions) Validate() error {
 	return nil
 }
 
+// GenFeaturesAndClusterSet returns the features and the user cluster set of the NineCluster by the options,
+// the component specs are applied on top of the profile
+func (t ClusterOptions) GenFeaturesAndClusterSet() (map[string]string, []nineinfrav1alpha1.ClusterInfo, error) {
+	var features = map[string]string{}
+	var userClusterSet []nineinfrav1alpha1.ClusterInfo
+	if t.Olap != "" {
+		features[FeaturesOlapKey] = t.Olap
+		dorisBe := *DorisBeClusterInfo.DeepCopy()
+		dorisBe.Resource.ResourceRequirements.Requests["storage"] =
+			*resource.NewQuantity(int64(t.OlapVolume*GiMultiplier), resource.BinarySI)
+		dorisBe.Resource.StorageClass = t.OlapStoragePool
+		dorisBe.Resource.Replicas = t.OlapExecutors
+		userClusterSet = append(userClusterSet, dorisBe)
+		dorisFe := *DorisFeClusterInfo.DeepCopy()
+		dorisFe.Resource.StorageClass = t.OlapStoragePool
+		userClusterSet = append(userClusterSet, dorisFe)
+	}
+	if t.StoragePool != "" {
+		minio := *MinioClusterInfo.DeepCopy()
+		minio.Resource.StorageClass = t.StoragePool
+		userClusterSet = append(userClusterSet, minio)
+	}
+	if t.MetastoreStoragePool != "" {
+		pg := *PGClusterInfo.DeepCopy()
+		pg.Resource.StorageClass = t.MetastoreStoragePool
+		userClusterSet = append(userClusterSet, pg)
+	}
+	if t.EnableKyuubiHA {
+		features[FeaturesKyuubiHAKey] = strconv.FormatBool(t.EnableKyuubiHA)
+	}
+	if t.EnablePostgresqlCDC {
+		features[FeaturesPostgresqlCDCKey] = strconv.FormatBool(t.EnablePostgresqlCDC)
+	}
+	if t.MainStorage != "" {
+		features[FeaturesStorageKey] = t.MainStorage
+	}
+	if t.Profile != nil {
+		userClusterSet = t.Profile.Apply(userClusterSet, features)
+		// the olap flags override the profile
+		if i := FindClusterInfo(userClusterSet, nineinfrav1alpha1.DorisBEClusterType); i >= 0 {
+			userClusterSet[i].Resource.Replicas = t.OlapExecutors
+			userClusterSet[i].Resource.ResourceRequirements.Requests["storage"] =
+				*resource.NewQuantity(int64(t.OlapVolume*GiMultiplier), resource.BinarySI)
+		}
+	}
+	if len(t.ComponentSpecs) != 0 {
+		var err error
+		userClusterSet, err = ApplyComponentSpecs(userClusterSet, features, t.ComponentSpecs)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return features, userClusterSet, nil
+}
+
+// NineCluster returns the NineCluster object by the options
+func (t ClusterOptions) NineCluster() (*nineinfrav1alpha1.NineCluster, error) {
+	features, userClusterSet, err := t.GenFeaturesAndClusterSet()
+	if err != nil {
+		return nil, err
+	}
+	nc := &nineinfrav1alpha1.NineCluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: nineinfrav1alpha1.GroupVersion.String(),
+			Kind:       NineClusterKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      t.Name,
+			Namespace: t.NS,
+		},
+		Spec: nineinfrav1alpha1.NineClusterSpec{
+			DataVolume: t.DataVolume,
+			Features:   features,
+			ClusterSet: userClusterSet,
+		},
+	}
+	SetNineClusterTTL(nc, t.TTL)
+	return nc, nil
+}
+
+// ApplyProfile loads the profile and takes its values for the options not changed by the flags
+func (t *ClusterOptions) ApplyProfile(changed map[string]bool) error {
+	if t.ProfileName == "" {
+		return nil
+	}
+	profile, err := LoadClusterProfile(t.ProfileName, t.ProfileFile)
+	if err != nil {
+		return err
+	}
+	t.Profile = profile
+	if profile.DataVolume > 0 && !changed["data-volume"] {
+		t.DataVolume = profile.DataVolume
+	}
+	if be, ok := profile.Components[string(nineinfrav1alpha1.DorisBEClusterType)]; ok {
+		if be.Replicas > 0 && !changed["olap-executors"] {
+			t.OlapExecutors = be.Replicas
+		}
+		if be.StorageGi() > 0 && !changed["olap-volume"] {
+			t.OlapVolume = be.StorageGi()
+		}
+	}
+	return nil
+}
+
 func newClusterCreateCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	c := &createCmd{out: out, errOut: errOut}
 
 	cmd := &cobra.Command{
-		Use:     "create <NINECLUSTERNAME> --datavolume <SIZE>",
+		Use:     "create <NINECLUSTERNAME> --datavolume <SIZE> | -f <FILE>",
 		Short:   "Create a NineCluster",
 		Long:    createDesc,
 		Example: createExample,
 		Args: func(cmd *cobra.Command, args []string) error {
+			c.changed = make(map[string]bool)
+			for _, flag := range []string{"data-volume", "olap-executors", "olap-volume"} {
+				c.changed[flag] = cmd.Flags().Changed(flag)
+			}
 			return c.validate(args)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -153,22 +299,46 @@ func newClusterCreateCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd = DisableHelp(cmd)
+	cmd.Flags().StringVarP(&c.file, "filename", "f", "", "file of the cluster specs or the NineCluster manifests,- for the stdin")
+	cmd.Flags().StringVar(&c.dryRun, "dry-run", DryRunNone, fmt.Sprintf("only print the NineClusters to be created,support [%s]", strings.Join(dryRunSupported, ",")))
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = DryRunClient
+	cmd.Flags().BoolVar(&c.force, "force", false, "only warn if the storage pools could not fit the ninecluster")
+	cmd.Flags().StringVar(&c.output, "output", "", fmt.Sprintf("output format of the NineClusters,support [%s]", strings.Join(outputSupported, ",")))
 	f := cmd.Flags()
-	f.IntVarP(&c.clusterOpts.DataVolume, "data-volume", "v", 32, "total raw data volumes of the ninecluster,the unit is Gi, e.g. 16")
-	f.StringVar(&c.clusterOpts.MainStorage, "main-storage", "minio", fmt.Sprintf("main storage for the ninecluster,support [%s]", strings.Join(MainStorageSupported, ",")))
+	f.IntVarP(&c.clusterOpts.DataVolume, "data-volume", "v", DefaultClusterDataVolume, "total raw data volumes of the ninecluster,the unit is Gi, e.g. 16")
+	f.StringVar(&c.clusterOpts.MainStorage, "main-storage", FeaturesStorageValueMinio, fmt.Sprintf("main storage for the ninecluster,support [%s]", strings.Join(MainStorageSupported, ",")))
 	f.StringVarP(&c.clusterOpts.Olap, "olap", "a", "", fmt.Sprintf("add olap to the ninecluster,support [%s]", OlapsSupported))
-	f.IntVar(&c.clusterOpts.OlapVolume, "olap-volume", 100, "olap storage volume size")
+	f.IntVar(&c.clusterOpts.OlapVolume, "olap-volume", DefaultOlapVolume, "olap storage volume size")
 	f.StringVarP(&c.clusterOpts.StoragePool, "storage-pool", "s", "", "storage pool for the ninecluster")
 	f.StringVarP(&c.clusterOpts.OlapStoragePool, "olap-storage-pool", "o", "", "storage pool for olap")
-	f.Int32VarP(&c.clusterOpts.OlapExecutors, "olap-executors", "r", 3, "num of the olap executors")
+	f.Int32VarP(&c.clusterOpts.OlapExecutors, "olap-executors", "r", DefaultOlapExecutors, "num of the olap executors")
 	f.StringVarP(&c.clusterOpts.MetastoreStoragePool, "metastore-storage-pool", "m", "", "storage pool for metastore")
 	f.BoolVar(&c.clusterOpts.EnableKyuubiHA, "enable-kyuubi-ha", false, "enable kyuubi with high availability")
+	f.BoolVar(&c.clusterOpts.EnablePostgresqlCDC, "enable-postgresql-cdc", false, "enable the cdc of the postgresql")
+	f.StringVar(&c.clusterOpts.ProfileName, "profile", "", fmt.Sprintf("sizing profile of the ninecluster,support [%s] and the profiles in the profile file", profilesSupported))
+	f.StringVar(&c.clusterOpts.ProfileFile, "profile-file", DefaultProfileFile(), "file of the user defined profiles")
+	f.StringVar(&c.clusterOpts.ComponentSpecArgs.File, "component-spec", "", "file of the resources of the components")
+	f.StringArrayVar(&c.clusterOpts.ComponentSpecArgs.Requests, "requests", nil, "resource requests of a component,e.g. kyuubi=cpu=2,memory=4Gi")
+	f.StringArrayVar(&c.clusterOpts.ComponentSpecArgs.Limits, "limits", nil, "resource limits of a component,e.g. kyuubi=cpu=4,memory=8Gi")
+	f.DurationVar(&c.clusterOpts.TTL, "ttl", 0, "time to live of the ninecluster,it is deleted by the 'gc' command after expired,e.g. 8h")
 	f.BoolVar(&DEBUG, "debug", false, "print debug information")
 	f.StringVarP(&c.clusterOpts.NS, "namespace", "n", "", "k8s namespace for this ninecluster")
 	return cmd
 }
 
 func (c *createCmd) validate(args []string) error {
+	if err := ValidateDryRunAndOutput(c.dryRun, c.output); err != nil {
+		return err
+	}
+	if c.file != "" {
+		if len(args) != 0 {
+			return errors.New("the ninecluster name could not be specified with -f")
+		}
+		if c.clusterOpts.TTL < 0 {
+			return errors.New("--ttl should not be negative")
+		}
+		return nil
+	}
 	if args == nil {
 		return errors.New("create command requires specifying the ninecluster name as an argument, e.g. 'kubectl nine create c1'")
 	}
@@ -186,72 +356,94 @@ func (c *createCmd) validate(args []string) error {
 	if c.clusterOpts.NS == "" {
 		return errors.New("--namespace flag is required")
 	}
+	if err := c.clusterOpts.ApplyProfile(c.changed); err != nil {
+		return err
+	}
+	specs, err := LoadComponentSpecs(c.clusterOpts.ComponentSpecArgs)
+	if err != nil {
+		return err
+	}
+	c.clusterOpts.ComponentSpecs = specs
 	return c.clusterOpts.Validate()
 }
 
 // run initializes local config and creates a NineCluster to Kubernetes cluster.
 func (c *createCmd) run(_ []string) error {
+	var clusters []*nineinfrav1alpha1.NineCluster
+	if c.file != "" {
+		var err error
+		clusters, err = LoadNineClusterFile(c.file, c.clusterOpts.ProfileFile)
+		if err != nil {
+			return err
+		}
+		for _, cluster := range clusters {
+			SetNineClusterTTL(cluster, c.clusterOpts.TTL)
+		}
+	} else {
+		desiredNineCluster, err := c.clusterOpts.NineCluster()
+		if err != nil {
+			return err
+		}
+		clusters = append(clusters, desiredNineCluster)
+	}
+	if c.dryRun == DryRunClient {
+		return PrintNineClusters(c.out, clusters, c.output)
+	}
+
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
 	nc, err := GetNineInfraClient(path)
 	if err != nil {
 		return err
 	}
-	var features = map[string]string{}
-	var userClusterSet []nineinfrav1alpha1.ClusterInfo
-	if c.clusterOpts.Olap != "" {
-		features[FeaturesOlapKey] = c.clusterOpts.Olap
-		DorisBeClusterInfo.Resource.ResourceRequirements.Requests["storage"] =
-			*resource.NewQuantity(int64(c.clusterOpts.OlapVolume*GiMultiplier), resource.BinarySI)
-		DorisBeClusterInfo.Resource.StorageClass = c.clusterOpts.OlapStoragePool
-		DorisBeClusterInfo.Resource.Replicas = c.clusterOpts.OlapExecutors
-		userClusterSet = append(userClusterSet, DorisBeClusterInfo)
-		DorisFeClusterInfo.Resource.StorageClass = c.clusterOpts.OlapStoragePool
-		userClusterSet = append(userClusterSet, DorisFeClusterInfo)
-	}
-	if c.clusterOpts.StoragePool != "" {
-		MinioClusterInfo.Resource.StorageClass = c.clusterOpts.StoragePool
-		userClusterSet = append(userClusterSet, MinioClusterInfo)
-	}
-	if c.clusterOpts.MetastoreStoragePool != "" {
-		PGClusterInfo.Resource.StorageClass = c.clusterOpts.MetastoreStoragePool
-		userClusterSet = append(userClusterSet, PGClusterInfo)
-	}
-	if c.clusterOpts.EnableKyuubiHA {
-		features[FeaturesKyuubiHAKey] = strconv.FormatBool(c.clusterOpts.EnableKyuubiHA)
+	for _, desiredNineCluster := range clusters {
+		exists, _ := CheckNineClusterExist(desiredNineCluster.Name, desiredNineCluster.Namespace)
+		if exists {
+			return errors.New("NineCluster:" + desiredNineCluster.Name + " already exists in namespace:" + desiredNineCluster.Namespace + "!")
+		}
 	}
-
-	features[FeaturesStorageKey] = c.clusterOpts.MainStorage
-
-	desiredNineCluster := &nineinfrav1alpha1.NineCluster{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      c.clusterOpts.Name,
-			Namespace: c.clusterOpts.NS,
-		},
-		Spec: nineinfrav1alpha1.NineClusterSpec{
-			DataVolume: c.clusterOpts.DataVolume,
-			Features:   features,
-			ClusterSet: userClusterSet,
-		},
+	var demands []StorageDemand
+	for _, desiredNineCluster := range clusters {
+		demands = append(demands, NineClusterStorageDemands(desiredNineCluster)...)
 	}
-
-	exists, _ := CheckNineClusterExist(c.clusterOpts.Name, c.clusterOpts.NS)
-	if exists {
-		return errors.New("NineCluster:" + c.clusterOpts.Name + " already exists in namespace:" + c.clusterOpts.NS + "!")
+	if err := CheckCapacity(demands, c.force); err != nil {
+		return err
 	}
 
-	if DEBUG {
-		fmt.Printf("Start to create a nine cluster,detail info:%v\n", desiredNineCluster)
+	var created []*nineinfrav1alpha1.NineCluster
+	for _, desiredNineCluster := range clusters {
+		if DEBUG {
+			fmt.Printf("Start to create a nine cluster,detail info:%v\n", desiredNineCluster)
+		}
+		opts := metav1.CreateOptions{}
+		if c.dryRun == DryRunServer {
+			opts.DryRun = []string{metav1.DryRunAll}
+		}
+		result, err := nc.NineinfraV1alpha1().NineClusters(desiredNineCluster.Namespace).Create(context.TODO(), desiredNineCluster, opts)
+		if err != nil {
+			return err
+		}
+		created = append(created, result)
+		if c.dryRun == DryRunServer {
+			if c.output == "" {
+				fmt.Println("NineCluster:" + result.Name + " in namespace:" + result.Namespace + " is created (server dry run)")
+			}
+			continue
+		}
+		RecordRevision(result, nil, "create")
+		fmt.Println("NineCluster:" + result.Name + " in namespace:" + result.Namespace + " is created successfully!")
 	}
-
-	_, err = nc.NineinfraV1alpha1().NineClusters(c.clusterOpts.NS).Create(context.TODO(), desiredNineCluster, metav1.CreateOptions{})
-	if err != nil {
-		return err
+	if c.dryRun == DryRunServer {
+		if c.output != "" {
+			return PrintNineClusters(c.out, created, c.output)
+		}
+		return nil
 	}
 
-	fmt.Println("NineCluster:" + c.clusterOpts.Name + " in namespace:" + c.clusterOpts.NS + " is created successfully!")
 	fmt.Println("It may take a few minutes for it to be ready")
 	fmt.Println("You can check its status using the following command：")
-	fmt.Println("kubectl nine show " + c.clusterOpts.Name + " -n " + c.clusterOpts.NS)
+	for _, desiredNineCluster := range created {
+		fmt.Println("kubectl nine show " + desiredNineCluster.Name + " -n " + desiredNineCluster.Namespace)
+	}
 
 	return nil
 }
@@ -72,7 +72,7 @@ func (o *dagCmd) uploadFileToAirflow(filename string, cluster *nineinfrav1alpha1
 	if err != nil {
 		return err
 	}
-	_, _, err = runCommand("kubectl", "cp", filename, fmt.Sprintf("%s:%s/%s", podNames[0], DefaultAirflowDagsPath, filepath.Base(filename)), "-n", cluster.Namespace)
+	_, _, err = runCommand("kubectl", kubectlArgs("cp", filename, fmt.Sprintf("%s:%s/%s", podNames[0], DefaultAirflowDagsPath, filepath.Base(filename)), "-n", cluster.Namespace)...)
 	if err != nil {
 		return err
 	}
@@ -89,7 +89,7 @@ func (o *dagCmd) list(parameters []string) error {
 	if err != nil {
 		return err
 	}
-	err = runCommandWithOSIO("kubectl", "exec", "-it", podNames[0], "-n", o.ns, "-c", "scheduler", "--", "/bin/ls", "-l", "/opt/airflow/dags")
+	err = runCommandWithOSIO("kubectl", kubectlArgs("exec", "-it", podNames[0], "-n", o.ns, "-c", "scheduler", "--", "/bin/ls", "-l", "/opt/airflow/dags")...)
 	if err != nil {
 		return err
 	}
@@ -2,24 +2,76 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
 	"github.com/spf13/cobra"
 	"io"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
+	"strings"
+	"time"
 )
 
 const (
 	deleteDesc = `
-'delete' command delete the NineCluster along with all the dependencies.`
-	deleteExample = `  kubectl nine delete c1 --namespace ns-c1`
+'delete' command delete the NineCluster along with all the dependencies.
+The tools,the etl configmaps and the tpcds spark ui services of the NineCluster are removed first,
+then the NineCluster itself,and with --delete-pvc its pvcs,the released pvs and the directpv volumes.`
+	deleteExample = `1. Delete a NineCluster
+   $ kubectl nine delete c1 --namespace ns-c1
+
+2. Delete a NineCluster with all its data in the CI
+   $ kubectl nine delete c1 --namespace ns-c1 --delete-pvc --yes
+
+3. Show the objects will be removed
+   $ kubectl nine delete c1 --namespace ns-c1 --delete-pvc --dry-run`
 )
 
+const (
+	PrintFmtStrDeletePlan = "%-12s\t%-24s\t%-60s\n"
+	DefaultDeleteTimeout  = 10 * time.Minute
+)
+
+const (
+	DeleteCategoryTool      = "tool"
+	DeleteCategoryEtl       = "etl"
+	DeleteCategoryService   = "service"
+	DeleteCategoryCluster   = "ninecluster"
+	DeleteCategoryPVC       = "pvc"
+	DeleteCategoryStorage   = "storage"
+	DeleteKindRelease       = "release"
+	DeleteKindConfigMap     = "configmap"
+	DeleteKindService       = "service"
+	DeleteKindNineCluster   = "ninecluster"
+	DeleteKindPVC           = "persistentvolumeclaim"
+	DeleteKindPV            = "persistentvolume"
+	DeleteKindDirectPVVol   = "directpvvolume"
+	DeleteEtlConfigPg2Hdfs  = "pg2hdfs"
+	DeleteEtlConfigPg2Doris = "pg2doris"
+)
+
+// deleteCategoryOrder is the order to execute the delete plan,the users of the NineCluster go first
+// and the storage is released after the NineCluster is gone
+var deleteCategoryOrder = []string{
+	DeleteCategoryTool,
+	DeleteCategoryEtl,
+	DeleteCategoryService,
+	DeleteCategoryCluster,
+	DeleteCategoryPVC,
+	DeleteCategoryStorage,
+}
+
 type DeleteOptions struct {
 	Name      string
 	NS        string
 	dangerous bool
 	deletePVC bool
+	yes       bool
+	dryRun    bool
+	timeout   time.Duration
 }
 
 type deleteCmd struct {
@@ -29,6 +81,38 @@ type deleteCmd struct {
 	deleteOpts DeleteOptions
 }
 
+// DeletePlanItem is an object which will be removed with the NineCluster
+type DeletePlanItem struct {
+	Category string
+	Kind     string
+	Name     string
+}
+
+// DeletePlan holds all the objects which will be removed with the NineCluster,grouped by category
+type DeletePlan struct {
+	Items map[string][]DeletePlanItem
+}
+
+func (p *DeletePlan) add(category string, kind string, name string) {
+	if p.Items == nil {
+		p.Items = make(map[string][]DeletePlanItem)
+	}
+	p.Items[category] = append(p.Items[category], DeletePlanItem{
+		Category: category,
+		Kind:     kind,
+		Name:     name,
+	})
+}
+
+func (p *DeletePlan) Print() {
+	fmt.Printf(PrintFmtStrDeletePlan, "CATEGORY", "KIND", "NAME")
+	for _, category := range deleteCategoryOrder {
+		for _, item := range p.Items[category] {
+			fmt.Printf(PrintFmtStrDeletePlan, item.Category, item.Kind, item.Name)
+		}
+	}
+}
+
 func newClusterDeleteCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	c := &deleteCmd{out: out, errOut: errOut}
 
@@ -41,11 +125,6 @@ func newClusterDeleteCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 			return c.validate(args)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !c.deleteOpts.dangerous {
-				fmt.Println("Please provide the i-know-it-is-dangerous flag to confirm deletion!")
-				return fmt.Errorf("Aborting NineCluster deletion")
-			}
-
 			err := c.run(args)
 			if err != nil {
 				klog.Warning(err)
@@ -58,7 +137,11 @@ func newClusterDeleteCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	f := cmd.Flags()
 	f.StringVarP(&c.deleteOpts.NS, "namespace", "n", "", "namespace scope for this request")
 	f.BoolVar(&c.deleteOpts.dangerous, "i-know-it-is-dangerous", false, "confirm deletion")
-	f.BoolVar(&c.deleteOpts.deletePVC, "delete-pvc", false, "delete the ninecluster's pvcs")
+	_ = f.MarkDeprecated("i-know-it-is-dangerous", "use --yes instead")
+	f.BoolVar(&c.deleteOpts.deletePVC, "delete-pvc", false, "delete the ninecluster's pvcs,the released pvs and the directpv volumes")
+	f.BoolVarP(&c.deleteOpts.yes, "yes", "y", false, "skip the confirmation prompts")
+	f.BoolVar(&c.deleteOpts.dryRun, "dry-run", false, "only print the objects will be removed")
+	f.DurationVar(&c.deleteOpts.timeout, "timeout", DefaultDeleteTimeout, "time to wait for the NineCluster and its pvcs to be removed")
 	cmd.MarkFlagRequired("namespace")
 
 	return cmd
@@ -72,113 +155,284 @@ func (d *deleteCmd) validate(args []string) error {
 	return ValidateClusterArgs("delete", args)
 }
 
-func constructMinioPVCLabel(name string) string {
-	return DefaultMinioPVCLabelKey + "=" + name
+// nineClusterOfName returns the cluster of the clusters whose resources the name is,empty if none.The longest
+// matched resource name wins since the cluster names could contain each other,e.g. data-nine-test-nine-ss-0-0
+// is of the cluster nine-test rather than test.The name embedding the resource name after the claim template
+// is matched if embedded is true,e.g. data-c1-nine-ss-0-0
+func nineClusterOfName(clusters []string, objName string, embedded bool) string {
+	owner, matched := "", ""
+	for _, c := range clusters {
+		prefix := NineResourceName(c)
+		ok := objName == prefix || strings.HasPrefix(objName, prefix+"-")
+		if embedded && !ok {
+			ok = strings.Contains(objName, "-"+prefix+"-") || strings.HasSuffix(objName, "-"+prefix)
+		}
+		if ok && len(prefix) > len(matched) {
+			owner, matched = c, prefix
+		}
+	}
+	return owner
 }
 
-func constructZookeeperPVCLabel(name string) string {
-	return fmt.Sprintf("%s=%s,%s=%s", DefaultClusterLabelKey, name, DefaultAppLabelKey, "zookeeper")
+// ownedByNineCluster returns true if the object is one of the resources of the NineCluster name,the others are
+// the other NineClusters in the namespace.The objects labeled by the NineCluster are matched by the label cluster,
+// otherwise the name or the label app.kubernetes.io/instance should be the resource name of the NineCluster,
+// e.g. c1-nine,c1-nine-doris-be or data-c1-nine-ss-0-0
+func ownedByNineCluster(name string, others []string, objName string, objLabels map[string]string) bool {
+	if objLabels["app"] == DefaultClusterSign && objLabels["cluster"] != "" {
+		return objLabels["cluster"] == name
+	}
+	clusters := append([]string{name}, others...)
+	if nineClusterOfName(clusters, objName, true) == name {
+		return true
+	}
+	if instance, ok := objLabels[DefaultZookeeperPVCLabelKey]; ok {
+		return nineClusterOfName(clusters, instance, false) == name
+	}
+	return false
 }
 
-func constructHdfsPVCLabel(name string) string {
-	return fmt.Sprintf("%s=%s,%s=%s", DefaultClusterLabelKey, name, DefaultAppLabelKey, "hdfs")
+// otherNineClusters returns the names of the NineClusters in the namespace except the name
+func otherNineClusters(name string, namespace string) ([]string, error) {
+	ncList, err := GetNineCLusters(namespace)
+	if ncList == nil {
+		return nil, err
+	}
+	others := make([]string, 0, len(ncList.Items))
+	for _, nc := range ncList.Items {
+		if nc.Name != name {
+			others = append(others, nc.Name)
+		}
+	}
+	return others, nil
 }
 
-func deleteNineInfraPVC(name string, namespace string) error {
-	if name == "" || namespace == "" {
-		return nil
-	}
+func (d *deleteCmd) planStorage(plan *DeletePlan) error {
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
-	c, err := GetKubeClient(path)
+	client, err := GetKubeClient(path)
 	if err != nil {
 		return err
 	}
-	// delete minio pvc
-	err = c.CoreV1().PersistentVolumeClaims(namespace).DeleteCollection(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: constructMinioPVCLabel(name)})
+	others, err := otherNineClusters(d.deleteOpts.Name, d.deleteOpts.NS)
 	if err != nil {
 		return err
 	}
-	// delete hdfs pvc
-	err = c.CoreV1().PersistentVolumeClaims(namespace).DeleteCollection(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: constructHdfsPVCLabel(name)})
+	pvcList, err := client.CoreV1().PersistentVolumeClaims(d.deleteOpts.NS).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		return err
 	}
+	for _, pvc := range pvcList.Items {
+		if !ownedByNineCluster(d.deleteOpts.Name, others, pvc.Name, pvc.Labels) {
+			continue
+		}
+		plan.add(DeleteCategoryPVC, DeleteKindPVC, pvc.Name)
+		if pvc.Spec.VolumeName == "" {
+			continue
+		}
+		pv, err := client.CoreV1().PersistentVolumes().Get(context.TODO(), pvc.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		plan.add(DeleteCategoryStorage, DeleteKindPV, pv.Name)
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == directpvv1beta1.Identity {
+			plan.add(DeleteCategoryStorage, DeleteKindDirectPVVol, pv.Name)
+		}
+	}
+
+	// the pvs left behind by the pvcs deleted before
+	pvList, err := client.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, pv := range pvList.Items {
+		if pv.Status.Phase != corev1.VolumeReleased || pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Namespace != d.deleteOpts.NS ||
+			!ownedByNineCluster(d.deleteOpts.Name, others, pv.Spec.ClaimRef.Name, nil) {
+			continue
+		}
+		plan.add(DeleteCategoryStorage, DeleteKindPV, pv.Name)
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == directpvv1beta1.Identity {
+			plan.add(DeleteCategoryStorage, DeleteKindDirectPVVol, pv.Name)
+		}
+	}
 	return nil
 }
 
-func deleteZookeeperPVC(name string, namespace string) error {
-	if name == "" || namespace == "" {
-		return nil
+// buildPlan collects all the objects owned by or labeled for the NineCluster
+func (d *deleteCmd) buildPlan() (*DeletePlan, error) {
+	plan := &DeletePlan{}
+	for _, tool := range SortedKeys(NineToolList) {
+		relName := NineResourceName(d.deleteOpts.Name, tool)
+		if CheckHelmReleaseExist(relName, d.deleteOpts.NS) {
+			plan.add(DeleteCategoryTool, DeleteKindRelease, relName)
+		}
 	}
+
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
-	c, err := GetKubeClient(path)
+	client, err := GetKubeClient(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	err = c.CoreV1().PersistentVolumeClaims(namespace).DeleteCollection(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: constructZookeeperPVCLabel(name)})
+	for _, suffix := range []string{DeleteEtlConfigPg2Hdfs, DeleteEtlConfigPg2Doris} {
+		cmName := NineResourceName(d.deleteOpts.Name, suffix)
+		_, err := client.CoreV1().ConfigMaps(d.deleteOpts.NS).Get(context.TODO(), cmName, metav1.GetOptions{})
+		if err == nil {
+			plan.add(DeleteCategoryEtl, DeleteKindConfigMap, cmName)
+		} else if !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	// the tpcds services are shared by all the NineClusters in the namespace
+	others, err := otherNineClusters(d.deleteOpts.Name, d.deleteOpts.NS)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(others) != 0 {
+		fmt.Printf("Keep the %s services used by the NineClusters %s\n", DefaultTPCDSPrefix, strings.Join(others, ","))
+	} else {
+		for _, svcName := range []string{DefaultTPCDSPrefix + "-cluster", DefaultTPCDSPrefix + "-client"} {
+			_, err := client.CoreV1().Services(d.deleteOpts.NS).Get(context.TODO(), svcName, metav1.GetOptions{})
+			if err == nil {
+				plan.add(DeleteCategoryService, DeleteKindService, svcName)
+			} else if !k8serrors.IsNotFound(err) {
+				return nil, err
+			}
+		}
 	}
 
-	return nil
+	if exists, _ := CheckNineClusterExist(d.deleteOpts.Name, d.deleteOpts.NS); exists {
+		plan.add(DeleteCategoryCluster, DeleteKindNineCluster, d.deleteOpts.Name)
+	}
+	if d.deleteOpts.deletePVC {
+		if err := d.planStorage(plan); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
 }
 
-func deleteOlapPVC(name string, namespace string) error {
-	if name == "" || namespace == "" {
-		return nil
+// waitGone waits until the get returns not found
+func waitGone(get func() error, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := get()
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for the deletion")
+		}
+		time.Sleep(3 * time.Second)
 	}
+}
+
+func (d *deleteCmd) removeItem(item DeletePlanItem, flags string, deadline time.Time) error {
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
-	c, err := GetKubeClient(path)
+	client, err := GetKubeClient(path)
 	if err != nil {
 		return err
 	}
-
-	olapPvcLabel := DefaultOlapPVCLabelKey + "=" + name + DefaultDorisBENameSuffix
-	err = c.CoreV1().PersistentVolumeClaims(namespace).DeleteCollection(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: olapPvcLabel})
-	if err != nil {
-		return err
+	ns := d.deleteOpts.NS
+	switch item.Kind {
+	case DeleteKindRelease:
+		return HelmUnInstall(item.Name, ns, flags)
+	case DeleteKindConfigMap:
+		err = client.CoreV1().ConfigMaps(ns).Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+	case DeleteKindService:
+		err = client.CoreV1().Services(ns).Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+	case DeleteKindNineCluster:
+		nclient, cerr := GetNineInfraClient(path)
+		if cerr != nil {
+			return cerr
+		}
+		err = nclient.NineinfraV1alpha1().NineClusters(ns).Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		err = waitGone(func() error {
+			_, err := nclient.NineinfraV1alpha1().NineClusters(ns).Get(context.TODO(), item.Name, metav1.GetOptions{})
+			return err
+		}, time.Until(deadline))
+	case DeleteKindPVC:
+		err = client.CoreV1().PersistentVolumeClaims(ns).Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		err = waitGone(func() error {
+			_, err := client.CoreV1().PersistentVolumeClaims(ns).Get(context.TODO(), item.Name, metav1.GetOptions{})
+			return err
+		}, time.Until(deadline))
+	case DeleteKindPV:
+		err = client.CoreV1().PersistentVolumes().Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+	case DeleteKindDirectPVVol:
+		// the volume is released by the directpv after its pv is deleted,only wait for it
+		dpclient, cerr := GetDirectPVClient(path)
+		if cerr != nil {
+			return cerr
+		}
+		err = waitGone(func() error {
+			_, err := dpclient.DirectPVVolumes().Get(context.TODO(), item.Name, metav1.GetOptions{})
+			return err
+		}, time.Until(deadline))
 	}
-	olapPvcLabel = DefaultOlapPVCLabelKey + "=" + name + DefaultDorisFENameSuffix
-	err = c.CoreV1().PersistentVolumeClaims(namespace).DeleteCollection(context.TODO(), metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: olapPvcLabel})
-	if err != nil {
+	if err != nil && !k8serrors.IsNotFound(err) {
 		return err
 	}
+	fmt.Printf("Delete %s %s successfully!\n", item.Kind, item.Name)
 	return nil
 }
 
 func (d *deleteCmd) run(args []string) error {
-	path, _ := rootCmd.Flags().GetString(kubeconfig)
-	nc, err := GetNineInfraClient(path)
-	if err != nil {
-		return err
+	exists, _ := CheckNineClusterExist(d.deleteOpts.Name, d.deleteOpts.NS)
+	if !exists && !d.deleteOpts.deletePVC {
+		return errors.New("NineCluster:" + d.deleteOpts.Name + " not found in namespace:" + d.deleteOpts.NS)
 	}
-
-	err = nc.NineinfraV1alpha1().NineClusters(d.deleteOpts.NS).Delete(context.TODO(), args[0], metav1.DeleteOptions{})
+	plan, err := d.buildPlan()
 	if err != nil {
 		return err
 	}
+	plan.Print()
+	if d.deleteOpts.dryRun {
+		return nil
+	}
+	if !d.deleteOpts.yes && !d.deleteOpts.dangerous {
+		if !Ask("All the objects above will be removed, are you sure you want to continue") {
+			return errors.New("aborting NineCluster deletion")
+		}
+		if len(plan.Items[DeleteCategoryPVC]) != 0 &&
+			!Ask("This is irreversible, are you sure you want to delete all pvcs of this NineCluster") {
+			return errors.New("aborting NineCluster deletion")
+		}
+	}
 
-	if d.deleteOpts.deletePVC {
-		if Ask("This is irreversible, are you sure you want to delete all pvcs of this NineCluster") {
-			fmt.Println("All PVCs used by Nine will be deleted")
-			err := deleteNineInfraPVC(d.deleteOpts.Name+DefaultNineSuffix, d.deleteOpts.NS)
-			if err != nil {
-				return err
-			}
-			err = deleteOlapPVC(d.deleteOpts.Name+DefaultNineSuffix, d.deleteOpts.NS)
-			if err != nil {
-				return err
-			}
-			err = deleteZookeeperPVC(d.deleteOpts.Name+DefaultNineSuffix, d.deleteOpts.NS)
-			if err != nil {
-				return err
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	parameters := []string{}
+	if path != "" {
+		parameters = append(parameters, []string{"--kubeconfig", path}...)
+	}
+	flags := strings.Join(parameters, " ")
+	deadline := time.Now().Add(d.deleteOpts.timeout)
+	var failed []string
+	for _, category := range deleteCategoryOrder {
+		for _, item := range plan.Items[category] {
+			if err := d.removeItem(item, flags, deadline); err != nil {
+				fmt.Printf("Error: %v \n", err)
+				failed = append(failed, fmt.Sprintf("%s %s: %v", item.Kind, item.Name, err))
 			}
-		} else {
-			fmt.Println("All PVCs used by Nine will not be deleted")
 		}
 	}
+	if len(failed) != 0 {
+		fmt.Println("The following objects could not be removed:")
+		for _, f := range failed {
+			fmt.Println("  " + f)
+		}
+		return fmt.Errorf("NineCluster:%s in namespace:%s is deleted partially", d.deleteOpts.Name, d.deleteOpts.NS)
+	}
 	fmt.Println("NineCluster:" + d.deleteOpts.Name + " in namespace:" + d.deleteOpts.NS + " is deleted successfully!")
-	fmt.Println("It may take a few minutes for it to be deleted completely")
 	return nil
 }
@@ -0,0 +1,40 @@
+package cmd
+
+import "testing"
+
+func TestOwnedByNineCluster(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster string
+		others  []string
+		objName string
+		labels  map[string]string
+		want    bool
+	}{
+		{"resource name", "c1", nil, "c1-nine", nil, true},
+		{"resource name with suffix", "c1", nil, "c1-nine-doris-be", nil, true},
+		{"claim of statefulset", "c1", nil, "data-c1-nine-ss-0-0", nil, true},
+		{"claim with hyphenated template", "c1", nil, "be-storage-c1-nine-doris-be-0", nil, true},
+		{"other cluster", "c1", []string{"c2"}, "data-c2-nine-ss-0-0", nil, false},
+		{"cluster name is a prefix", "c1", []string{"c10"}, "c10-nine-doris-be", nil, false},
+		{"cluster name is a suffix of other", "test", []string{"nine-test"}, "nine-test-nine-doris-be", nil, false},
+		{"claim of cluster name is a suffix of other", "test", []string{"nine-test"}, "data-nine-test-nine-ss-0-0", nil, false},
+		{"claim of other containing the name", "test", []string{"test-nine-x"}, "data-test-nine-x-nine-ss-0-0", nil, false},
+		{"claim of the longer cluster", "nine-test", []string{"test"}, "data-nine-test-nine-ss-0-0", nil, true},
+		{"unrelated name", "c1", nil, "c1-data", nil, false},
+		{"unrelated name containing the cluster", "c1", nil, "xc1-nine-0", nil, false},
+		{"labeled by the cluster", "c1", nil, "pg-0", map[string]string{"app": DefaultClusterSign, "cluster": "c1"}, true},
+		{"labeled by other cluster", "c1", []string{"c2"}, "data-c1-nine-0", map[string]string{"app": DefaultClusterSign, "cluster": "c2"}, false},
+		{"cluster label of other app", "c1", nil, "data-c1-nine-0", map[string]string{"cluster": "c2"}, true},
+		{"instance label", "c1", nil, "zk-data-0", map[string]string{DefaultZookeeperPVCLabelKey: "c1-nine-zookeeper"}, true},
+		{"instance label of other cluster", "test", []string{"nine-test"}, "zk-data-0", map[string]string{DefaultZookeeperPVCLabelKey: "nine-test-nine-zookeeper"}, false},
+		{"other label values are ignored", "c1", nil, "zk-data-0", map[string]string{"release": "c1-nine"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownedByNineCluster(tt.cluster, tt.others, tt.objName, tt.labels); got != tt.want {
+				t.Errorf("ownedByNineCluster(%q, %v, %q, %v) = %v, want %v", tt.cluster, tt.others, tt.objName, tt.labels, got, tt.want)
+			}
+		})
+	}
+}
@@ -54,14 +54,7 @@ func (d *describeCmd) validate(args []string) error {
 }
 
 func (d *describeCmd) run(_ []string) error {
-	path, _ := rootCmd.Flags().GetString(kubeconfig)
-
-	parameters := []string{"describe", "ninecluster", d.name, "-n", d.ns}
-	if path != "" {
-		parameters = append(parameters, []string{"--kubeconfig", path}...)
-	}
-
-	cmd := exec.Command("kubectl", parameters...)
+	cmd := exec.Command("kubectl", kubectlArgs("describe", "ninecluster", d.name, "-n", d.ns)...)
 
 	stdoutReader, _ := cmd.StdoutPipe()
 	stdoutScanner := bufio.NewScanner(stdoutReader)
@@ -1,12 +1,26 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
 	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 )
 
 const (
-	DefaultCMDDirectPVURL         = "https://github.com/minio/directpv/releases/download/v4.0.9/kubectl-directpv_4.0.9_linux_amd64"
-	DefaultCMDDirectPVInstallPath = "/usr/local/bin/"
+	DefaultDirectPVInitConfigVersion = "v1"
+	PrintFmtStrDiscoveredDevice      = "%-40s\t%-15s\t%-8s\t%-10s\t%-10s\t%-20s\t%-9s\t%-s\n"
+	PrintFmtStrInitResult            = "%-20s\t%-15s\t%-8s\t%-s\n"
 )
 
 func CheckDirectPVCmdExist() bool {
@@ -18,15 +32,7 @@ func CheckDirectPVCmdExist() bool {
 }
 
 func InstallDirectPVCmd() error {
-	_, _, err := runCommand("curl", "-o", DefaultCMDDirectPVInstallPath+DefaultCMDDirectPV, "-fsSL", DefaultCMDDirectPVURL)
-	if err != nil {
-		return err
-	}
-	_, _, err = runCommand("chmod", "0755", DefaultCMDDirectPVInstallPath+DefaultCMDDirectPV)
-	if err != nil {
-		return err
-	}
-	return nil
+	return InstallTool(DefaultCMDDirectPV, DefaultToolImportDir)
 }
 
 func InitDirectPV() error {
@@ -38,3 +44,441 @@ func InitDirectPV() error {
 	}
 	return nil
 }
+
+// DiscoveredDevice is a device reported by the directpv node server of a node
+type DiscoveredDevice struct {
+	Node string
+	directpvv1beta1.Device
+}
+
+// Available returns true if the device could be initialized as a directpv drive
+func (d DiscoveredDevice) Available() bool {
+	return d.DeniedReason == ""
+}
+
+// InitConfigDrive is a drive in the init config
+type InitConfigDrive struct {
+	ID     string `yaml:"id"`
+	Name   string `yaml:"name"`
+	Size   uint64 `yaml:"size"`
+	Make   string `yaml:"make"`
+	FS     string `yaml:"fs,omitempty"`
+	Select string `yaml:"select"`
+}
+
+// InitConfigNode is a node with its drives in the init config
+type InitConfigNode struct {
+	Name   string            `yaml:"name"`
+	Drives []InitConfigDrive `yaml:"drives"`
+}
+
+// InitConfig is the drives to be initialized,in the format of the init config of the kubectl-directpv
+type InitConfig struct {
+	Version string           `yaml:"version"`
+	Nodes   []InitConfigNode `yaml:"nodes"`
+}
+
+// InitResult is the result of the initialization of a drive
+type InitResult struct {
+	RequestID string
+	Node      string
+	Drive     string
+	Error     string
+}
+
+var ellipsesRegexp = regexp.MustCompile(`\{([0-9a-zA-Z]+)\.\.\.([0-9a-zA-Z]+)\}`)
+
+// expandRange returns the values of the range of an ellipses pattern,the numbers keep the width of the start
+// if it is zero padded,the letters are in the same case
+func expandRange(start string, end string) ([]string, error) {
+	var values []string
+	s, errStart := strconv.Atoi(start)
+	e, errEnd := strconv.Atoi(end)
+	switch {
+	case errStart == nil && errEnd == nil:
+		if s > e {
+			return nil, fmt.Errorf("invalid ellipses range {%s...%s}", start, end)
+		}
+		format := "%d"
+		if len(start) > 1 && start[0] == '0' {
+			format = "%0" + strconv.Itoa(len(start)) + "d"
+		}
+		for i := s; i <= e; i++ {
+			values = append(values, fmt.Sprintf(format, i))
+		}
+	case len(start) == 1 && len(end) == 1 && unicode.IsLetter(rune(start[0])) && unicode.IsLetter(rune(end[0])) &&
+		unicode.IsLower(rune(start[0])) == unicode.IsLower(rune(end[0])):
+		if start[0] > end[0] {
+			return nil, fmt.Errorf("invalid ellipses range {%s...%s}", start, end)
+		}
+		for c := start[0]; c <= end[0]; c++ {
+			values = append(values, string(c))
+		}
+	default:
+		return nil, fmt.Errorf("invalid ellipses range {%s...%s}", start, end)
+	}
+	return values, nil
+}
+
+// ExpandEllipses expands the ellipses patterns,e.g. node{1...3} to node1,node2,node3 and sd{a...b}{1...2} to
+// sda1,sda2,sdb1,sdb2
+func ExpandEllipses(patterns []string) ([]string, error) {
+	var values []string
+	for _, pattern := range patterns {
+		loc := ellipsesRegexp.FindStringSubmatchIndex(pattern)
+		if loc == nil {
+			values = append(values, pattern)
+			continue
+		}
+		ranges, err := expandRange(pattern[loc[2]:loc[3]], pattern[loc[4]:loc[5]])
+		if err != nil {
+			return nil, err
+		}
+		rests, err := ExpandEllipses([]string{pattern[loc[1]:]})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range ranges {
+			for _, rest := range rests {
+				values = append(values, pattern[:loc[0]]+r+rest)
+			}
+		}
+	}
+	return values, nil
+}
+
+// driveFilter returns the set of the expanded patterns without the /dev/ prefix,nil matches all
+func driveFilter(patterns []string) (map[string]bool, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	values, err := ExpandEllipses(patterns)
+	if err != nil {
+		return nil, err
+	}
+	filter := make(map[string]bool)
+	for _, v := range values {
+		filter[strings.TrimPrefix(v, "/dev/")] = true
+	}
+	return filter, nil
+}
+
+// refreshDirectPVNodes asks the directpv node servers to probe the devices again and waits for them to finish,
+// the devices known are used for the nodes not refreshed in time
+func refreshDirectPVNodes(dpclient *directpvv1beta1.DirectpvV1beta1Client, nodes []directpvv1beta1.DirectPVNode, timeout time.Duration) {
+	pending := make(map[string]bool)
+	for i := range nodes {
+		nodes[i].Spec.Refresh = true
+		if _, err := dpclient.DirectPVNodes().Update(context.TODO(), &nodes[i], metav1.UpdateOptions{}); err != nil {
+			fmt.Printf("Warning: refresh the devices of node %s failed,err:%v\n", nodes[i].Name, err)
+			continue
+		}
+		pending[nodes[i].Name] = true
+	}
+	deadline := time.Now().Add(timeout)
+	for len(pending) != 0 && time.Now().Before(deadline) {
+		time.Sleep(2 * time.Second)
+		for name := range pending {
+			node, err := dpclient.DirectPVNodes().Get(context.TODO(), name, metav1.GetOptions{})
+			if err == nil && !node.Spec.Refresh {
+				delete(pending, name)
+			}
+		}
+	}
+	for name := range pending {
+		fmt.Printf("Warning: the devices of node %s are not refreshed in %s\n", name, timeout)
+	}
+}
+
+// DiscoverDevices returns the devices of the directpv nodes matching the node and drive patterns,the devices not
+// available are included if all is true
+func DiscoverDevices(nodePatterns []string, drivePatterns []string, all bool, timeout time.Duration) ([]DiscoveredDevice, error) {
+	nodeFilter, err := driveFilter(nodePatterns)
+	if err != nil {
+		return nil, err
+	}
+	deviceFilter, err := driveFilter(drivePatterns)
+	if err != nil {
+		return nil, err
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return nil, err
+	}
+	nodeList, err := dpclient.DirectPVNodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var nodes []directpvv1beta1.DirectPVNode
+	for _, node := range nodeList.Items {
+		if nodeFilter == nil || nodeFilter[node.Name] {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("no directpv node found,check the directpv is installed and the --nodes")
+	}
+	refreshDirectPVNodes(dpclient, nodes, timeout)
+
+	var devices []DiscoveredDevice
+	for _, n := range nodes {
+		node, err := dpclient.DirectPVNodes().Get(context.TODO(), n.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range node.Status.Devices {
+			if deviceFilter != nil && !deviceFilter[device.Name] {
+				continue
+			}
+			d := DiscoveredDevice{Node: node.Name, Device: device}
+			if all || d.Available() {
+				devices = append(devices, d)
+			}
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Node == devices[j].Node {
+			return devices[i].Name < devices[j].Name
+		}
+		return devices[i].Node < devices[j].Node
+	})
+	return devices, nil
+}
+
+// PrintDiscoveredDevices prints the devices as the discover command of the kubectl-directpv
+func PrintDiscoveredDevices(devices []DiscoveredDevice, noHeaders bool) {
+	if !noHeaders {
+		fmt.Printf(PrintFmtStrDiscoveredDevice, "ID", "NODE", "DRIVE", "SIZE", "FILESYSTEM", "MAKE", "AVAILABLE", "DESCRIPTION")
+	}
+	for _, d := range devices {
+		available, fsType, driveMake := "YES", d.FSType, d.Make
+		if !d.Available() {
+			available = "NO"
+		}
+		if fsType == "" {
+			fsType = "-"
+		}
+		if driveMake == "" {
+			driveMake = "-"
+		}
+		fmt.Printf(PrintFmtStrDiscoveredDevice, d.ID, d.Node, d.Name, formatBytes(int64(d.Size)), fsType, driveMake, available, d.DeniedReason)
+	}
+}
+
+// WriteInitConfig writes the available devices into the init config file
+func WriteInitConfig(file string, devices []DiscoveredDevice) error {
+	config := InitConfig{Version: DefaultDirectPVInitConfigVersion}
+	index := make(map[string]int)
+	for _, d := range devices {
+		if !d.Available() {
+			continue
+		}
+		i, ok := index[d.Node]
+		if !ok {
+			i = len(config.Nodes)
+			index[d.Node] = i
+			config.Nodes = append(config.Nodes, InitConfigNode{Name: d.Node})
+		}
+		config.Nodes[i].Drives = append(config.Nodes[i].Drives, InitConfigDrive{
+			ID:     d.ID,
+			Name:   d.Name,
+			Size:   d.Size,
+			Make:   d.Make,
+			FS:     d.FSType,
+			Select: "yes",
+		})
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// InitDevices creates an init request per node for the available devices and waits for the node servers to process
+// them,the devices with a filesystem are formatted by force.The requests are removed after processed
+func InitDevices(devices []DiscoveredDevice, timeout time.Duration) ([]InitResult, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return nil, err
+	}
+	initDevices := make(map[string][]directpvv1beta1.InitDevice)
+	for _, d := range devices {
+		if d.Available() {
+			initDevices[d.Node] = append(initDevices[d.Node], directpvv1beta1.InitDevice{ID: d.ID, Name: d.Name, Force: d.FSType != ""})
+		}
+	}
+	requestID := "nine-" + time.Now().Format("20060102150405")
+	requests := make(map[string]string)
+	for _, node := range SortedKeys(initDevices) {
+		req := directpvv1beta1.NewDirectPVInitRequest(requestID, directpvv1beta1.NodeID(node), initDevices[node])
+		created, err := dpclient.DirectPVInitRequests().Create(context.TODO(), req, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		requests[created.Name] = node
+	}
+	defer func() {
+		for name := range requests {
+			_ = dpclient.DirectPVInitRequests().Delete(context.TODO(), name, metav1.DeleteOptions{})
+		}
+	}()
+
+	var results []InitResult
+	pending := make(map[string]string)
+	for name, node := range requests {
+		pending[name] = node
+	}
+	deadline := time.Now().Add(timeout)
+	for len(pending) != 0 && time.Now().Before(deadline) {
+		time.Sleep(2 * time.Second)
+		for name, node := range pending {
+			req, err := dpclient.DirectPVInitRequests().Get(context.TODO(), name, metav1.GetOptions{})
+			if err != nil || req.Status.Status == directpvv1beta1.InitStatusPending {
+				continue
+			}
+			for _, r := range req.Status.Results {
+				results = append(results, InitResult{RequestID: name, Node: node, Drive: r.Name, Error: r.Error})
+			}
+			if req.Status.Status == directpvv1beta1.InitStatusError && len(req.Status.Results) == 0 {
+				results = append(results, InitResult{RequestID: name, Node: node, Drive: "-", Error: "the init request failed"})
+			}
+			delete(pending, name)
+		}
+	}
+	for name, node := range pending {
+		for _, d := range initDevices[node] {
+			results = append(results, InitResult{RequestID: name, Node: node, Drive: d.Name, Error: fmt.Sprintf("not processed in %s", timeout)})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Node == results[j].Node {
+			return results[i].Drive < results[j].Drive
+		}
+		return results[i].Node < results[j].Node
+	})
+	return results, nil
+}
+
+// PrintInitResults prints the results of the initialization as the init command of the kubectl-directpv
+func PrintInitResults(results []InitResult) {
+	fmt.Printf(PrintFmtStrInitResult, "REQUEST_ID", "NODE", "DRIVE", "MESSAGE")
+	for _, r := range results {
+		message := "Success"
+		if r.Error != "" {
+			message = r.Error
+		}
+		fmt.Printf(PrintFmtStrInitResult, r.RequestID, r.Node, r.Drive, message)
+	}
+}
+
+// driveKey returns the key of a drive on a node,e.g. node1/sdb
+func driveKey(node string, drive string) string {
+	return node + "/" + strings.TrimPrefix(drive, "/dev/")
+}
+
+// InitializedDrives returns the keys of the drives initialized successfully
+func InitializedDrives(results []InitResult) map[string]bool {
+	drives := make(map[string]bool)
+	for _, r := range results {
+		if r.Error == "" {
+			drives[driveKey(r.Node, r.Drive)] = true
+		}
+	}
+	return drives
+}
+
+// AvailableDrives returns the keys of the devices which could be initialized
+func AvailableDrives(devices []DiscoveredDevice) map[string]bool {
+	drives := make(map[string]bool)
+	for _, d := range devices {
+		if d.Available() {
+			drives[driveKey(d.Node, d.Name)] = true
+		}
+	}
+	return drives
+}
+
+// labelConflict returns why the drive should not be labeled with the storage pool,empty if none
+func labelConflict(drive *directpvv1beta1.DirectPVDrive, pool string) string {
+	if current := drive.Labels[DefaultStoragePoolLabelKey]; current != "" && current != pool {
+		return fmt.Sprintf("drive %s on node %s is in the storage pool %s", drive.GetDriveName(), drive.GetNodeID(), current)
+	}
+	if n := drive.GetVolumeCount(); n > 0 {
+		return fmt.Sprintf("drive %s on node %s holds %d volumes", drive.GetDriveName(), drive.GetNodeID(), n)
+	}
+	return ""
+}
+
+// LabelDrives labels the given directpv drives with the storage pool,the drives in another storage pool or
+// holding volumes are refused unless force,the labeled drives are returned
+func LabelDrives(targets map[string]bool, pool string, force bool, dryRun bool) ([]directpvv1beta1.DirectPVDrive, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return nil, err
+	}
+	drives, err := dpclient.DirectPVDrives().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	suffix := ""
+	if dryRun {
+		suffix = " (dry run)"
+	}
+	found := make(map[string]bool)
+	var selected []directpvv1beta1.DirectPVDrive
+	var conflicts []string
+	for _, drive := range drives.Items {
+		key := driveKey(string(drive.GetNodeID()), string(drive.GetDriveName()))
+		if !targets[key] {
+			continue
+		}
+		found[key] = true
+		if drive.Labels[DefaultStoragePoolLabelKey] == pool {
+			continue
+		}
+		if conflict := labelConflict(&drive, pool); conflict != "" {
+			conflicts = append(conflicts, conflict)
+		}
+		selected = append(selected, drive)
+	}
+	if len(conflicts) != 0 && !force {
+		return nil, fmt.Errorf("refuse to label the drives with %s=%s,%s,use --force to relabel them",
+			DefaultStoragePoolLabelKey, pool, strings.Join(conflicts, ","))
+	}
+	var missing []string
+	for _, key := range SortedKeys(targets) {
+		if found[key] {
+			continue
+		}
+		if !dryRun {
+			missing = append(missing, key)
+			continue
+		}
+		node, name, _ := strings.Cut(key, "/")
+		fmt.Printf("Label the drive %s on node %s with %s=%s%s\n", name, node, DefaultStoragePoolLabelKey, pool, suffix)
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("drives %s not found", strings.Join(missing, ","))
+	}
+
+	var labeled []directpvv1beta1.DirectPVDrive
+	for _, drive := range selected {
+		if !dryRun {
+			if drive.Labels == nil {
+				drive.Labels = make(map[string]string)
+			}
+			drive.Labels[DefaultStoragePoolLabelKey] = pool
+			if _, err := dpclient.DirectPVDrives().Update(context.TODO(), &drive, metav1.UpdateOptions{}); err != nil {
+				return labeled, err
+			}
+		}
+		labeled = append(labeled, drive)
+		fmt.Printf("Label the drive %s on node %s with %s=%s%s\n", drive.GetDriveName(), drive.GetNodeID(),
+			DefaultStoragePoolLabelKey, pool, suffix)
+	}
+	return labeled, nil
+}
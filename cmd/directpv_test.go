@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
+)
+
+func TestExpandEllipses(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{"no pattern", []string{"node1", "node2"}, []string{"node1", "node2"}, false},
+		{"numbers", []string{"node{1...3}"}, []string{"node1", "node2", "node3"}, false},
+		{"zero padded", []string{"node{08...10}"}, []string{"node08", "node09", "node10"}, false},
+		{"letters", []string{"sd{a...c}"}, []string{"sda", "sdb", "sdc"}, false},
+		{"upper letters", []string{"SD{A...B}"}, []string{"SDA", "SDB"}, false},
+		{"multiple ellipses", []string{"sd{a...b}{1...2}"}, []string{"sda1", "sda2", "sdb1", "sdb2"}, false},
+		{"suffix", []string{"node{1...2}.local"}, []string{"node1.local", "node2.local"}, false},
+		{"multiple patterns", []string{"sda", "nvme{0...1}n1"}, []string{"sda", "nvme0n1", "nvme1n1"}, false},
+		{"single value range", []string{"node{5...5}"}, []string{"node5"}, false},
+		{"descending numbers", []string{"node{3...1}"}, nil, true},
+		{"descending letters", []string{"sd{c...a}"}, nil, true},
+		{"mixed case", []string{"sd{a...C}"}, nil, true},
+		{"letter and number", []string{"sd{a...3}"}, nil, true},
+		{"multi letters", []string{"sd{aa...ab}"}, nil, true},
+		{"invalid nested range", []string{"sd{a...b}{3...1}"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandEllipses(tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandEllipses(%v) err = %v, wantErr %v", tt.patterns, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandEllipses(%v) = %v, want %v", tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitializedDrives(t *testing.T) {
+	results := []InitResult{
+		{Node: "node1", Drive: "sdb"},
+		{Node: "node1", Drive: "/dev/sdc"},
+		{Node: "node2", Drive: "sdb", Error: "device busy"},
+	}
+	want := map[string]bool{"node1/sdb": true, "node1/sdc": true}
+	if got := InitializedDrives(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("InitializedDrives() = %v, want %v", got, want)
+	}
+}
+
+func TestLabelConflict(t *testing.T) {
+	newDrive := func(pool string, volumes ...string) *directpvv1beta1.DirectPVDrive {
+		drive := directpvv1beta1.NewDirectPVDrive("id", directpvv1beta1.DriveStatus{}, "node1", "sdb", directpvv1beta1.AccessTierDefault)
+		if pool != "" {
+			drive.Labels[DefaultStoragePoolLabelKey] = pool
+		}
+		for _, v := range volumes {
+			drive.AddVolumeFinalizer(v)
+		}
+		return drive
+	}
+	tests := []struct {
+		name     string
+		drive    *directpvv1beta1.DirectPVDrive
+		conflict bool
+	}{
+		{"new drive", newDrive(""), false},
+		{"same pool", newDrive("nineinfra-high"), false},
+		{"other pool", newDrive("nineinfra-low"), true},
+		{"holding volumes", newDrive("", "pvc-1"), true},
+		{"same pool holding volumes", newDrive("nineinfra-high", "pvc-1", "pvc-2"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := labelConflict(tt.drive, "nineinfra-high"); (got != "") != tt.conflict {
+				t.Errorf("labelConflict() = %q, want conflict %v", got, tt.conflict)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
+	"github.com/spf13/cobra"
+	"io"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+const (
+	driveDesc = `
+'drive' command manages the lifecycle of a directpv drive.'cordon' and 'uncordon' stop and resume scheduling new
+volumes on the drive.'drain' cordons the drive and lists its volumes with the NineCluster components using them,
+the volumes are local to the drive and their replicas must be rebuilt elsewhere.'replace' guides the replacement
+of a failed disk,it cordons the old drive,initializes the new disk into the same storage pool and lists the
+component replicas to be rebuilt.The drive is <NODE>/<DRIVE> or the drive id.`
+	driveExample = `1. Stop scheduling new volumes on a drive
+   $ kubectl nine storage drive cordon node1/sdb
+
+2. Resume scheduling new volumes on a drive
+   $ kubectl nine storage drive uncordon node1/sdb
+
+3. Cordon a drive and list the volumes to be moved
+   $ kubectl nine storage drive drain node1/sdb
+
+4. Replace a failed drive with the new disk sdf on the same node
+   $ kubectl nine storage drive replace node1/sdb --new-drive sdf --dangerous`
+)
+
+const (
+	DriveActionCordon      = "cordon"
+	DriveActionUncordon    = "uncordon"
+	DriveActionDrain       = "drain"
+	DriveActionReplace     = "replace"
+	PrintFmtStrDriveVolume = "%-40s\t%-15s\t%-30s\t%-20s\t%-12s\t%-10s\t%-s\n"
+)
+
+var driveActions = []string{DriveActionCordon, DriveActionUncordon, DriveActionDrain, DriveActionReplace}
+
+type driveCmd struct {
+	out       io.Writer
+	errOut    io.Writer
+	action    string
+	target    string
+	newDrive  string
+	dangerous bool
+	dryRun    bool
+	timeout   time.Duration
+}
+
+// DriveVolume is a directpv volume on a drive with the NineCluster component using it
+type DriveVolume struct {
+	Name        string
+	Namespace   string
+	Pod         string
+	NineCluster string
+	Component   string
+	Size        int64
+	Lost        bool
+}
+
+func newStorageDriveCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &driveCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "drive <cordon|uncordon|drain|replace> <NODE>/<DRIVE>",
+		Short:   "Cordon,uncordon,drain or replace a directpv drive",
+		Long:    driveDesc,
+		Example: driveExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return c.validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVar(&c.newDrive, "new-drive", "", "name of the new disk on the same node for replace,defaults to the name of the old drive")
+	f.BoolVar(&c.dangerous, "dangerous", false, "Perform initialization of the new disk which will permanently erase existing data")
+	f.BoolVar(&c.dryRun, "dry-run", false, "only print the volumes and the actions")
+	f.DurationVar(&c.timeout, "timeout", nodeListTimeout, "time to wait for the discovery and the initialization of the new disk")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	return cmd
+}
+
+func (c *driveCmd) validate(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("drive command requires an action [%s] and a drive,e.g. cordon node1/sdb", strings.Join(driveActions, ","))
+	}
+	c.action, c.target = args[0], args[1]
+	if !directpvv1beta1.Contains(driveActions, c.action) {
+		return fmt.Errorf("unsupported drive action %s,support [%s]", c.action, strings.Join(driveActions, ","))
+	}
+	if c.action != DriveActionReplace && (c.newDrive != "" || c.dangerous) {
+		return errors.New("--new-drive and --dangerous are supported with replace only")
+	}
+	return nil
+}
+
+// FindDrive returns the directpv drive of the <NODE>/<DRIVE> or the drive id
+func FindDrive(dpclient *directpvv1beta1.DirectpvV1beta1Client, target string) (*directpvv1beta1.DirectPVDrive, error) {
+	node, name, found := strings.Cut(target, "/")
+	if !found {
+		return dpclient.DirectPVDrives().Get(context.TODO(), target, metav1.GetOptions{})
+	}
+	name = strings.TrimPrefix(name, "dev/")
+	drives, err := dpclient.DirectPVDrives().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var matched []directpvv1beta1.DirectPVDrive
+	for _, drive := range drives.Items {
+		if string(drive.GetNodeID()) == node && string(drive.GetDriveName()) == name {
+			matched = append(matched, drive)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("drive %s is not found", target)
+	case 1:
+		return &matched[0], nil
+	}
+	ids := make([]string, 0, len(matched))
+	for _, drive := range matched {
+		ids = append(ids, drive.Name+"("+string(drive.Status.Status)+")")
+	}
+	return nil, fmt.Errorf("multiple drives %s match %s,specify the drive id instead", strings.Join(ids, ","), target)
+}
+
+// DriveVolumes returns the volumes on the drive with the NineCluster components using them
+func DriveVolumes(dpclient *directpvv1beta1.DirectpvV1beta1Client, drive *directpvv1beta1.DirectPVDrive) ([]DriveVolume, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	nclient, err := GetNineInfraClient(path)
+	if err != nil {
+		return nil, err
+	}
+	ncList, err := nclient.NineinfraV1alpha1().NineClusters("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusters := make(map[string][]string)
+	for _, nc := range ncList.Items {
+		clusters[nc.Namespace] = append(clusters[nc.Namespace], nc.Name)
+	}
+	volumes, err := dpclient.DirectPVVolumes().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: string(directpvv1beta1.DriveLabelKey) + "=" + drive.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]DriveVolume, 0, len(volumes.Items))
+	for i := range volumes.Items {
+		volume := &volumes.Items[i]
+		dv := DriveVolume{
+			Name:      volume.Name,
+			Namespace: volume.GetPodNS(),
+			Pod:       volume.GetPodName(),
+			Component: "-",
+			Size:      volume.Status.TotalCapacity,
+			Lost:      volume.IsDriveLost(),
+		}
+		dv.NineCluster = volumeNineCluster(volume, clusters)
+		if dv.NineCluster != "" {
+			dv.Component = usageComponent(strings.TrimPrefix(dv.NineCluster, dv.Namespace+"/"), dv.Pod)
+		} else {
+			dv.NineCluster = "-"
+		}
+		result = append(result, dv)
+	}
+	return result, nil
+}
+
+// PrintDriveVolumes prints the volumes on a drive
+func PrintDriveVolumes(volumes []DriveVolume) {
+	if len(volumes) == 0 {
+		fmt.Println("No volume found on the drive")
+		return
+	}
+	fmt.Printf(PrintFmtStrDriveVolume, "VOLUME", "NAMESPACE", "POD", "NINECLUSTER", "COMPONENT", "SIZE", "STATUS")
+	for _, v := range volumes {
+		status := "ok"
+		if v.Lost {
+			status = "lost"
+		}
+		fmt.Printf(PrintFmtStrDriveVolume, v.Name, v.Namespace, v.Pod, v.NineCluster, v.Component, formatBytes(v.Size), status)
+	}
+}
+
+// printRebuildGuide prints how to rebuild the component replicas on the volumes,the pvc and the pod are recreated
+// by the statefulset on the schedulable drives and the component re-replicates its data
+func printRebuildGuide(volumes []DriveVolume) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return
+	}
+	fmt.Println("The following replicas must be rebuilt on the other drives:")
+	for _, v := range volumes {
+		pvc := "<pvc of the volume>"
+		pv, err := client.CoreV1().PersistentVolumes().Get(context.TODO(), v.Name, metav1.GetOptions{})
+		if err == nil && pv.Spec.ClaimRef != nil {
+			pvc = pv.Spec.ClaimRef.Name
+		}
+		fmt.Printf("  %s %s of NineCluster %s:\n", v.Component, v.Pod, v.NineCluster)
+		fmt.Printf("    kubectl delete pvc %s -n %s --wait=false && kubectl delete pod %s -n %s\n", pvc, v.Namespace, v.Pod, v.Namespace)
+	}
+	fmt.Println("The components re-replicate their data to the new replicas,check them with 'kubectl nine show'")
+}
+
+// setDriveSchedulable cordons or uncordons the drive
+func setDriveSchedulable(dpclient *directpvv1beta1.DirectpvV1beta1Client, drive *directpvv1beta1.DirectPVDrive, schedulable bool, dryRun bool) error {
+	state := "cordoned"
+	if schedulable {
+		state = "uncordoned"
+	}
+	target := string(drive.GetNodeID()) + "/" + string(drive.GetDriveName())
+	if drive.IsUnschedulable() != schedulable {
+		fmt.Printf("Drive %s is already %s\n", target, state)
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("Drive %s will be %s (dry run)\n", target, state)
+		return nil
+	}
+	if schedulable {
+		drive.Schedulable()
+	} else {
+		drive.Unschedulable()
+	}
+	if _, err := dpclient.DirectPVDrives().Update(context.TODO(), drive, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	fmt.Printf("Drive %s is %s\n", target, state)
+	return nil
+}
+
+// replace cordons the failed drive,initializes the new disk on the same node into the storage pool of the
+// failed drive and lists the replicas to be rebuilt
+func (c *driveCmd) replace(dpclient *directpvv1beta1.DirectpvV1beta1Client, drive *directpvv1beta1.DirectPVDrive, volumes []DriveVolume) error {
+	node := string(drive.GetNodeID())
+	newDrive := strings.TrimPrefix(c.newDrive, "/dev/")
+	if newDrive == "" {
+		newDrive = string(drive.GetDriveName())
+	}
+	pool := drive.Labels[DefaultStoragePoolLabelKey]
+	if pool == "" {
+		return fmt.Errorf("drive %s/%s has no storage pool", node, drive.GetDriveName())
+	}
+	lost := 0
+	for _, v := range volumes {
+		if v.Lost {
+			lost++
+		}
+	}
+	fmt.Printf("Drive %s/%s is %s with %d volumes,%d of them lost\n", node, drive.GetDriveName(), drive.Status.Status, len(volumes), lost)
+	if drive.Status.Status == directpvv1beta1.DriveStatusReady && lost == 0 && !c.dryRun &&
+		!Ask("The drive is still ready, are you sure you want to replace it") {
+		return errors.New("aborting drive replacement")
+	}
+	if err := setDriveSchedulable(dpclient, drive, false, c.dryRun); err != nil {
+		return err
+	}
+
+	devices, err := DiscoverDevices([]string{node}, []string{newDrive}, false, c.timeout)
+	if err != nil {
+		return err
+	}
+	PrintDiscoveredDevices(devices, false)
+	if len(devices) != 1 {
+		return fmt.Errorf("expect one available disk %s on node %s,found %d", newDrive, node, len(devices))
+	}
+	if c.dryRun {
+		fmt.Printf("Disk %s on node %s will be initialized into the storage pool %s (dry run)\n", newDrive, node, pool)
+	} else {
+		if !c.dangerous {
+			return errors.New("initializing the new disk will permanently erase the existing data,please review the disk and use --dangerous")
+		}
+		results, err := InitDevices(devices, c.timeout)
+		if err != nil {
+			return err
+		}
+		PrintInitResults(results)
+		for _, r := range results {
+			if r.Error != "" {
+				return errors.New("the new disk is not initialized,please check the messages above")
+			}
+		}
+		if _, err := LabelDrives(InitializedDrives(results), pool, false, false); err != nil {
+			return err
+		}
+	}
+
+	if len(volumes) != 0 {
+		printRebuildGuide(volumes)
+	}
+	fmt.Printf("Remove the old drive by 'kubectl directpv remove --nodes %s --drives %s' after its volumes are released\n", node, drive.GetDriveName())
+	return nil
+}
+
+func (c *driveCmd) run() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return err
+	}
+	drive, err := FindDrive(dpclient, c.target)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("drive %s is not found", c.target)
+		}
+		return err
+	}
+
+	switch c.action {
+	case DriveActionCordon:
+		return setDriveSchedulable(dpclient, drive, false, c.dryRun)
+	case DriveActionUncordon:
+		return setDriveSchedulable(dpclient, drive, true, c.dryRun)
+	}
+
+	volumes, err := DriveVolumes(dpclient, drive)
+	if err != nil {
+		return err
+	}
+	PrintDriveVolumes(volumes)
+	if c.action == DriveActionReplace {
+		return c.replace(dpclient, drive, volumes)
+	}
+	if err := setDriveSchedulable(dpclient, drive, false, c.dryRun); err != nil {
+		return err
+	}
+	if len(volumes) != 0 {
+		printRebuildGuide(volumes)
+	}
+	return nil
+}
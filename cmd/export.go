@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+	"strings"
+	"time"
+)
+
+const (
+	exportDesc = `
+'export' command writes the definitions of a NineCluster into a portable bundle,a gzipped tar holding the
+NineCluster spec without the runtime fields,the values of the tool releases,the ETL configmaps,the DAGs in the
+airflow DAG volume and the secrets generated for the NineCluster.The secrets could be encrypted with a passphrase.
+The bundle is imported by the 'import' command on another kubernetes cluster.The data of the NineCluster is not
+exported.`
+	exportExample = `1. Export a NineCluster into a bundle
+   $ kubectl nine export c1 -n c1-ns -o c1-bundle.tar.gz
+
+2. Export a NineCluster with the secrets encrypted by a passphrase
+   $ kubectl nine export c1 -n c1-ns -o c1-bundle.tar.gz --encrypt
+
+3. Export a NineCluster with the passphrase read from a file
+   $ kubectl nine export c1 -n c1-ns -o c1-bundle.tar.gz --encrypt --passphrase-file passphrase.txt`
+)
+
+const (
+	DefaultBundleSuffix = "-bundle.tar.gz"
+	HelmReleaseSecret   = "helm.sh/release.v1"
+	HelmManagedValue    = "Helm"
+)
+
+// BundleToolOrder are the tools could be in the bundle,in the installation order
+var BundleToolOrder = []string{
+	DefaultToolRedisName,
+	DefaultToolZookeeperName,
+	DefaultToolAirflowName,
+	DefaultToolSupersetName,
+	DefaultToolNifiName,
+}
+
+// BundleEnvironmentValues are the values of the tools depending on the kubernetes cluster,e.g. the service ips,
+// they are not exported and regenerated by the import
+var BundleEnvironmentValues = map[string][]string{
+	DefaultToolSupersetName: {"extraConfigs.import_datasources"},
+}
+
+// BundleEtlConfigMaps are the suffixes of the ETL configmaps of the NineCluster
+var BundleEtlConfigMaps = []string{"pg2minio", "pg2hdfs", "pg2doris"}
+
+type exportCmd struct {
+	out            io.Writer
+	errOut         io.Writer
+	name           string
+	ns             string
+	output         string
+	encrypt        bool
+	passphraseFile string
+	skipDags       bool
+}
+
+func newClusterExportCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &exportCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "export <NINECLUSTERNAME>",
+		Short:   "Export a NineCluster into a portable bundle",
+		Long:    exportDesc,
+		Example: exportExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return c.validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace scope for this request")
+	f.StringVarP(&c.output, "output", "o", "", "file of the bundle,defaults to <NINECLUSTERNAME>"+DefaultBundleSuffix)
+	f.BoolVar(&c.encrypt, "encrypt", false, "encrypt the secrets in the bundle with a passphrase")
+	f.StringVar(&c.passphraseFile, "passphrase-file", "", "file holding the passphrase,prompted if not specified")
+	f.BoolVar(&c.skipDags, "skip-dags", false, "do not export the DAGs of the airflow")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	return cmd
+}
+
+func (c *exportCmd) validate(args []string) error {
+	if err := ValidateClusterArgs("export", args); err != nil {
+		return err
+	}
+	c.name = args[0]
+	if c.output == "" {
+		c.output = c.name + DefaultBundleSuffix
+	}
+	if c.passphraseFile != "" && !c.encrypt {
+		return errors.New("--passphrase-file is supported with --encrypt only")
+	}
+	return nil
+}
+
+// PortableNineCluster returns the NineCluster without the runtime fields,the pause state and the expiry
+func PortableNineCluster(nc *nineinfrav1alpha1.NineCluster) *nineinfrav1alpha1.NineCluster {
+	portable := &nineinfrav1alpha1.NineCluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: nineinfrav1alpha1.GroupVersion.String(),
+			Kind:       NineClusterKind,
+		},
+		ObjectMeta: PortableObjectMeta(nc.ObjectMeta),
+		Spec:       *nc.Spec.DeepCopy(),
+	}
+	delete(portable.Annotations, PausedAnnoKey)
+	delete(portable.Annotations, PausedWorkloadsAnnoKey)
+	delete(portable.Annotations, ExpiresAtAnnoKey)
+	return portable
+}
+
+// PortableValues returns the values of the tool release without the environment values
+func PortableValues(tool string, values []byte) ([]byte, error) {
+	if len(BundleEnvironmentValues[tool]) == 0 {
+		return values, nil
+	}
+	parsed := make(map[string]interface{})
+	if err := yaml.Unmarshal(values, &parsed); err != nil {
+		return nil, err
+	}
+	for _, key := range BundleEnvironmentValues[tool] {
+		unsetValue(parsed, key)
+	}
+	return yaml.Marshal(parsed)
+}
+
+// helmReleaseChart returns the chart of the helm release,empty if the release is not found
+func helmReleaseChart(name string, namespace string) (string, error) {
+	output, errput, err := runCommand("helm", append([]string{"list", "-n", namespace, "-f", "^" + name + "$", "-o", "json"}, kubeconfigArgs()...)...)
+	if err != nil {
+		return "", errors.New(strings.TrimSpace(errput))
+	}
+	var releases []struct {
+		Name  string `json:"name"`
+		Chart string `json:"chart"`
+	}
+	if err := json.Unmarshal([]byte(output), &releases); err != nil {
+		return "", err
+	}
+	for _, r := range releases {
+		if r.Name == name {
+			return r.Chart, nil
+		}
+	}
+	return "", nil
+}
+
+// exportReleases adds the tool releases of the NineCluster and their user supplied values to the bundle
+func (c *exportCmd) exportReleases(b *Bundle) error {
+	for _, tool := range BundleToolOrder {
+		name := NineResourceName(c.name, tool)
+		chart, err := helmReleaseChart(name, c.ns)
+		if err != nil {
+			return err
+		}
+		if chart == "" {
+			continue
+		}
+		values, errput, err := runCommand("helm", append([]string{"get", "values", name, "-n", c.ns, "-o", "yaml"}, kubeconfigArgs()...)...)
+		if err != nil {
+			return fmt.Errorf("get the values of the release %s failed,err:%s", name, strings.TrimSpace(errput))
+		}
+		portable, err := PortableValues(tool, []byte(values))
+		if err != nil {
+			return fmt.Errorf("invalid values of the release %s,err:%v", name, err)
+		}
+		b.Manifest.Releases = append(b.Manifest.Releases, BundleRelease{
+			Name:    name,
+			Tool:    tool,
+			Version: strings.TrimPrefix(chart, tool+"-"),
+		})
+		b.Values[name] = portable
+		fmt.Printf("Export the release %s\n", name)
+	}
+	return nil
+}
+
+// exportConfigMaps adds the ETL configmaps of the NineCluster to the bundle
+func (c *exportCmd) exportConfigMaps(b *Bundle) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	for _, suffix := range BundleEtlConfigMaps {
+		cm, err := client.CoreV1().ConfigMaps(c.ns).Get(context.TODO(), NineResourceName(c.name, suffix), metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		b.ConfigMaps = append(b.ConfigMaps, corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: PortableObjectMeta(cm.ObjectMeta),
+			Data:       cm.Data,
+			BinaryData: cm.BinaryData,
+		})
+		b.Manifest.ConfigMaps = append(b.Manifest.ConfigMaps, cm.Name)
+		fmt.Printf("Export the configmap %s\n", cm.Name)
+	}
+	return nil
+}
+
+// isGeneratedSecret returns true if the secret is generated for the NineCluster by the operators,
+// the ones of the helm releases are recreated by the releases
+func isGeneratedSecret(name string, secret *corev1.Secret) bool {
+	if !strings.HasPrefix(secret.Name, NineResourceName(name)+"-") {
+		return false
+	}
+	switch secret.Type {
+	case HelmReleaseSecret, corev1.SecretTypeServiceAccountToken:
+		return false
+	}
+	return secret.Labels["app.kubernetes.io/managed-by"] != HelmManagedValue && secret.Labels["heritage"] != HelmManagedValue
+}
+
+// exportSecrets adds the generated secrets of the NineCluster to the bundle
+func (c *exportCmd) exportSecrets(b *Bundle) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	secrets, err := client.CoreV1().Secrets(c.ns).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !isGeneratedSecret(c.name, secret) {
+			continue
+		}
+		b.Secrets = append(b.Secrets, corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: PortableObjectMeta(secret.ObjectMeta),
+			Type:       secret.Type,
+			Data:       secret.Data,
+		})
+		b.Manifest.Secrets = append(b.Manifest.Secrets, secret.Name)
+		fmt.Printf("Export the secret %s\n", secret.Name)
+	}
+	return nil
+}
+
+// exportDags adds the DAGs in the airflow DAG volume to the bundle as a tar
+func (c *exportCmd) exportDags(b *Bundle) error {
+	podNames, err := GetAirflowPodNames(c.name, "scheduler", c.ns)
+	if err != nil {
+		return err
+	}
+	if len(podNames) == 0 {
+		return errors.New("no airflow scheduler found,use --skip-dags to export without the DAGs")
+	}
+	dags, err := RunExecCommandWithStdin(podNames[0], "scheduler", c.ns, []string{"tar", "cf", "-", "-C", DefaultAirflowDagsPath, "."}, nil)
+	if err != nil {
+		return fmt.Errorf("export the DAGs failed,err:%v", err)
+	}
+	b.Dags = []byte(dags)
+	fmt.Printf("Export the DAGs in %s\n", DefaultAirflowDagsPath)
+	return nil
+}
+
+func (c *exportCmd) run() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	nc, err := client.NineinfraV1alpha1().NineClusters(c.ns).Get(context.TODO(), c.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	var passphrase string
+	if c.encrypt {
+		if passphrase, err = ReadPassphrase(c.passphraseFile, true); err != nil {
+			return err
+		}
+	}
+
+	b := &Bundle{
+		Manifest: BundleManifest{
+			Version:    BundleVersion,
+			Cluster:    nc.Name,
+			Namespace:  nc.Namespace,
+			ExportedAt: time.Now().Format(time.RFC3339),
+		},
+		NineCluster: PortableNineCluster(nc),
+		Values:      make(map[string][]byte),
+	}
+	if err := c.exportReleases(b); err != nil {
+		return err
+	}
+	if err := c.exportConfigMaps(b); err != nil {
+		return err
+	}
+	if err := c.exportSecrets(b); err != nil {
+		return err
+	}
+	if !c.skipDags && b.Values[NineResourceName(c.name, DefaultToolAirflowName)] != nil {
+		if err := c.exportDags(b); err != nil {
+			return err
+		}
+	}
+	if len(b.Secrets) != 0 && !c.encrypt {
+		fmt.Println("Warning: the secrets are not encrypted,keep the bundle safe or use --encrypt")
+	}
+	if err := WriteBundle(c.output, b, passphrase); err != nil {
+		return err
+	}
+	fmt.Println("NineCluster:" + c.name + " in namespace:" + c.ns + " is exported to " + c.output + " successfully!")
+	return nil
+}
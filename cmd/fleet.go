@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/pflag"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultContextTimeout   = 30 * time.Second
+	ContextStateUnreachable = "unreachable"
+)
+
+// FleetOptions selects the kubeconfig contexts a command runs in
+type FleetOptions struct {
+	AllContexts bool
+	Contexts    []string
+	Timeout     time.Duration
+}
+
+// ContextResult is the output of a command run in a kubeconfig context
+type ContextResult struct {
+	Context string
+	Output  []byte
+	Err     error
+}
+
+// AddFlags adds the flags selecting the contexts
+func (o *FleetOptions) AddFlags(f *pflag.FlagSet) {
+	f.BoolVar(&o.AllContexts, "all-contexts", false, "run in all the contexts of the kubeconfig")
+	f.StringSliceVar(&o.Contexts, "contexts", nil, "contexts of the kubeconfig to run in,e.g. a,b,c")
+	f.DurationVar(&o.Timeout, "context-timeout", DefaultContextTimeout, "time to wait for each context")
+}
+
+// Enabled returns true if the command should run in multiple contexts
+func (o *FleetOptions) Enabled() bool {
+	return o.AllContexts || len(o.Contexts) != 0
+}
+
+func (o *FleetOptions) Validate() error {
+	if o.AllContexts && len(o.Contexts) != 0 {
+		return errors.New("--all-contexts and --contexts could not be specified together")
+	}
+	if o.Enabled() && kubeContext != "" {
+		return errors.New("--context could not be specified with --all-contexts or --contexts")
+	}
+	if o.Timeout <= 0 {
+		return errors.New("--context-timeout should be positive")
+	}
+	return nil
+}
+
+// ResolveContexts returns the contexts to run in,the specified ones should exist in the kubeconfig
+func (o *FleetOptions) ResolveContexts() ([]string, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	contexts, err := GetKubeContexts(path)
+	if err != nil {
+		return nil, err
+	}
+	if o.AllContexts {
+		if len(contexts) == 0 {
+			return nil, errors.New("no context found in the kubeconfig")
+		}
+		return contexts, nil
+	}
+	existing := make(map[string]bool)
+	for _, c := range contexts {
+		existing[c] = true
+	}
+	for _, c := range o.Contexts {
+		if !existing[c] {
+			return nil, fmt.Errorf("context %s is not found in the kubeconfig", c)
+		}
+	}
+	return o.Contexts, nil
+}
+
+// RunInContexts runs the kubectl-nine command in the contexts concurrently,the results are in the order of the
+// contexts.Each run is a separate process,so an unreachable context is killed after the timeout without blocking
+// the others
+func RunInContexts(contexts []string, timeout time.Duration, args ...string) []ContextResult {
+	results := make([]ContextResult, len(contexts))
+	self, err := os.Executable()
+	if err != nil {
+		for i, c := range contexts {
+			results[i] = ContextResult{Context: c, Err: err}
+		}
+		return results
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	var wg sync.WaitGroup
+	for i, c := range contexts {
+		wg.Add(1)
+		go func(i int, c string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			cmdArgs := []string{"--" + kubecontext, c}
+			if path != "" {
+				cmdArgs = append(cmdArgs, "--"+kubeconfig, path)
+			}
+			cmd := exec.CommandContext(ctx, self, append(cmdArgs, args...)...)
+			var output, errput bytes.Buffer
+			cmd.Stdout = &output
+			cmd.Stderr = &errput
+			err := cmd.Run()
+			switch {
+			case ctx.Err() == context.DeadlineExceeded:
+				err = fmt.Errorf("timed out after %s", timeout)
+			case err != nil && errput.Len() != 0:
+				err = errors.New(lastLine(errput.String()))
+			}
+			if DEBUG {
+				fmt.Printf("Exec %s in context %s with output:%s,errput:%s,err:%v\n", args, c, output.String(), errput.String(), err)
+			}
+			results[i] = ContextResult{Context: c, Output: output.Bytes(), Err: err}
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// lastLine returns the last line of the output without the prefix of the cobra errors
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimPrefix(strings.TrimSpace(lines[len(lines)-1]), "Error: ")
+}
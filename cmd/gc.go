@@ -0,0 +1,410 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+const (
+	gcDesc = `
+'gc' command deletes the expired NineClusters created with --ttl,along with their tools,pvcs and the directpv
+volumes as the 'delete' command does.With --install-cronjob,a CronJob running the same garbage collection is
+deployed into the kubernetes cluster,its image given by --image should have the kubectl-nine and the helm.
+The CronJob is allowed to delete only the kinds of the resources created by the NineClusters and their tools,
+in the namespace given by -n if specified.`
+	gcExample = `1. List the expired NineClusters without deleting them
+   $ kubectl nine gc --dry-run
+
+2. Delete the expired NineClusters in a namespace
+   $ kubectl nine gc -n dev --yes
+
+3. Run the garbage collection of a namespace every 30 minutes in the kubernetes cluster
+   $ kubectl nine gc -n dev --install-cronjob --image nineinfra/kubectl-nine:v0.8.0 --schedule "*/30 * * * *"
+
+4. Remove the CronJob of the garbage collection
+   $ kubectl nine gc --uninstall-cronjob`
+)
+
+const (
+	ExpiresAtAnnoKey       = "nine.nineinfra.tech/expires-at"
+	ClusterStateExpired    = "expired"
+	DefaultGCName          = "kubectl-nine-gc"
+	DefaultGCSchedule      = "*/30 * * * *"
+	DefaultGCLabelKey      = "app.kubernetes.io/name"
+	DefaultGCTimeout       = 20 * time.Minute
+	PrintFmtStrExpiredList = "%-20s\t%-20s\t%-26s\t%-10s\n"
+)
+
+type gcCmd struct {
+	out              io.Writer
+	errOut           io.Writer
+	ns               string
+	dryRun           bool
+	yes              bool
+	keepPVC          bool
+	timeout          time.Duration
+	installCronJob   bool
+	uninstallCronJob bool
+	schedule         string
+	image            string
+	cronJobNS        string
+}
+
+func newClusterGCCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &gcCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "gc",
+		Short:   "Delete the expired NineClusters",
+		Long:    gcDesc,
+		Example: gcExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("gc command takes no arguments")
+			}
+			if c.installCronJob && c.uninstallCronJob {
+				return errors.New("--install-cronjob and --uninstall-cronjob could not be specified together")
+			}
+			if c.installCronJob {
+				return validateGCImage(c.image)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace scope for this request,all namespaces if not specified")
+	f.BoolVar(&c.dryRun, "dry-run", false, "only print the expired NineClusters")
+	f.BoolVarP(&c.yes, "yes", "y", false, "skip the confirmation prompt")
+	f.BoolVar(&c.keepPVC, "keep-pvc", false, "keep the pvcs of the expired NineClusters")
+	f.DurationVar(&c.timeout, "timeout", DefaultGCTimeout, "time to wait for each NineCluster to be deleted")
+	f.BoolVar(&c.installCronJob, "install-cronjob", false, "deploy a CronJob running the garbage collection")
+	f.BoolVar(&c.uninstallCronJob, "uninstall-cronjob", false, "remove the CronJob of the garbage collection")
+	f.StringVar(&c.schedule, "schedule", DefaultGCSchedule, "schedule of the CronJob")
+	f.StringVar(&c.image, "image", "", "image of the CronJob with the kubectl-nine and the helm,pinned by a tag or a digest")
+	f.StringVar(&c.cronJobNS, "cronjob-namespace", DefaultNamespace, "namespace of the CronJob")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	return cmd
+}
+
+// SetNineClusterTTL records the expiry of the NineCluster,nothing is recorded if the ttl is not positive
+func SetNineClusterTTL(nc *nineinfrav1alpha1.NineCluster, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if nc.Annotations == nil {
+		nc.Annotations = make(map[string]string)
+	}
+	nc.Annotations[ExpiresAtAnnoKey] = time.Now().Add(ttl).UTC().Format(time.RFC3339)
+}
+
+// NineClusterExpiresAt returns the expiry of the NineCluster,false if it has no ttl
+func NineClusterExpiresAt(nc *nineinfrav1alpha1.NineCluster) (time.Time, bool) {
+	value, ok := nc.Annotations[ExpiresAtAnnoKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiresAt, true
+}
+
+// NineClusterExpires returns the time left before the NineCluster expires
+func NineClusterExpires(nc *nineinfrav1alpha1.NineCluster) string {
+	expiresAt, ok := NineClusterExpiresAt(nc)
+	if !ok {
+		return "-"
+	}
+	left := time.Until(expiresAt)
+	if left <= 0 {
+		return ClusterStateExpired
+	}
+	return duration.HumanDuration(left)
+}
+
+// ExpiredNineClusters returns the expired NineClusters in the namespace,all namespaces if empty
+func ExpiredNineClusters(namespace string) ([]nineinfrav1alpha1.NineCluster, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := client.NineinfraV1alpha1().NineClusters(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var expired []nineinfrav1alpha1.NineCluster
+	for _, nc := range clusters.Items {
+		if expiresAt, ok := NineClusterExpiresAt(&nc); ok && time.Now().After(expiresAt) && nc.DeletionTimestamp == nil {
+			expired = append(expired, nc)
+		}
+	}
+	return expired, nil
+}
+
+// validateGCImage checks the image of the CronJob is pinned by a tag other than latest or by a digest
+func validateGCImage(image string) error {
+	if image == "" {
+		return errors.New("--image is required with --install-cronjob")
+	}
+	if strings.Contains(image, "@sha256:") {
+		return nil
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, found := strings.Cut(name, ":")
+	if !found || tag == "" || tag == "latest" {
+		return fmt.Errorf("image %s should be pinned by a tag other than latest or by a digest", image)
+	}
+	return nil
+}
+
+// gcNamespacedRules are the rules of the namespaced resources deleted by the gc,the NineClusters,the ETL
+// configmaps,the pvcs and the resources of the tool releases uninstalled by the helm
+func gcNamespacedRules() []rbacv1.PolicyRule {
+	verbs := []string{"get", "list", "watch", "delete"}
+	return []rbacv1.PolicyRule{
+		{APIGroups: []string{nineinfrav1alpha1.GroupVersion.Group}, Resources: []string{"nineclusters"}, Verbs: verbs},
+		{APIGroups: []string{""}, Resources: []string{"configmaps", "secrets", "services", "serviceaccounts", "persistentvolumeclaims"}, Verbs: verbs},
+		{APIGroups: []string{""}, Resources: []string{"pods", "endpoints"}, Verbs: []string{"get", "list", "watch"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments", "statefulsets"}, Verbs: verbs},
+		{APIGroups: []string{"batch"}, Resources: []string{"jobs", "cronjobs"}, Verbs: verbs},
+		{APIGroups: []string{"policy"}, Resources: []string{"poddisruptionbudgets"}, Verbs: verbs},
+		{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"networkpolicies", "ingresses"}, Verbs: verbs},
+		{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"roles", "rolebindings"}, Verbs: verbs},
+	}
+}
+
+// gcRBAC returns the service account of the CronJob,the cluster role for the released pvs and the directpv
+// volumes and the role for the namespaced resources.The namespaced rules are in the cluster role if the gc is
+// not scoped to a namespace
+func (c *gcCmd) gcRBAC() (*corev1.ServiceAccount, *rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding, *rbacv1.Role, *rbacv1.RoleBinding) {
+	labels := map[string]string{DefaultGCLabelKey: DefaultGCName}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: DefaultGCName, Namespace: c.cronJobNS, Labels: labels}}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: DefaultGCName, Namespace: c.cronJobNS}}
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultGCName, Labels: labels},
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"persistentvolumes"}, Verbs: []string{"get", "list", "watch", "delete"}},
+			{APIGroups: []string{"directpv.min.io"}, Resources: []string{"directpvvolumes"}, Verbs: []string{"get", "list", "watch"}},
+		},
+	}
+	clusterBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultGCName, Labels: labels},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: DefaultGCName},
+		Subjects:   subjects,
+	}
+	if c.ns == "" {
+		clusterRole.Rules = append(clusterRole.Rules, gcNamespacedRules()...)
+		return sa, clusterRole, clusterBinding, nil, nil
+	}
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultGCName, Namespace: c.ns, Labels: labels},
+		Rules:      gcNamespacedRules(),
+	}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultGCName, Namespace: c.ns, Labels: labels},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: DefaultGCName},
+		Subjects:   subjects,
+	}
+	return sa, clusterRole, clusterBinding, role, binding
+}
+
+// gcCronJob returns the CronJob running the garbage collection with the flags of the command
+func (c *gcCmd) gcCronJob() *batchv1.CronJob {
+	args := []string{"gc", "--yes", "--timeout", c.timeout.String()}
+	if c.ns != "" {
+		args = append(args, "-n", c.ns)
+	}
+	if c.keepPVC {
+		args = append(args, "--keep-pvc")
+	}
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultGCName, Namespace: c.cronJobNS},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          c.schedule,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							ServiceAccountName: DefaultGCName,
+							RestartPolicy:      corev1.RestartPolicyNever,
+							Containers: []corev1.Container{{
+								Name:    "gc",
+								Image:   c.image,
+								Command: []string{"kubectl-nine"},
+								Args:    args,
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// installGCCronJob creates or updates the CronJob and its rbac
+func (c *gcCmd) installGCCronJob() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	if err := ensureNamespace(c.cronJobNS); err != nil {
+		return err
+	}
+	sa, clusterRole, clusterBinding, role, binding := c.gcRBAC()
+	if _, err := client.CoreV1().ServiceAccounts(c.cronJobNS).Create(context.TODO(), sa, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	existingClusterRole, err := client.RbacV1().ClusterRoles().Get(context.TODO(), clusterRole.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = client.RbacV1().ClusterRoles().Create(context.TODO(), clusterRole, metav1.CreateOptions{})
+	} else if err == nil {
+		existingClusterRole.Rules = clusterRole.Rules
+		_, err = client.RbacV1().ClusterRoles().Update(context.TODO(), existingClusterRole, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := client.RbacV1().ClusterRoleBindings().Create(context.TODO(), clusterBinding, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	if role != nil {
+		existingRole, err := client.RbacV1().Roles(role.Namespace).Get(context.TODO(), role.Name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			_, err = client.RbacV1().Roles(role.Namespace).Create(context.TODO(), role, metav1.CreateOptions{})
+		} else if err == nil {
+			existingRole.Rules = role.Rules
+			_, err = client.RbacV1().Roles(role.Namespace).Update(context.TODO(), existingRole, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := client.RbacV1().RoleBindings(binding.Namespace).Create(context.TODO(), binding, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	cronJob := c.gcCronJob()
+	existing, err := client.BatchV1().CronJobs(c.cronJobNS).Get(context.TODO(), cronJob.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = client.BatchV1().CronJobs(c.cronJobNS).Create(context.TODO(), cronJob, metav1.CreateOptions{})
+	} else if err == nil {
+		existing.Spec = cronJob.Spec
+		_, err = client.BatchV1().CronJobs(c.cronJobNS).Update(context.TODO(), existing, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("CronJob %s in namespace %s is installed with the schedule %q\n", DefaultGCName, c.cronJobNS, c.schedule)
+	return nil
+}
+
+// uninstallGCCronJob removes the CronJob and its rbac
+func (c *gcCmd) uninstallGCCronJob() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	propagation := metav1.DeletePropagationBackground
+	errs := []error{
+		client.BatchV1().CronJobs(c.cronJobNS).Delete(context.TODO(), DefaultGCName, metav1.DeleteOptions{PropagationPolicy: &propagation}),
+		client.RbacV1().ClusterRoleBindings().Delete(context.TODO(), DefaultGCName, metav1.DeleteOptions{}),
+		client.RbacV1().ClusterRoles().Delete(context.TODO(), DefaultGCName, metav1.DeleteOptions{}),
+		client.CoreV1().ServiceAccounts(c.cronJobNS).Delete(context.TODO(), DefaultGCName, metav1.DeleteOptions{}),
+	}
+	// the roles of the namespaces the gc was scoped to
+	selector := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", DefaultGCLabelKey, DefaultGCName)}
+	bindings, err := client.RbacV1().RoleBindings("").List(context.TODO(), selector)
+	if err != nil {
+		return err
+	}
+	for _, b := range bindings.Items {
+		errs = append(errs, client.RbacV1().RoleBindings(b.Namespace).Delete(context.TODO(), b.Name, metav1.DeleteOptions{}))
+	}
+	roles, err := client.RbacV1().Roles("").List(context.TODO(), selector)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles.Items {
+		errs = append(errs, client.RbacV1().Roles(r.Namespace).Delete(context.TODO(), r.Name, metav1.DeleteOptions{}))
+	}
+	for _, err := range errs {
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	fmt.Printf("CronJob %s in namespace %s is uninstalled\n", DefaultGCName, c.cronJobNS)
+	return nil
+}
+
+func (c *gcCmd) run() error {
+	if c.installCronJob {
+		return c.installGCCronJob()
+	}
+	if c.uninstallCronJob {
+		return c.uninstallGCCronJob()
+	}
+	expired, err := ExpiredNineClusters(c.ns)
+	if err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		fmt.Println("No expired NineCluster found")
+		return nil
+	}
+	fmt.Printf(PrintFmtStrExpiredList, "NAME", "NAMESPACE", "EXPIRED-AT", "DATAVOLUME")
+	for _, nc := range expired {
+		fmt.Printf(PrintFmtStrExpiredList, nc.Name, nc.Namespace, nc.Annotations[ExpiresAtAnnoKey], fmt.Sprintf("%dGi", nc.Spec.DataVolume))
+	}
+	if c.dryRun {
+		return nil
+	}
+	if !c.yes && !Ask("The expired NineClusters above will be deleted with their tools and pvcs, are you sure you want to continue") {
+		return errors.New("aborting NineCluster garbage collection")
+	}
+
+	var failed []string
+	for _, nc := range expired {
+		fmt.Println("Deleting the expired NineCluster:" + nc.Name + " in namespace:" + nc.Namespace)
+		d := &deleteCmd{out: c.out, errOut: c.errOut, deleteOpts: DeleteOptions{
+			Name:      nc.Name,
+			NS:        nc.Namespace,
+			deletePVC: !c.keepPVC,
+			yes:       true,
+			timeout:   c.timeout,
+		}}
+		if err := d.run(nil); err != nil {
+			fmt.Printf("Error: %v \n", err)
+			failed = append(failed, nc.Namespace+"/"+nc.Name)
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("the expired NineClusters %v are not deleted completely", failed)
+	}
+	return nil
+}
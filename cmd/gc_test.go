@@ -0,0 +1,49 @@
+package cmd
+
+import "testing"
+
+func TestValidateGCImage(t *testing.T) {
+	tests := []struct {
+		image   string
+		wantErr bool
+	}{
+		{"", true},
+		{"nineinfra/kubectl-nine", true},
+		{"nineinfra/kubectl-nine:latest", true},
+		{"registry:5000/nineinfra/kubectl-nine", true},
+		{"nineinfra/kubectl-nine:v0.8.0", false},
+		{"registry:5000/nineinfra/kubectl-nine:v0.8.0", false},
+		{"nineinfra/kubectl-nine@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", false},
+	}
+	for _, tt := range tests {
+		if err := validateGCImage(tt.image); (err != nil) != tt.wantErr {
+			t.Errorf("validateGCImage(%q) err = %v, wantErr %v", tt.image, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGCRBACScope(t *testing.T) {
+	c := &gcCmd{cronJobNS: DefaultNamespace, ns: "dev"}
+	_, clusterRole, _, role, binding := c.gcRBAC()
+	if role == nil || binding == nil || role.Namespace != "dev" || binding.Namespace != "dev" {
+		t.Fatal("the namespaced rules should be in a role of the namespace of the gc")
+	}
+	for _, rule := range append(clusterRole.Rules, role.Rules...) {
+		for _, r := range rule.Resources {
+			if r == "*" {
+				t.Errorf("rule %v should not grant all the resources", rule)
+			}
+		}
+	}
+	for _, rule := range clusterRole.Rules {
+		for _, r := range rule.Resources {
+			if r == "secrets" {
+				t.Error("the secrets should not be granted cluster wide for a namespaced gc")
+			}
+		}
+	}
+	c.ns = ""
+	if _, _, _, role, _ := c.gcRBAC(); role != nil {
+		t.Error("no role is needed for the gc of all the namespaces")
+	}
+}
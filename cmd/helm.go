@@ -10,7 +10,6 @@ import (
 const (
 	DefaultHelmRepoName = "nineinfra"
 	DefaultHelmRepo     = "https://nineinfra.github.io/nineinfra-charts/"
-	DefaultHelmCmdURL   = "https://raw.githubusercontent.com/helm/helm/main/scripts/get-helm-3"
 )
 
 func CheckHelmCmdExist() bool {
@@ -22,15 +21,7 @@ func CheckHelmCmdExist() bool {
 }
 
 func InstallHelmCmd() error {
-	_, _, err := runCommand("curl", "-OfsSL", DefaultHelmCmdURL)
-	if err != nil {
-		return err
-	}
-	_, _, err = runCommand("bash", "./get-helm-3")
-	if err != nil {
-		return err
-	}
-	return nil
+	return InstallTool(DefaultCMDHelm, DefaultToolImportDir)
 }
 
 func AddHelmRepo(repo string) error {
@@ -74,30 +65,6 @@ func InitHelm() error {
 	return nil
 }
 
-func HelmInstall(name string, repoName string, chartPath string, chart string, version string, namespace string, flags string) error {
-	if repoName == "" {
-		if chartPath != "" {
-			chart = chartPath + "/" + ChartName2TarName(chart, 0)
-		} else {
-			repoName = DefaultHelmRepoName
-			chart = repoName + "/" + chart
-		}
-	}
-	if flags == "" {
-		_, errput, err := runCommand("helm", "install", name, chart, "--version", version, "-n", namespace)
-		if err != nil && !strings.Contains(errput, "in use") {
-			return errors.New(errput)
-		}
-	} else {
-		_, errput, err := runCommand("helm", "install", name, chart, "--version", version, "-n", namespace, flags)
-		if err != nil && !strings.Contains(errput, "in use") {
-			return errors.New(errput)
-		}
-	}
-	fmt.Printf("Install %s successfully!\n", name)
-	return nil
-}
-
 func ChartName2TarName(chart string, flag int) string {
 	switch flag {
 	case 0:
@@ -112,15 +79,50 @@ func ChartName2TarName(chart string, flag int) string {
 	return ""
 }
 
-func HelmInstallWithParameters(name string, repoName string, chartPath string, chart string, version string, namespace string, parameters ...string) error {
+func resolveChart(repoName string, chartPath string, chart string, flag int) string {
 	if repoName == "" {
 		if chartPath != "" {
-			chart = chartPath + "/" + ChartName2TarName(chart, 1)
-		} else {
-			repoName = DefaultHelmRepoName
-			chart = repoName + "/" + chart
+			return chartPath + "/" + ChartName2TarName(chart, flag)
 		}
+		repoName = DefaultHelmRepoName
+	}
+	return repoName + "/" + chart
+}
+
+// HelmInstallChart installs a chart of the NineInfra platform with the parameters
+func HelmInstallChart(name string, chartPath string, chart string, version string, namespace string, parameters ...string) error {
+	if err := helmInstallChart(name, chartPath, chart, version, namespace, parameters...); err != nil {
+		return err
+	}
+	fmt.Printf("Install %s successfully!\n", name)
+	return nil
+}
+
+// helmInstallChart installs a chart of the NineInfra platform without printing anything
+func helmInstallChart(name string, chartPath string, chart string, version string, namespace string, parameters ...string) error {
+	args := []string{"install", name, resolveChart("", chartPath, chart, 0), "--version", version, "-n", namespace}
+	args = append(args, parameters...)
+	_, errput, err := runCommand("helm", args...)
+	if err != nil && !strings.Contains(errput, "in use") {
+		return errors.New(strings.TrimSpace(errput))
+	}
+	return nil
+}
+
+// HelmUpgradeChart upgrades a chart of the NineInfra platform with the parameters
+func HelmUpgradeChart(name string, chartPath string, chart string, version string, namespace string, parameters ...string) error {
+	args := []string{"upgrade", name, resolveChart("", chartPath, chart, 0), "--version", version, "-n", namespace}
+	args = append(args, parameters...)
+	_, errput, err := runCommand("helm", args...)
+	if err != nil {
+		return errors.New(errput)
 	}
+	fmt.Printf("Upgrade %s to %s successfully!\n", name, version)
+	return nil
+}
+
+func HelmInstallWithParameters(name string, repoName string, chartPath string, chart string, version string, namespace string, parameters ...string) error {
+	chart = resolveChart(repoName, chartPath, chart, 1)
 
 	args := []string{"install", name, chart, "--version", version, "-n", namespace}
 	args = append(args, parameters...)
@@ -145,8 +147,17 @@ func HelmUnInstall(name string, namespace string, flags string) error {
 	return nil
 }
 
+// kubeconfigArgs returns the --kubeconfig parameter for the helm,empty if the kubeconfig is not specified
+func kubeconfigArgs() []string {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	if path == "" {
+		return nil
+	}
+	return []string{"--kubeconfig", path}
+}
+
 func CheckHelmReleaseExist(name string, namespace string) bool {
-	_, errput, err := runCommand("helm", "status", name, "-n", namespace)
+	_, errput, err := runCommand("helm", append([]string{"status", name, "-n", namespace}, kubeconfigArgs()...)...)
 	if err != nil {
 		if !strings.Contains(errput, "not found") {
 			return false
@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	historyDesc = `
+'history' command shows the spec revisions of a NineCluster recorded by the create,apply,update,scale,
+upgrade-cluster and rollback commands,with the time,the user and the changed fields of each.The revisions are
+kept in the configmap <NINECLUSTERNAME>-nine-history owned by the NineCluster.`
+	historyExample = `1. Show the revisions of a NineCluster
+   $ kubectl nine history c1 -n c1-ns
+
+2. Show the spec of a revision
+   $ kubectl nine history c1 --revision 3 -n c1-ns`
+)
+
+const (
+	HistoryConfigMapSuffix  = "history"
+	LatestRevisionAnnoKey   = "nine.nineinfra.tech/latest-revision"
+	RevisionKeyPrefix       = "revision-"
+	DefaultHistoryLimit     = 10
+	UnknownRevisionSource   = "-"
+	changedFieldsDepth      = 3
+	PrintFmtStrHistory      = "%-10s\t%-26s\t%-20s\t%-16s\t%s\n"
+	DefaultRevisionMaxShown = 5
+)
+
+// Revision is a spec of the NineCluster recorded when it was changed by the kubectl-nine
+type Revision struct {
+	Revision int                               `json:"revision"`
+	Time     string                            `json:"time"`
+	User     string                            `json:"user"`
+	Command  string                            `json:"command"`
+	Changed  []string                          `json:"changed,omitempty"`
+	Spec     nineinfrav1alpha1.NineClusterSpec `json:"spec"`
+}
+
+// CurrentUser returns the user authenticated by the api server,or the user of the kubeconfig if not supported
+func CurrentUser() string {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err == nil {
+		review, err := client.AuthenticationV1().SelfSubjectReviews().Create(context.TODO(), &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+		if err == nil && review.Status.UserInfo.Username != "" {
+			return review.Status.UserInfo.Username
+		}
+	}
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path != "" {
+		loadingRules.ExplicitPath = path
+	}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).RawConfig()
+	if err == nil {
+		current := config.CurrentContext
+		if kubeContext != "" {
+			current = kubeContext
+		}
+		if ctx, ok := config.Contexts[current]; ok && ctx.AuthInfo != "" {
+			return ctx.AuthInfo
+		}
+	}
+	return UnknownRevisionSource
+}
+
+// flattenFields flattens the value into the field paths and their values,the elements of the cluster set are
+// keyed by their types
+func flattenFields(prefix string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flattenFields(path, child, fields)
+		}
+	case []interface{}:
+		for i, child := range v {
+			key := strconv.Itoa(i)
+			if m, ok := child.(map[string]interface{}); ok {
+				if t, ok := m["type"].(string); ok {
+					key = t
+				}
+			}
+			flattenFields(fmt.Sprintf("%s[%s]", prefix, key), child, fields)
+		}
+	default:
+		data, _ := json.Marshal(v)
+		fields[prefix] = string(data)
+	}
+}
+
+// ChangedFields returns the fields changed between the specs,the deep fields are cut to their parents
+func ChangedFields(before *nineinfrav1alpha1.NineClusterSpec, after *nineinfrav1alpha1.NineClusterSpec) []string {
+	flatten := func(spec *nineinfrav1alpha1.NineClusterSpec) map[string]string {
+		fields := make(map[string]string)
+		if spec == nil {
+			return fields
+		}
+		data, _ := json.Marshal(spec)
+		var value interface{}
+		_ = json.Unmarshal(data, &value)
+		flattenFields("", value, fields)
+		return fields
+	}
+	b, a := flatten(before), flatten(after)
+	changed := make(map[string]bool)
+	for path, v := range a {
+		if b[path] != v {
+			changed[path] = true
+		}
+	}
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			changed[path] = true
+		}
+	}
+	cut := make(map[string]bool)
+	for path := range changed {
+		segments := strings.Split(path, ".")
+		if len(segments) > changedFieldsDepth {
+			segments = segments[:changedFieldsDepth]
+		}
+		cut[strings.Join(segments, ".")] = true
+	}
+	return SortedKeys(cut)
+}
+
+// LoadRevisions returns the revisions of the NineCluster in order,nil if none is recorded
+func LoadRevisions(name string, namespace string) ([]Revision, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.TODO(), NineResourceName(name, HistoryConfigMapSuffix), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return revisionsOf(cm)
+}
+
+func revisionsOf(cm *corev1.ConfigMap) ([]Revision, error) {
+	var revisions []Revision
+	for key, data := range cm.Data {
+		if !strings.HasPrefix(key, RevisionKeyPrefix) {
+			continue
+		}
+		var r Revision
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			return nil, fmt.Errorf("invalid revision %s in configmap %s,err:%v", key, cm.Name, err)
+		}
+		revisions = append(revisions, r)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// appendRevisions adds the revisions of the specs to the configmap,keeping the latest ones within the limit,
+// the spec same as the latest revision is skipped
+func appendRevisions(cm *corev1.ConfigMap, user string, command string, previous *nineinfrav1alpha1.NineClusterSpec,
+	spec *nineinfrav1alpha1.NineClusterSpec) (bool, error) {
+	revisions, err := revisionsOf(cm)
+	if err != nil {
+		return false, err
+	}
+	var latest *Revision
+	if len(revisions) != 0 {
+		latest = &revisions[len(revisions)-1]
+	} else if previous != nil {
+		// the spec before the first recorded change,made by someone else
+		revisions = append(revisions, Revision{
+			Revision: 1,
+			Time:     UnknownRevisionSource,
+			User:     UnknownRevisionSource,
+			Command:  UnknownRevisionSource,
+			Spec:     *previous.DeepCopy(),
+		})
+		latest = &revisions[0]
+	}
+	// the first revision has nothing to compare with
+	next := 1
+	var changed []string
+	if latest != nil {
+		if changed = ChangedFields(&latest.Spec, spec); len(changed) == 0 {
+			return false, nil
+		}
+		next = latest.Revision + 1
+	}
+	revisions = append(revisions, Revision{
+		Revision: next,
+		Time:     time.Now().Format(time.RFC3339),
+		User:     user,
+		Command:  command,
+		Changed:  changed,
+		Spec:     *spec.DeepCopy(),
+	})
+	if len(revisions) > DefaultHistoryLimit {
+		revisions = revisions[len(revisions)-DefaultHistoryLimit:]
+	}
+	cm.Data = make(map[string]string)
+	for _, r := range revisions {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return false, err
+		}
+		cm.Data[RevisionKeyPrefix+strconv.Itoa(r.Revision)] = string(data)
+	}
+	if cm.Annotations == nil {
+		cm.Annotations = make(map[string]string)
+	}
+	cm.Annotations[LatestRevisionAnnoKey] = strconv.Itoa(next)
+	return true, nil
+}
+
+// RecordRevision records the spec of the NineCluster changed by the command,the previous spec is recorded
+// first if the NineCluster has no revision yet.It only warns on failure since the change has been made
+func RecordRevision(nc *nineinfrav1alpha1.NineCluster, previous *nineinfrav1alpha1.NineClusterSpec, command string) {
+	if err := recordRevision(nc, previous, command); err != nil {
+		fmt.Printf("Warning: failed to record the revision of NineCluster:%s,err:%v\n", nc.Name, err)
+	}
+}
+
+func recordRevision(nc *nineinfrav1alpha1.NineCluster, previous *nineinfrav1alpha1.NineClusterSpec, command string) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	user := CurrentUser()
+	name := NineResourceName(nc.Name, HistoryConfigMapSuffix)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := client.CoreV1().ConfigMaps(nc.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					Namespace:       nc.Namespace,
+					Labels:          NineResourceLabels(nc),
+					OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(nc, nineinfrav1alpha1.GroupVersion.WithKind(NineClusterKind))},
+				},
+			}
+			if _, err := appendRevisions(cm, user, command, previous, &nc.Spec); err != nil {
+				return err
+			}
+			_, err = client.CoreV1().ConfigMaps(nc.Namespace).Create(context.TODO(), cm, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		changed, err := appendRevisions(cm, user, command, previous, &nc.Spec)
+		if err != nil || !changed {
+			return err
+		}
+		_, err = client.CoreV1().ConfigMaps(nc.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// FindRevision returns the revision of the NineCluster
+func FindRevision(name string, namespace string, revision int) (*Revision, error) {
+	revisions, err := LoadRevisions(name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if revisions[i].Revision == revision {
+			return &revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d of NineCluster %s not found,check it by 'kubectl nine history %s -n %s'", revision, name, name, namespace)
+}
+
+type historyCmd struct {
+	out      io.Writer
+	errOut   io.Writer
+	name     string
+	ns       string
+	revision int
+}
+
+func newClusterHistoryCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &historyCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "history <NINECLUSTERNAME>",
+		Short:   "Show the spec revisions of a NineCluster",
+		Long:    historyDesc,
+		Example: historyExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := ValidateClusterArgs("history", args); err != nil {
+				return err
+			}
+			c.name = args[0]
+			if c.revision < 0 {
+				return errors.New("--revision should not be negative")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace scope for this request")
+	f.IntVar(&c.revision, "revision", 0, "show the spec of the revision")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	return cmd
+}
+
+func (c *historyCmd) run() error {
+	if c.revision > 0 {
+		r, err := FindRevision(c.name, c.ns, c.revision)
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(r.Spec)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Revision %d of NineCluster:%s by %s at %s\n", r.Revision, c.name, r.User, r.Time)
+		fmt.Print(string(data))
+		return nil
+	}
+	revisions, err := LoadRevisions(c.name, c.ns)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		fmt.Println("No revision of NineCluster:" + c.name + " in namespace:" + c.ns + " is recorded")
+		return nil
+	}
+	fmt.Printf(PrintFmtStrHistory, "REVISION", "TIME", "USER", "COMMAND", "CHANGED")
+	for _, r := range revisions {
+		changed := strings.Join(r.Changed, ",")
+		if len(r.Changed) > DefaultRevisionMaxShown {
+			changed = strings.Join(r.Changed[:DefaultRevisionMaxShown], ",") + fmt.Sprintf(",...(%d more)", len(r.Changed)-DefaultRevisionMaxShown)
+		}
+		if changed == "" {
+			changed = UnknownRevisionSource
+		}
+		fmt.Printf(PrintFmtStrHistory, strconv.Itoa(r.Revision), r.Time, r.User, r.Command, changed)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestChangedFields(t *testing.T) {
+	spec := func(mutate func(spec *nineinfrav1alpha1.NineClusterSpec)) *nineinfrav1alpha1.NineClusterSpec {
+		s := &nineinfrav1alpha1.NineClusterSpec{
+			DataVolume: 16,
+			Features:   map[string]string{FeaturesStorageKey: FeaturesStorageValueMinio},
+			ClusterSet: []nineinfrav1alpha1.ClusterInfo{
+				{Type: nineinfrav1alpha1.MinioClusterType, Version: "RELEASE.2023-09-07T02-05-02Z"},
+				{Type: nineinfrav1alpha1.DorisBEClusterType, Version: "v2.0.2", Resource: nineinfrav1alpha1.ResourceConfig{Replicas: 3}},
+			},
+		}
+		if mutate != nil {
+			mutate(s)
+		}
+		return s
+	}
+	tests := []struct {
+		name   string
+		before *nineinfrav1alpha1.NineClusterSpec
+		after  *nineinfrav1alpha1.NineClusterSpec
+		want   []string
+	}{
+		{"unchanged", spec(nil), spec(nil), []string{}},
+		{"top level field", spec(nil), spec(func(s *nineinfrav1alpha1.NineClusterSpec) { s.DataVolume = 32 }), []string{"dataVolume"}},
+		{"added feature", spec(nil), spec(func(s *nineinfrav1alpha1.NineClusterSpec) { s.Features[FeaturesOlapKey] = "doris" }),
+			[]string{"features." + FeaturesOlapKey}},
+		{"removed feature", spec(nil), spec(func(s *nineinfrav1alpha1.NineClusterSpec) { delete(s.Features, FeaturesStorageKey) }),
+			[]string{"features." + FeaturesStorageKey}},
+		{"cluster keyed by type", spec(nil), spec(func(s *nineinfrav1alpha1.NineClusterSpec) { s.ClusterSet[1].Resource.Replicas = 5 }),
+			[]string{"clusterSet[doris-be].resource.replicas"}},
+		{"reordered cluster set", spec(nil), spec(func(s *nineinfrav1alpha1.NineClusterSpec) {
+			s.ClusterSet[0], s.ClusterSet[1] = s.ClusterSet[1], s.ClusterSet[0]
+		}), []string{}},
+		{"deep field cut to its parent", spec(nil), spec(func(s *nineinfrav1alpha1.NineClusterSpec) {
+			s.ClusterSet[1].Resource.ResourceRequirements.Requests = corev1.ResourceList{"storage": resource.MustParse("100Gi")}
+		}), []string{"clusterSet[doris-be].resource.resourceRequirements"}},
+		{"several fields sorted", spec(nil), spec(func(s *nineinfrav1alpha1.NineClusterSpec) {
+			s.DataVolume = 32
+			s.ClusterSet[0].Version = "RELEASE.2024-01-01T00-00-00Z"
+		}), []string{"clusterSet[minio].version", "dataVolume"}},
+		{"from nil", nil, &nineinfrav1alpha1.NineClusterSpec{DataVolume: 1}, []string{"dataVolume"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChangedFields(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChangedFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"time"
+)
+
+const (
+	importDesc = `
+'import' command recreates a NineCluster from the bundle written by the 'export' command,in the order of the
+namespace,the generated secrets,the NineCluster,the ETL configmaps,the tool releases and the DAGs.The tool
+releases are installed after the postgresql of the NineCluster is ready,with the values depending on the
+kubernetes cluster like the superset datasources regenerated,and the DAGs are uploaded after the airflow
+scheduler is ready.`
+	importExample = `1. Import a NineCluster from a bundle
+   $ kubectl nine import -f c1-bundle.tar.gz
+
+2. Import a NineCluster into another namespace with the charts in a local path
+   $ kubectl nine import -f c1-bundle.tar.gz -n c1-dr --chart-path /opt/charts
+
+3. Show the contents of a bundle without importing
+   $ kubectl nine import -f c1-bundle.tar.gz --dry-run`
+)
+
+const DefaultImportTimeout = 30 * time.Minute
+
+type importCmd struct {
+	out            io.Writer
+	errOut         io.Writer
+	file           string
+	ns             string
+	chartPath      string
+	passphraseFile string
+	skipTools      bool
+	skipDags       bool
+	dryRun         bool
+	force          bool
+	yes            bool
+	timeout        time.Duration
+}
+
+func newClusterImportCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &importCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "import -f <BUNDLE>",
+		Short:   "Import a NineCluster from a portable bundle",
+		Long:    importDesc,
+		Example: importExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("import command takes the bundle by -f only")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.file, "file", "f", "", "file of the bundle")
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace to import into,defaults to the namespace in the bundle")
+	f.StringVarP(&c.chartPath, "chart-path", "p", "", "local path of the charts")
+	f.StringVar(&c.passphraseFile, "passphrase-file", "", "file holding the passphrase of the encrypted secrets,prompted if not specified")
+	f.BoolVar(&c.skipTools, "skip-tools", false, "do not install the tool releases and the DAGs")
+	f.BoolVar(&c.skipDags, "skip-dags", false, "do not upload the DAGs")
+	f.BoolVar(&c.dryRun, "dry-run", false, "only print the contents of the bundle")
+	f.BoolVar(&c.force, "force", false, "only warn if the storage pools could not fit the NineCluster")
+	f.BoolVarP(&c.yes, "yes", "y", false, "skip the confirmation prompt")
+	f.DurationVar(&c.timeout, "timeout", DefaultImportTimeout, "time to wait for the NineCluster and the tools to be ready")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+// retarget moves the objects of the bundle into the namespace
+func retarget(b *Bundle, namespace string) {
+	b.NineCluster.Namespace = namespace
+	for i := range b.ConfigMaps {
+		b.ConfigMaps[i].Namespace = namespace
+	}
+	for i := range b.Secrets {
+		b.Secrets[i].Namespace = namespace
+	}
+}
+
+// printBundle prints the contents of the bundle in the import order
+func printBundle(b *Bundle) {
+	fmt.Printf("Bundle of NineCluster:%s in namespace:%s exported at %s\n", b.Manifest.Cluster, b.Manifest.Namespace, b.Manifest.ExportedAt)
+	fmt.Println("The following will be imported in order:")
+	fmt.Printf("  namespace %s\n", b.NineCluster.Namespace)
+	for _, s := range b.Secrets {
+		fmt.Printf("  secret %s\n", s.Name)
+	}
+	fmt.Printf("  ninecluster %s\n", b.NineCluster.Name)
+	for _, cm := range b.ConfigMaps {
+		fmt.Printf("  configmap %s\n", cm.Name)
+	}
+	for _, r := range b.Manifest.Releases {
+		fmt.Printf("  release %s(%s %s)\n", r.Name, r.Tool, r.Version)
+	}
+	if b.Manifest.Dags {
+		fmt.Printf("  dags into %s\n", DefaultAirflowDagsPath)
+	}
+}
+
+func ensureNamespace(namespace string) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err == nil || !k8serrors.IsNotFound(err) {
+		return err
+	}
+	_, err = client.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Namespace %s is created\n", namespace)
+	return nil
+}
+
+// importSecrets creates the secrets,the existing ones are kept
+func importSecrets(secrets []corev1.Secret) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	for i := range secrets {
+		_, err := client.CoreV1().Secrets(secrets[i].Namespace).Create(context.TODO(), &secrets[i], metav1.CreateOptions{})
+		if k8serrors.IsAlreadyExists(err) {
+			fmt.Printf("Secret %s already exists,skip it\n", secrets[i].Name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Secret %s is created\n", secrets[i].Name)
+	}
+	return nil
+}
+
+// importConfigMaps creates or updates the configmaps owned by the NineCluster
+func importConfigMaps(nc *nineinfrav1alpha1.NineCluster, configMaps []corev1.ConfigMap) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	owner := metav1.NewControllerRef(nc, nineinfrav1alpha1.GroupVersion.WithKind(NineClusterKind))
+	for i := range configMaps {
+		cm := &configMaps[i]
+		cm.OwnerReferences = []metav1.OwnerReference{*owner}
+		existing, err := client.CoreV1().ConfigMaps(cm.Namespace).Get(context.TODO(), cm.Name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			if _, err := client.CoreV1().ConfigMaps(cm.Namespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+			fmt.Printf("ConfigMap %s is created\n", cm.Name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		existing.Data = cm.Data
+		existing.BinaryData = cm.BinaryData
+		if _, err := client.CoreV1().ConfigMaps(cm.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		fmt.Printf("ConfigMap %s is updated\n", cm.Name)
+	}
+	return nil
+}
+
+// importReleases creates the databases of the tools and installs the releases with their values
+func (c *importCmd) importReleases(b *Bundle) error {
+	nc := b.NineCluster
+	pg := PausedWorkload{Kind: PauseKindPG, Name: NineWorkLoadName(nc.Name, "postgresql")}
+	fmt.Printf("Waiting for the postgresql %s to be ready\n", pg.Name)
+	if err := waitWorkloadReady(pg, nc.Namespace, c.timeout); err != nil {
+		return err
+	}
+	t := &toolsCmd{ns: nc.Namespace, nineName: nc.Name, chartPath: c.chartPath}
+	if err := t.createDatabase(DefaultNineInfraDBName, DefaultNineInfraDBUser, DefaultNineInfraDBPwd); err != nil {
+		return err
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	var parameters []string
+	if path != "" {
+		parameters = append(parameters, "--kubeconfig", path)
+	}
+	for _, r := range b.Manifest.Releases {
+		if err := t.createToolDatabase(r.Tool); err != nil {
+			return err
+		}
+		valuesFile, cleanup, err := WriteTempValuesFile(r.Name, b.Values[r.Name])
+		if err != nil {
+			return err
+		}
+		params := append(append([]string{}, parameters...), "--values", valuesFile)
+		envParams, err := c.environmentParameters(t, r.Tool)
+		if err == nil {
+			err = HelmInstallWithParameters(r.Name, "", c.chartPath, r.Tool, r.Version, nc.Namespace, append(params, envParams...)...)
+		}
+		cleanup()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// environmentParameters regenerates the environment values of the tool on this kubernetes cluster,they override
+// the ones in the bundles exported by the old versions
+func (c *importCmd) environmentParameters(t *toolsCmd, tool string) ([]string, error) {
+	switch tool {
+	case DefaultToolSupersetName:
+		fmt.Println("Waiting for the thrift service to generate the superset datasources")
+		deadline := time.Now().Add(c.timeout)
+		for {
+			ip, port := GetThriftIpAndPort(t.nineName, t.ns)
+			if ip != "" && port != 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("the thrift service of the NineCluster %s is not found in %s", t.nineName, c.timeout)
+			}
+			time.Sleep(3 * time.Second)
+		}
+		if err := t.genSupersetDataSourcesFile(); err != nil {
+			return nil, err
+		}
+		return []string{"--set-file", fmt.Sprintf("extraConfigs.import_datasources=%s", DefaultToolSupersetSDataSourcesFile)}, nil
+	}
+	return nil, nil
+}
+
+// importDags uploads the DAGs into the airflow DAG volume after the scheduler is ready
+func (c *importCmd) importDags(b *Bundle) error {
+	nc := b.NineCluster
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Waiting for the airflow scheduler to be ready")
+	deadline := time.Now().Add(c.timeout)
+	for {
+		podNames, err := GetAirflowPodNames(nc.Name, "scheduler", nc.Namespace)
+		if err != nil {
+			return err
+		}
+		if len(podNames) != 0 {
+			pod, err := client.CoreV1().Pods(nc.Namespace).Get(context.TODO(), podNames[0], metav1.GetOptions{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return err
+			}
+			if err == nil && podReady(pod) {
+				_, err = RunExecCommandWithStdin(pod.Name, "scheduler", nc.Namespace,
+					[]string{"tar", "xf", "-", "-C", DefaultAirflowDagsPath}, bytes.NewReader(b.Dags))
+				if err != nil {
+					return fmt.Errorf("upload the DAGs failed,err:%v", err)
+				}
+				fmt.Printf("The DAGs are uploaded into %s\n", DefaultAirflowDagsPath)
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("airflow scheduler in namespace %s is not ready in %s", nc.Namespace, c.timeout)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+func (c *importCmd) run() error {
+	b, err := ReadBundle(c.file, func() (string, error) {
+		return ReadPassphrase(c.passphraseFile, false)
+	})
+	if err != nil {
+		return err
+	}
+	if c.ns != "" {
+		retarget(b, c.ns)
+	}
+	nc := b.NineCluster
+	if err := ValidateNineCluster(nc); err != nil {
+		return err
+	}
+	printBundle(b)
+	if c.dryRun {
+		return nil
+	}
+
+	if exists, _ := CheckNineClusterExist(nc.Name, nc.Namespace); exists {
+		return errors.New("NineCluster:" + nc.Name + " already exists in namespace:" + nc.Namespace + "!")
+	}
+	if err := CheckCapacity(NineClusterStorageDemands(nc), c.force); err != nil {
+		return err
+	}
+	if !c.yes && !Ask("The bundle will be imported as above, are you sure you want to continue") {
+		return errors.New("aborting NineCluster import")
+	}
+
+	if err := ensureNamespace(nc.Namespace); err != nil {
+		return err
+	}
+	// the secrets go before the NineCluster so that the operators take them instead of generating new ones
+	if err := importSecrets(b.Secrets); err != nil {
+		return err
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	created, err := client.NineinfraV1alpha1().NineClusters(nc.Namespace).Create(context.TODO(), nc, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	RecordRevision(created, nil, "import")
+	fmt.Println("NineCluster:" + created.Name + " in namespace:" + created.Namespace + " is created")
+	if err := importConfigMaps(created, b.ConfigMaps); err != nil {
+		return err
+	}
+	if !c.skipTools && len(b.Manifest.Releases) != 0 {
+		if err := c.importReleases(b); err != nil {
+			return fmt.Errorf("import the releases failed,the NineCluster is imported without the tools,err:%v", err)
+		}
+		if !c.skipDags && b.Manifest.Dags {
+			if err := c.importDags(b); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Println("NineCluster:" + created.Name + " in namespace:" + created.Namespace + " is imported successfully!")
+	fmt.Println("It may take a few minutes for it to be ready")
+	fmt.Println("You can check its status using the following command：")
+	fmt.Println("kubectl nine show " + created.Name + " -n " + created.Namespace)
+	return nil
+}
@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"k8s.io/klog/v2"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -13,14 +16,37 @@ import (
 const (
 	operatorInstallDesc = `
  'install' command creates the NineInfra platform along with all the dependencies.`
-	operatorInstallExample = `  kubectl nine install`
+	operatorInstallExample = `1. Install the NineInfra
+   $ kubectl nine install
+
+2. Install another NineInfra platform into its own namespace
+   $ kubectl nine install --platform-namespace nineinfra-staging
+
+3. Install the NineInfra with custom values of the charts
+   $ kubectl nine install --values doris-operator=doris.yaml --set nineinfra.replicaCount=2 --show-values
+
+4. Install the NineInfra with at most 2 charts in parallel
+   $ kubectl nine install --parallel 2 --timeout 15m`
+)
+
+const (
+	DefaultInstallParallel     = 4
+	DefaultInstallReadyTimeout = 10 * time.Minute
+	installReadyPollInterval   = 3 * time.Second
 )
 
 type operatorInstallCmd struct {
-	out       io.Writer
-	errOut    io.Writer
-	output    bool
-	chartPath string
+	out        io.Writer
+	errOut     io.Writer
+	output     bool
+	chartPath  string
+	platformNS string
+	valuesArgs []string
+	setArgs    []string
+	showValues bool
+	values     *ChartValues
+	parallel   int
+	timeout    time.Duration
 }
 
 func newInstallCmd(out io.Writer, errOut io.Writer) *cobra.Command {
@@ -33,7 +59,15 @@ func newInstallCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 		Example: operatorInstallExample,
 		Args:    cobra.MaximumNArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := o.run()
+			values, err := NewChartValues(o.valuesArgs, o.setArgs, o.showValues, DefaultChartList)
+			if err != nil {
+				return err
+			}
+			o.values = values
+			if o.parallel < 1 {
+				return fmt.Errorf("invalid --parallel %d,should be at least 1", o.parallel)
+			}
+			err = o.run()
 			if err != nil {
 				klog.Warning(err)
 				return err
@@ -45,11 +79,26 @@ func newInstallCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	f := cmd.Flags()
 	f.BoolVar(&DEBUG, "debug", false, "print debug information")
 	f.StringVarP(&o.chartPath, "chart-path", "p", "", "local path of the charts")
+	f.StringVar(&o.platformNS, "platform-namespace", DefaultNamespace, "k8s namespace for the NineInfra platform")
+	f.StringArrayVar(&o.valuesArgs, "values", nil, "values file of a chart,e.g. doris-operator=doris.yaml")
+	f.StringArrayVar(&o.setArgs, "set", nil, "value of a chart,e.g. nineinfra.replicaCount=2")
+	f.BoolVar(&o.showValues, "show-values", false, "only print the merged values of the charts")
+	f.IntVar(&o.parallel, "parallel", DefaultInstallParallel, "max number of the charts installed in parallel")
+	f.DurationVar(&o.timeout, "timeout", DefaultInstallReadyTimeout, "time to wait for the workloads of a chart to be ready")
+	f.StringVar(&DefaultToolImportDir, "from-dir", "", "local dir holding the release artifacts of the tools")
 	return cmd
 }
 
 // run initializes local config and installs the Nineinfra to Kubernetes cluster.
 func (o *operatorInstallCmd) run() error {
+	if o.values.ShowOnly() {
+		for _, c := range SortedKeys(DefaultChartList) {
+			if _, _, err := o.values.Apply(c, c, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
 
@@ -64,23 +113,134 @@ func (o *operatorInstallCmd) run() error {
 		os.Exit(1)
 	}
 
-	if err := CreateIfNotExist(DefaultNamespace, "namespace", flags); err != nil {
+	if err := CreateIfNotExist(o.platformNS, "namespace", flags); err != nil {
 		fmt.Printf("Error: %v \n", err)
 		os.Exit(1)
 	}
 
-	for c, v := range DefaultChartList {
-		err := HelmInstall(c, "", o.chartPath, c, v, DefaultNamespace, flags)
-		if err != nil {
-			fmt.Printf("Error: %v \n", err)
-			os.Exit(1)
+	others, err := OtherPlatformNamespaces(o.platformNS)
+	if err != nil {
+		return err
+	}
+	if len(others) != 0 {
+		// the crds and the cluster scoped charts are owned by the first platform
+		fmt.Printf("NineInfra platforms found in [%s],the crds and the cluster scoped charts [%s] are shared with them\n",
+			strings.Join(others, ","), strings.Join(NineInfraClusterScopedCharts, ","))
+		parameters = append(parameters, "--skip-crds")
+	}
+
+	charts := make([]string, 0)
+	for _, c := range SortedKeys(DefaultChartList) {
+		if len(others) != 0 && IsClusterScopedChart(c) {
+			continue
 		}
+		charts = append(charts, c)
+	}
+	if err := o.installCharts(charts, parameters); err != nil {
+		fmt.Printf("Error: %v \n", err)
+		os.Exit(1)
+	}
+
+	if err := MarkPlatformNamespace(o.platformNS); err != nil {
+		fmt.Printf("Error: %v \n", err)
+		os.Exit(1)
 	}
 
 	fmt.Println("NineInfra is installed successfully!")
-	fmt.Println("It may take a few minutes for it to be ready")
 	fmt.Println("You can check its status using the following command")
-	fmt.Println("kubectl nine status")
+	fmt.Println("kubectl nine status --platform-namespace " + o.platformNS)
+
+	return nil
+}
+
+// waitReleaseReady waits for the workloads of the release in the namespace to be ready
+func waitReleaseReady(release string, namespace string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, ready, err := workloadStatus(namespace)
+		if err != nil {
+			return err
+		}
+		// the release without workloads in the namespace is ready once installed
+		if r, ok := ready[release]; !ok || r {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the workloads of %s are not ready in %s", release, timeout)
+		}
+		time.Sleep(installReadyPollInterval)
+	}
+}
 
+// installChart installs the chart and waits for it to be ready
+func (o *operatorInstallCmd) installChart(chart string, parameters []string, view *ProgressView) error {
+	view.Set(chart, ChartStateInstalling, nil)
+	params, cleanup, err := o.values.Apply(chart, chart, parameters)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := helmInstallChart(chart, o.chartPath, chart, DefaultChartList[chart], o.platformNS, params...); err != nil {
+		return err
+	}
+	view.Set(chart, ChartStateWaitingReady, nil)
+	return waitReleaseReady(chart, o.platformNS, o.timeout)
+}
+
+// installCharts installs the charts by a worker pool,a chart is installed once all of its dependencies are ready
+func (o *operatorInstallCmd) installCharts(charts []string, parameters []string) error {
+	view := NewProgressView(os.Stdout, charts)
+	view.Start()
+
+	sem := make(chan struct{}, o.parallel)
+	done := make(map[string]chan struct{})
+	for _, c := range charts {
+		done[c] = make(chan struct{})
+	}
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	var wg sync.WaitGroup
+	for _, c := range charts {
+		wg.Add(1)
+		go func(chart string) {
+			defer wg.Done()
+			defer close(done[chart])
+			for _, dep := range NineInfraChartDependencies[chart] {
+				ch, ok := done[dep]
+				if !ok {
+					// the dependency is shared with the other platforms
+					continue
+				}
+				<-ch
+				mu.Lock()
+				depFailed := failed[dep]
+				if depFailed {
+					failed[chart] = true
+				}
+				mu.Unlock()
+				if depFailed {
+					view.Set(chart, ChartStateSkipped, fmt.Errorf("dependency %s failed", dep))
+					return
+				}
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := o.installChart(chart, parameters, view); err != nil {
+				mu.Lock()
+				failed[chart] = true
+				mu.Unlock()
+				view.Set(chart, ChartStateFailed, err)
+				return
+			}
+			view.Set(chart, ChartStateDone, nil)
+		}(c)
+	}
+	wg.Wait()
+	view.Stop()
+	view.PrintSummary()
+
+	if len(failed) != 0 {
+		return errors.New(fmt.Sprintf("%d of %d charts failed to install", len(failed), len(charts)))
+	}
 	return nil
 }
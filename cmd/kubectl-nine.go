@@ -3,6 +3,7 @@ package cmd
 import (
 	"k8s.io/cli-runtime/pkg/genericiooptions"
 	"log"
+	"os"
 
 	"github.com/spf13/cobra"
 	// Workaround for auth import issues refer https://github.com/minio/operator/issues/283
@@ -15,22 +16,34 @@ const (
 The NineInfra is a cloudnative data platform.
 You can reference to https://github.com/nineinfra/nineinfra`
 	kubeconfig  = "kubeconfig"
+	kubecontext = "context"
 	nineVersion = "v0.8.0"
 )
 
 var (
-	confPath string
-	rootCmd  = &cobra.Command{
+	confPath    string
+	kubeContext string
+	rootCmd     = &cobra.Command{
 		Use:          "nine",
 		Short:        nineShortDesc,
 		Long:         nineDesc,
 		Version:      nineVersion,
 		SilenceUsage: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// the helm releases are managed in the same kubeconfig and context as the clients
+			if confPath != "" {
+				_ = os.Setenv("KUBECONFIG", confPath)
+			}
+			if kubeContext != "" {
+				_ = os.Setenv("HELM_KUBECONTEXT", kubeContext)
+			}
+		},
 	}
 )
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&confPath, kubeconfig, "", "Custom kubeconfig path")
+	rootCmd.PersistentFlags().StringVar(&kubeContext, kubecontext, "", "Name of the kubeconfig context to use")
 
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 }
@@ -38,14 +51,30 @@ func init() {
 // New creates a new root command for kubectl-nine
 func New(_ genericiooptions.IOStreams) *cobra.Command {
 	rootCmd = DisableHelp(rootCmd)
+	AddToolCacheToPath()
 	cobra.EnableCommandSorting = false
 	rootCmd.AddCommand(newPrepareCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newInstallCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newUninstallCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newUpgradeCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newNineStatusCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newClusterCreateCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterApplyCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterCloneCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterExportCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterImportCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newClusterDeleteCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterGCCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterScaleCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterUpdateCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterUpgradeCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterPauseCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterResumeCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterRestartCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterHistoryCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterRollbackCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newClusterListCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
+	rootCmd.AddCommand(newClusterUsageCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newClusterDescribeCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newClusterShowCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
 	rootCmd.AddCommand(newClusterSqlCmd(rootCmd.OutOrStdout(), rootCmd.ErrOrStderr()))
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/spf13/cobra"
 	"io"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -10,21 +12,41 @@ import (
 )
 
 const (
-	listDesc = `'list' command lists all clusters managed by the NineInfra`
+	listDesc = `'list' command lists all clusters managed by the NineInfra.
+With --all-contexts or --contexts,the NineClusters in the kubeconfig contexts are listed concurrently in one
+table,the unreachable contexts are reported without failing the others.`
+	listExample = `1. List the NineClusters
+   $ kubectl nine list
+
+2. List the NineClusters in all the contexts of the kubeconfig
+   $ kubectl nine list --all-contexts
+
+3. List the NineClusters in some contexts with a timeout of 10 seconds for each
+   $ kubectl nine list --contexts prod-a,prod-b --context-timeout 10s
+
+4. List the NineClusters in json
+   $ kubectl nine list --output json`
+)
+
+const (
+	PrintFmtStrFleetClusterList = "%-20s\t%-20s\t%-10s\t%-12s\t%-10s\t%-10s\t%-10s\n"
 )
 
 type listCmd struct {
 	out    io.Writer
 	errOut io.Writer
+	output string
+	fleet  FleetOptions
 }
 
 func newClusterListCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	c := &listCmd{out: out, errOut: errOut}
 
 	cmd := &cobra.Command{
-		Use:   "list",
-		Short: "List all NineClusters",
-		Long:  listDesc,
+		Use:     "list",
+		Short:   "List all NineClusters",
+		Long:    listDesc,
+		Example: listExample,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := c.validate(args); err != nil {
 				return err
@@ -38,6 +60,10 @@ func newClusterListCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVar(&c.output, "output", "", "output format of the list,support [json]")
+	c.fleet.AddFlags(f)
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
 	return cmd
 }
 
@@ -45,10 +71,16 @@ func (d *listCmd) validate(args []string) error {
 	if len(args) != 0 {
 		return errors.New("list command doesn't take any argument, try 'kubectl nine list'")
 	}
-	return nil
+	if d.output != "" && d.output != OutputJson {
+		return errors.New("output format " + d.output + " is not supported,support [json]")
+	}
+	return d.fleet.Validate()
 }
 
 func (d *listCmd) run(_ []string) error {
+	if d.fleet.Enabled() {
+		return d.runInContexts()
+	}
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
 	nclient, err := GetNineInfraClient(path)
 	if err != nil {
@@ -59,7 +91,59 @@ func (d *listCmd) run(_ []string) error {
 	if err != nil {
 		return err
 	}
+	if d.output == OutputJson {
+		return printJson(ClusterListItems(clusters))
+	}
 	PrintClusterList(clusters)
 
 	return nil
 }
+
+// runInContexts lists the NineClusters in the contexts,failing only if all the contexts are unreachable
+func (d *listCmd) runInContexts() error {
+	contexts, err := d.fleet.ResolveContexts()
+	if err != nil {
+		return err
+	}
+	items := make([]ClusterListItem, 0)
+	var unreachable []string
+	for _, r := range RunInContexts(contexts, d.fleet.Timeout, "list", "--output", OutputJson) {
+		var contextItems []ClusterListItem
+		if r.Err == nil {
+			r.Err = json.Unmarshal(r.Output, &contextItems)
+		}
+		if r.Err != nil {
+			unreachable = append(unreachable, r.Context)
+			items = append(items, ClusterListItem{Context: r.Context, Name: "-", DataVolume: "-", Ready: ContextStateUnreachable, Namespace: "-", Age: "-", Expires: "-"})
+			fmt.Fprintf(d.errOut, "Warning: context %s is unreachable,err:%v\n", r.Context, r.Err)
+			continue
+		}
+		for _, item := range contextItems {
+			item.Context = r.Context
+			items = append(items, item)
+		}
+	}
+	if d.output == OutputJson {
+		if err := printJson(items); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf(PrintFmtStrFleetClusterList, "CONTEXT", "NAME", "DATAVOLUME", "READY", "NAMESPACE", "AGE", "EXPIRES")
+		for _, item := range items {
+			fmt.Printf(PrintFmtStrFleetClusterList, item.Context, item.Name, item.DataVolume, item.Ready, item.Namespace, item.Age, item.Expires)
+		}
+	}
+	if len(unreachable) == len(contexts) {
+		return errors.New("all the contexts are unreachable")
+	}
+	return nil
+}
+
+func printJson(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -297,7 +297,7 @@ func (o *onekeyCmd) uploadFileToAirflow(filename string, cluster *nineinfrav1alp
 	if err != nil {
 		return err
 	}
-	_, _, err = runCommand("kubectl", "cp", filename, fmt.Sprintf("%s:%s/%s", podNames[0], DefaultAirflowDagsPath, filename), "-n", cluster.Namespace)
+	_, _, err = runCommand("kubectl", kubectlArgs("cp", filename, fmt.Sprintf("%s:%s/%s", podNames[0], DefaultAirflowDagsPath, filename), "-n", cluster.Namespace)...)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,504 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+const (
+	pauseDesc = `
+'pause' command scales all the workloads of a NineCluster and its tools to zero and hibernates its postgresql,
+the pvcs are kept.The replicas are recorded in the annotations of the NineCluster for the resume.
+The workloads are watched for a reconcile period after the pause,if the operators scale them back the pause
+is rolled back and fails,as the operators not honoring nine.nineinfra.tech/paused could not be paused.`
+	pauseExample = `1. Pause a NineCluster
+   $ kubectl nine pause c1 -n c1-ns
+
+2. Show the workloads will be paused
+   $ kubectl nine pause c1 -n c1-ns --dry-run`
+	resumeDesc = `
+'resume' command restores the replicas of a paused NineCluster in the dependency order,
+postgresql,zookeeper,hdfs or minio,metastore,kyuubi,doris and then the tools,waiting for each stage to be ready.`
+	resumeExample = `1. Resume a NineCluster
+   $ kubectl nine resume c1 -n c1-ns`
+)
+
+const (
+	PausedAnnoKey          = "nine.nineinfra.tech/paused"
+	PausedWorkloadsAnnoKey = "nine.nineinfra.tech/paused-workloads"
+	PGHibernationAnnoKey   = "cnpg.io/hibernation"
+	PGHibernationOn        = "on"
+	PGHibernationOff       = "off"
+	ClusterStatePaused     = "PAUSED"
+	PrintFmtStrPausePlan   = "%-12s\t%-12s\t%-48s\t%-10s\n"
+	DefaultResumeTimeout   = 10 * time.Minute
+	DefaultPauseVerify     = time.Minute
+)
+
+const (
+	PauseStagePostgresql = "postgresql"
+	PauseStageZookeeper  = "zookeeper"
+	PauseStageStorage    = "storage"
+	PauseStageMetastore  = "metastore"
+	PauseStageKyuubi     = "kyuubi"
+	PauseStageOlap       = "olap"
+	PauseStageOthers     = "others"
+	PauseStageTools      = "tools"
+	PauseKindSts         = "statefulset"
+	PauseKindDeploy      = "deployment"
+	PauseKindPG          = "cluster"
+)
+
+// resumeStageOrder is the dependency order to resume a NineCluster,the pause goes in the reverse order
+var resumeStageOrder = []string{
+	PauseStagePostgresql,
+	PauseStageZookeeper,
+	PauseStageStorage,
+	PauseStageMetastore,
+	PauseStageKyuubi,
+	PauseStageOlap,
+	PauseStageOthers,
+	PauseStageTools,
+}
+
+// projectPauseStages are the stages of the projects of the NineCluster
+var projectPauseStages = map[string]string{
+	"zookeeper":   PauseStageZookeeper,
+	"journalnode": PauseStageStorage,
+	"namenode":    PauseStageStorage,
+	"datanode":    PauseStageStorage,
+	"minio":       PauseStageStorage,
+	"metastore":   PauseStageMetastore,
+	"kyuubi":      PauseStageKyuubi,
+	"doris-fe":    PauseStageOlap,
+	"doris-be":    PauseStageOlap,
+}
+
+// PausedWorkload is a workload of the paused NineCluster with its replicas before the pause
+type PausedWorkload struct {
+	Stage    string `json:"stage"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Replicas int32  `json:"replicas"`
+}
+
+type pauseCmd struct {
+	out     io.Writer
+	errOut  io.Writer
+	name    string
+	ns      string
+	resume  bool
+	dryRun  bool
+	yes     bool
+	timeout time.Duration
+	verify  time.Duration
+}
+
+func newClusterPauseCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	return newPauseResumeCmd(out, errOut, false)
+}
+
+func newClusterResumeCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	return newPauseResumeCmd(out, errOut, true)
+}
+
+func newPauseResumeCmd(out io.Writer, errOut io.Writer, resume bool) *cobra.Command {
+	c := &pauseCmd{out: out, errOut: errOut, resume: resume}
+
+	cmd := &cobra.Command{
+		Use:     "pause <NINECLUSTERNAME>",
+		Short:   "Pause a NineCluster by scaling its workloads to zero",
+		Long:    pauseDesc,
+		Example: pauseExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := ValidateClusterArgs(cmd.Name(), args); err != nil {
+				return err
+			}
+			c.name = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run := c.pause
+			if c.resume {
+				run = c.resumeCluster
+			}
+			err := run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	if resume {
+		cmd.Use = "resume <NINECLUSTERNAME>"
+		cmd.Short = "Resume a paused NineCluster"
+		cmd.Long = resumeDesc
+		cmd.Example = resumeExample
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace scope for this request")
+	f.BoolVar(&c.dryRun, "dry-run", false, "only print the workloads and their replicas")
+	if resume {
+		f.DurationVar(&c.timeout, "timeout", DefaultResumeTimeout, "time to wait for each stage to be ready")
+	} else {
+		f.BoolVarP(&c.yes, "yes", "y", false, "skip the confirmation prompt")
+		f.DurationVar(&c.verify, "verify-period", DefaultPauseVerify, "time to check the workloads are not scaled back by the operators")
+	}
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	return cmd
+}
+
+// IsNineClusterPaused returns true if the NineCluster is paused by the pause command
+func IsNineClusterPaused(nc *nineinfrav1alpha1.NineCluster) bool {
+	return nc.Annotations[PausedAnnoKey] == "true"
+}
+
+// pauseStageOf returns the stage of the workload of the NineCluster,empty if it is not a part of the NineCluster,
+// the others are the other NineClusters in the namespace
+func pauseStageOf(name string, others []string, workload string, objLabels map[string]string, annotations map[string]string) string {
+	release := annotations[HelmReleaseNameAnnoKey]
+	for tool := range NineToolList {
+		if release == NineResourceName(name, tool) {
+			return PauseStageTools
+		}
+	}
+	for project, stage := range projectPauseStages {
+		if workload == NineWorkLoadName(name, project) {
+			return stage
+		}
+	}
+	if ownedByNineCluster(name, others, workload, objLabels) {
+		return PauseStageOthers
+	}
+	return ""
+}
+
+// NineClusterWorkloads returns the statefulsets,the deployments and the postgresql of the NineCluster and its tools
+func NineClusterWorkloads(name string, namespace string) ([]PausedWorkload, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	others, err := otherNineClusters(name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var workloads []PausedWorkload
+	stsList, err := client.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, sts := range stsList.Items {
+		if stage := pauseStageOf(name, others, sts.Name, sts.Labels, sts.Annotations); stage != "" {
+			workloads = append(workloads, PausedWorkload{Stage: stage, Kind: PauseKindSts, Name: sts.Name, Replicas: *sts.Spec.Replicas})
+		}
+	}
+	deploys, err := client.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, deploy := range deploys.Items {
+		if stage := pauseStageOf(name, others, deploy.Name, deploy.Labels, deploy.Annotations); stage != "" {
+			workloads = append(workloads, PausedWorkload{Stage: stage, Kind: PauseKindDeploy, Name: deploy.Name, Replicas: *deploy.Spec.Replicas})
+		}
+	}
+	pgClient, err := GetPGOperatorClient(path)
+	if err != nil {
+		return nil, err
+	}
+	pgName := NineWorkLoadName(name, "postgresql")
+	pg, err := pgClient.PostgresqlV1().Clusters(namespace).Get(context.TODO(), pgName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		workloads = append(workloads, PausedWorkload{Stage: PauseStagePostgresql, Kind: PauseKindPG, Name: pgName, Replicas: int32(pg.Spec.Instances)})
+	}
+	return workloads, nil
+}
+
+func printPausedWorkloads(workloads []PausedWorkload, stages []string) {
+	fmt.Printf(PrintFmtStrPausePlan, "STAGE", "KIND", "NAME", "REPLICAS")
+	for _, stage := range stages {
+		for _, w := range workloads {
+			if w.Stage == stage {
+				fmt.Printf(PrintFmtStrPausePlan, w.Stage, w.Kind, w.Name, fmt.Sprint(w.Replicas))
+			}
+		}
+	}
+}
+
+// scaleWorkload scales the statefulset or the deployment,the postgresql is hibernated for zero replicas
+func scaleWorkload(w PausedWorkload, namespace string, replicas int32) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	if w.Kind == PauseKindPG {
+		pgClient, err := GetPGOperatorClient(path)
+		if err != nil {
+			return err
+		}
+		hibernation := PGHibernationOff
+		if replicas == 0 {
+			hibernation = PGHibernationOn
+		}
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{"%s":"%s"}}}`, PGHibernationAnnoKey, hibernation)
+		_, err = pgClient.PostgresqlV1().Clusters(namespace).Patch(context.TODO(), w.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		return err
+	}
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	switch w.Kind {
+	case PauseKindSts:
+		_, err = client.AppsV1().StatefulSets(namespace).Patch(context.TODO(), w.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case PauseKindDeploy:
+		_, err = client.AppsV1().Deployments(namespace).Patch(context.TODO(), w.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	return err
+}
+
+// waitWorkloadReady waits for the workload to have the replicas all ready
+func waitWorkloadReady(w PausedWorkload, namespace string, timeout time.Duration) error {
+	switch w.Kind {
+	case PauseKindSts:
+		return WaitStsReady(w.Name, namespace, w.Replicas, timeout)
+	case PauseKindDeploy:
+		return WaitDeployReady(w.Name, namespace, w.Replicas, timeout)
+	}
+	deadline := time.Now().Add(timeout)
+	for !CheckPGClusterIfReady(w.Name, namespace) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgresql %s in namespace %s is not ready in %s", w.Name, namespace, timeout)
+		}
+		time.Sleep(3 * time.Second)
+	}
+	return nil
+}
+
+// workloadReplicas returns the desired replicas of the statefulset or the deployment
+func workloadReplicas(w PausedWorkload, namespace string) (int32, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return 0, err
+	}
+	var replicas *int32
+	switch w.Kind {
+	case PauseKindSts:
+		sts, err := client.AppsV1().StatefulSets(namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		replicas = sts.Spec.Replicas
+	case PauseKindDeploy:
+		deploy, err := client.AppsV1().Deployments(namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		replicas = deploy.Spec.Replicas
+	}
+	if replicas == nil {
+		return 1, nil
+	}
+	return *replicas, nil
+}
+
+// verifyPaused checks the statefulsets and the deployments stay scaled to zero for the period,
+// the postgresql is hibernated by its operator
+func verifyPaused(workloads []PausedWorkload, namespace string, period time.Duration) error {
+	deadline := time.Now().Add(period)
+	for {
+		for _, w := range workloads {
+			if w.Kind == PauseKindPG || w.Replicas == 0 {
+				continue
+			}
+			replicas, err := workloadReplicas(w, namespace)
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if replicas != 0 {
+				return fmt.Errorf("%s %s is scaled back to %d", w.Kind, w.Name, replicas)
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// rollbackPause restores the replicas of the workloads and removes the pause annotations
+func (c *pauseCmd) rollbackPause(workloads []PausedWorkload) {
+	for _, w := range workloads {
+		if err := scaleWorkload(w, c.ns, w.Replicas); err != nil && !k8serrors.IsNotFound(err) {
+			klog.Warningf("restore %s %s failed,err:%v", w.Kind, w.Name, err)
+		}
+	}
+	if err := annotateNineCluster(c.name, c.ns, map[string]string{PausedAnnoKey: "", PausedWorkloadsAnnoKey: ""}); err != nil {
+		klog.Warning(err)
+	}
+}
+
+// annotateNineCluster sets the annotations of the NineCluster,the empty values are removed
+func annotateNineCluster(name string, namespace string, annotations map[string]string) error {
+	values := make(map[string]interface{})
+	for k, v := range annotations {
+		if v == "" {
+			values[k] = nil
+		} else {
+			values[k] = v
+		}
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": values}})
+	if err != nil {
+		return err
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	_, err = client.NineinfraV1alpha1().NineClusters(namespace).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func (c *pauseCmd) getNineCluster() (*nineinfrav1alpha1.NineCluster, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return nil, err
+	}
+	return client.NineinfraV1alpha1().NineClusters(c.ns).Get(context.TODO(), c.name, metav1.GetOptions{})
+}
+
+// pause records the replicas of the NineCluster and scales its workloads to zero in the reverse order
+func (c *pauseCmd) pause() error {
+	nc, err := c.getNineCluster()
+	if err != nil {
+		return err
+	}
+	if IsNineClusterPaused(nc) {
+		return errors.New("NineCluster:" + c.name + " in namespace:" + c.ns + " is already paused")
+	}
+	workloads, err := NineClusterWorkloads(c.name, c.ns)
+	if err != nil {
+		return err
+	}
+	stages := make([]string, 0, len(resumeStageOrder))
+	for i := len(resumeStageOrder) - 1; i >= 0; i-- {
+		stages = append(stages, resumeStageOrder[i])
+	}
+	printPausedWorkloads(workloads, stages)
+	if c.dryRun {
+		return nil
+	}
+	if !c.yes && !Ask("The workloads above will be scaled to zero, are you sure you want to continue") {
+		return errors.New("aborting NineCluster pause")
+	}
+
+	// record the replicas first,so that a partial pause could still be resumed
+	data, err := json.Marshal(workloads)
+	if err != nil {
+		return err
+	}
+	err = annotateNineCluster(c.name, c.ns, map[string]string{PausedAnnoKey: "true", PausedWorkloadsAnnoKey: string(data)})
+	if err != nil {
+		return err
+	}
+	for _, stage := range stages {
+		for _, w := range workloads {
+			if w.Stage != stage {
+				continue
+			}
+			if err := scaleWorkload(w, c.ns, 0); err != nil {
+				return fmt.Errorf("pause %s %s failed,err:%v", w.Kind, w.Name, err)
+			}
+			if DEBUG {
+				fmt.Printf("Scale %s %s to 0\n", w.Kind, w.Name)
+			}
+		}
+	}
+	fmt.Printf("Verifying the workloads are not scaled back in %s\n", c.verify)
+	if err := verifyPaused(workloads, c.ns, c.verify); err != nil {
+		c.rollbackPause(workloads)
+		return fmt.Errorf("NineCluster:%s in namespace:%s could not be paused,the operator does not support pausing,%v", c.name, c.ns, err)
+	}
+	fmt.Println("NineCluster:" + c.name + " in namespace:" + c.ns + " is paused,the pvcs are kept")
+	fmt.Println("You can resume it using the following command：")
+	fmt.Println("kubectl nine resume " + c.name + " -n " + c.ns)
+	return nil
+}
+
+// resumeCluster restores the replicas of the NineCluster stage by stage
+func (c *pauseCmd) resumeCluster() error {
+	nc, err := c.getNineCluster()
+	if err != nil {
+		return err
+	}
+	if !IsNineClusterPaused(nc) {
+		return errors.New("NineCluster:" + c.name + " in namespace:" + c.ns + " is not paused")
+	}
+	var workloads []PausedWorkload
+	if err := json.Unmarshal([]byte(nc.Annotations[PausedWorkloadsAnnoKey]), &workloads); err != nil {
+		return fmt.Errorf("invalid annotation %s of NineCluster:%s,err:%v", PausedWorkloadsAnnoKey, c.name, err)
+	}
+	printPausedWorkloads(workloads, resumeStageOrder)
+	if c.dryRun {
+		return nil
+	}
+
+	for _, stage := range resumeStageOrder {
+		var resumed []PausedWorkload
+		for _, w := range workloads {
+			if w.Stage != stage {
+				continue
+			}
+			if err := scaleWorkload(w, c.ns, w.Replicas); err != nil {
+				if k8serrors.IsNotFound(err) {
+					fmt.Printf("Skip %s %s,it is not found\n", w.Kind, w.Name)
+					continue
+				}
+				return fmt.Errorf("resume %s %s failed,err:%v", w.Kind, w.Name, err)
+			}
+			resumed = append(resumed, w)
+		}
+		for _, w := range resumed {
+			if err := waitWorkloadReady(w, c.ns, c.timeout); err != nil {
+				return err
+			}
+		}
+		if len(resumed) != 0 {
+			names := make([]string, 0, len(resumed))
+			for _, w := range resumed {
+				names = append(names, w.Name)
+			}
+			fmt.Printf("Stage %s is ready:%s\n", stage, strings.Join(names, ","))
+		}
+	}
+
+	err = annotateNineCluster(c.name, c.ns, map[string]string{PausedAnnoKey: "", PausedWorkloadsAnnoKey: ""})
+	if err != nil {
+		return err
+	}
+	fmt.Println("NineCluster:" + c.name + " in namespace:" + c.ns + " is resumed successfully!")
+	return nil
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultPlatformLabelKey             = "nineinfra.tech/platform"
+	DefaultPlatformLabelValue           = "true"
+	DefaultPlatformVersionAnnotationKey = "nineinfra.tech/platform-version"
+	DefaultPlatformInstalledByKey       = "nineinfra.tech/installed-by"
+	DefaultWatchNamespaceEnv            = "WATCH_NAMESPACE"
+)
+
+// NineInfraClusterScopedCharts are the charts which install cluster scoped components,
+// only one NineInfra platform in a k8s cluster can own them
+var NineInfraClusterScopedCharts = []string{
+	"minio-directpv",
+}
+
+func IsClusterScopedChart(chart string) bool {
+	for _, c := range NineInfraClusterScopedCharts {
+		if c == chart {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverPlatformNamespaces returns the namespaces where a NineInfra platform is installed
+func DiscoverPlatformNamespaces() ([]string, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	nsList, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", DefaultPlatformLabelKey, DefaultPlatformLabelValue),
+	})
+	if err != nil {
+		return nil, err
+	}
+	platforms := make([]string, 0)
+	for _, ns := range nsList.Items {
+		platforms = append(platforms, ns.Name)
+	}
+	sort.Strings(platforms)
+	return platforms, nil
+}
+
+// ResolvePlatformNamespace returns the namespace of the NineInfra platform,the given one takes precedence,
+// otherwise the only labeled platform namespace,and the DefaultNamespace for the platforms installed
+// by the older versions of the nine
+func ResolvePlatformNamespace(ns string) (string, error) {
+	if ns != "" {
+		return ns, nil
+	}
+	platforms, err := DiscoverPlatformNamespaces()
+	if err != nil {
+		return "", err
+	}
+	switch len(platforms) {
+	case 0:
+		return DefaultNamespace, nil
+	case 1:
+		return platforms[0], nil
+	default:
+		return "", fmt.Errorf("multiple NineInfra platforms found in [%s],please specify one by --platform-namespace", strings.Join(platforms, ","))
+	}
+}
+
+// MarkPlatformNamespace labels the namespace as a NineInfra platform,so the later commands can find it
+func MarkPlatformNamespace(ns string) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	existsNs, err := client.CoreV1().Namespaces().Get(context.TODO(), ns, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existsNs.Labels == nil {
+		existsNs.Labels = make(map[string]string)
+	}
+	if existsNs.Annotations == nil {
+		existsNs.Annotations = make(map[string]string)
+	}
+	existsNs.Labels[DefaultPlatformLabelKey] = DefaultPlatformLabelValue
+	existsNs.Annotations[DefaultPlatformVersionAnnotationKey] = DefaultChartList[DefaultNineInfraPrefix]
+	existsNs.Annotations[DefaultPlatformInstalledByKey] = "kubectl-nine-" + nineVersion
+	_, err = client.CoreV1().Namespaces().Update(context.TODO(), existsNs, metav1.UpdateOptions{})
+	return err
+}
+
+// OtherPlatformNamespaces returns the NineInfra platforms installed in the namespaces other than ns
+func OtherPlatformNamespaces(ns string) ([]string, error) {
+	platforms, err := DiscoverPlatformNamespaces()
+	if err != nil {
+		return nil, err
+	}
+	others := make([]string, 0)
+	for _, p := range platforms {
+		if p != ns {
+			others = append(others, p)
+		}
+	}
+	return others, nil
+}
+
+// PlatformWatchNamespaces returns the namespaces watched by the operators of the NineInfra platform in ns,
+// nil if any of them watches all the namespaces,which is the default without the WATCH_NAMESPACE env
+func PlatformWatchNamespaces(ns string) ([]string, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	deploys, err := client.AppsV1().Deployments(ns).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(deploys.Items) == 0 {
+		return nil, nil
+	}
+	watched := make(map[string]bool)
+	for _, deploy := range deploys.Items {
+		value := ""
+		for _, c := range deploy.Spec.Template.Spec.Containers {
+			for _, env := range c.Env {
+				if env.Name == DefaultWatchNamespaceEnv {
+					value = env.Value
+				}
+			}
+		}
+		if strings.TrimSpace(value) == "" {
+			return nil, nil
+		}
+		for _, w := range strings.Split(value, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				watched[w] = true
+			}
+		}
+	}
+	watched[ns] = true
+	return SortedKeys(watched), nil
+}
@@ -10,7 +10,11 @@ import (
 
 const (
 	prepareDesc    = `'prepare' command check and prepare tools for the nine`
-	prepareExample = ` kubectl nine prepare`
+	prepareExample = `1. Download the tools into ~/.nine/bin
+   $ kubectl nine prepare
+
+2. Import the tools from a local dir on the hosts without internet access
+   $ kubectl nine prepare --from-dir /opt/nine-tools`
 )
 
 type prepareCmd struct {
@@ -39,14 +43,17 @@ func newPrepareCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	cmd = DisableHelp(cmd)
 	f := cmd.Flags()
 	f.BoolVar(&DEBUG, "debug", false, "print debug infomation")
+	f.StringVar(&DefaultToolImportDir, "from-dir", "", "local dir holding the release artifacts of the tools")
 	return cmd
 }
 
 func (o *prepareCmd) run(_ []string) error {
 	if err := InitHelm(); err != nil {
+		fmt.Printf("Error: %v \n", err)
 		os.Exit(1)
 	}
 	if err := InitDirectPV(); err != nil {
+		fmt.Printf("Error: %v \n", err)
 		os.Exit(1)
 	}
 	fmt.Println("The Nine is OK!")
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+const (
+	DefaultProfileFileName = "profiles.yaml"
+)
+
+// ComponentProfile is the size of a component of the NineCluster
+type ComponentProfile struct {
+	Replicas int32  `json:"replicas,omitempty"`
+	CPU      string `json:"cpu,omitempty"`
+	Memory   string `json:"memory,omitempty"`
+	Storage  string `json:"storage,omitempty"`
+}
+
+// ClusterProfile is a named size of the NineCluster,the components are keyed by the cluster types,
+// e.g. kyuubi,metastore,database,minio,hdfs,zookeeper,doris-fe and doris-be.
+// The roles of the hdfs share the resources of the hdfs component
+type ClusterProfile struct {
+	DataVolume int                         `json:"dataVolume,omitempty"`
+	Components map[string]ComponentProfile `json:"components,omitempty"`
+}
+
+// ProfileFile is the file of the user defined profiles
+type ProfileFile struct {
+	Profiles map[string]ClusterProfile `json:"profiles"`
+}
+
+var BuiltinClusterProfiles = map[string]ClusterProfile{
+	"dev": {
+		DataVolume: 16,
+		Components: map[string]ComponentProfile{
+			"kyuubi":    {Replicas: 1, CPU: "500m", Memory: "1Gi"},
+			"metastore": {Replicas: 1, CPU: "500m", Memory: "1Gi"},
+			"database":  {Replicas: 1, CPU: "250m", Memory: "512Mi", Storage: "5Gi"},
+			"minio":     {CPU: "500m", Memory: "1Gi"},
+			"hdfs":      {Replicas: 1, CPU: "500m", Memory: "1Gi"},
+			"zookeeper": {Replicas: 1, CPU: "250m", Memory: "512Mi"},
+			"doris-fe":  {Replicas: 1, CPU: "1", Memory: "2Gi", Storage: "10Gi"},
+			"doris-be":  {Replicas: 1, CPU: "1", Memory: "4Gi", Storage: "20Gi"},
+		},
+	},
+	"small": {
+		DataVolume: 64,
+		Components: map[string]ComponentProfile{
+			"kyuubi":    {Replicas: 1, CPU: "1", Memory: "2Gi"},
+			"metastore": {Replicas: 1, CPU: "1", Memory: "2Gi"},
+			"database":  {Replicas: 1, CPU: "500m", Memory: "1Gi", Storage: "10Gi"},
+			"minio":     {CPU: "1", Memory: "4Gi"},
+			"hdfs":      {Replicas: 3, CPU: "1", Memory: "2Gi"},
+			"zookeeper": {Replicas: 3, CPU: "500m", Memory: "1Gi"},
+			"doris-fe":  {Replicas: 1, CPU: "2", Memory: "4Gi", Storage: "20Gi"},
+			"doris-be":  {Replicas: 3, CPU: "2", Memory: "8Gi", Storage: "100Gi"},
+		},
+	},
+	"medium": {
+		DataVolume: 256,
+		Components: map[string]ComponentProfile{
+			"kyuubi":    {Replicas: 2, CPU: "2", Memory: "4Gi"},
+			"metastore": {Replicas: 2, CPU: "1", Memory: "4Gi"},
+			"database":  {Replicas: 2, CPU: "1", Memory: "2Gi", Storage: "20Gi"},
+			"minio":     {CPU: "2", Memory: "8Gi"},
+			"hdfs":      {Replicas: 3, CPU: "2", Memory: "8Gi"},
+			"zookeeper": {Replicas: 3, CPU: "1", Memory: "2Gi"},
+			"doris-fe":  {Replicas: 3, CPU: "4", Memory: "8Gi", Storage: "50Gi"},
+			"doris-be":  {Replicas: 3, CPU: "8", Memory: "32Gi", Storage: "500Gi"},
+		},
+	},
+	"large": {
+		DataVolume: 1024,
+		Components: map[string]ComponentProfile{
+			"kyuubi":    {Replicas: 3, CPU: "4", Memory: "8Gi"},
+			"metastore": {Replicas: 3, CPU: "2", Memory: "8Gi"},
+			"database":  {Replicas: 3, CPU: "2", Memory: "8Gi", Storage: "50Gi"},
+			"minio":     {CPU: "4", Memory: "16Gi"},
+			"hdfs":      {Replicas: 6, CPU: "4", Memory: "16Gi"},
+			"zookeeper": {Replicas: 3, CPU: "2", Memory: "4Gi"},
+			"doris-fe":  {Replicas: 3, CPU: "8", Memory: "16Gi", Storage: "100Gi"},
+			"doris-be":  {Replicas: 6, CPU: "16", Memory: "64Gi", Storage: "2000Gi"},
+		},
+	},
+}
+
+var profilesSupported = "dev,small,medium,large"
+
+// DefaultProfileFile returns the path of the user defined profiles
+func DefaultProfileFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, DefaultNineHomeDir, DefaultProfileFileName)
+}
+
+// LoadClusterProfile returns the profile by the name,the user defined profiles in the file take precedence over the builtin ones
+func LoadClusterProfile(name string, file string) (*ClusterProfile, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil && !(os.IsNotExist(err) && file == DefaultProfileFile()) {
+			return nil, err
+		}
+		if err == nil {
+			profiles := &ProfileFile{}
+			if err := yaml.UnmarshalStrict(data, profiles); err != nil {
+				return nil, fmt.Errorf("invalid profile file %s,err:%v", file, err)
+			}
+			if p, ok := profiles.Profiles[name]; ok {
+				return &p, p.Validate(name)
+			}
+		}
+	}
+	if p, ok := BuiltinClusterProfiles[name]; ok {
+		return &p, nil
+	}
+	return nil, fmt.Errorf("profile %s not found,support [%s] and the profiles in %s", name, profilesSupported, file)
+}
+
+// Validate checks the components and the quantities of the profile
+func (p *ClusterProfile) Validate(name string) error {
+	if p.DataVolume < 0 {
+		return fmt.Errorf("invalid dataVolume %d of profile %s", p.DataVolume, name)
+	}
+	for component, cp := range p.Components {
+		if !ClusterComponentSupported(component) {
+			return fmt.Errorf("unsupported component %s of profile %s", component, name)
+		}
+		if cp.Replicas < 0 {
+			return fmt.Errorf("invalid replicas %d of %s in profile %s", cp.Replicas, component, name)
+		}
+		for _, q := range []string{cp.CPU, cp.Memory, cp.Storage} {
+			if q == "" {
+				continue
+			}
+			if _, err := resource.ParseQuantity(q); err != nil {
+				return fmt.Errorf("invalid quantity %s of %s in profile %s", q, component, name)
+			}
+		}
+	}
+	return nil
+}
+
+// ClusterComponentSupported returns true if the component could be customized by the profiles and the component specs
+func ClusterComponentSupported(component string) bool {
+	switch nineinfrav1alpha1.ClusterType(component) {
+	case nineinfrav1alpha1.KyuubiClusterType, nineinfrav1alpha1.MetaStoreClusterType, nineinfrav1alpha1.DatabaseClusterType,
+		nineinfrav1alpha1.MinioClusterType, nineinfrav1alpha1.HdfsClusterType, nineinfrav1alpha1.ZookeeperClusterType,
+		nineinfrav1alpha1.DorisFEClusterType, nineinfrav1alpha1.DorisBEClusterType:
+		return true
+	}
+	return false
+}
+
+// clusterComponentEnabled returns true if the component is a part of the NineCluster with the features
+func clusterComponentEnabled(component string, features map[string]string) bool {
+	switch nineinfrav1alpha1.ClusterType(component) {
+	case nineinfrav1alpha1.DorisFEClusterType, nineinfrav1alpha1.DorisBEClusterType:
+		_, ok := features[FeaturesOlapKey]
+		return ok
+	case nineinfrav1alpha1.MinioClusterType:
+		return !strings.EqualFold(features[FeaturesStorageKey], FeaturesStorageValueHdfs)
+	case nineinfrav1alpha1.HdfsClusterType, nineinfrav1alpha1.ZookeeperClusterType:
+		return strings.EqualFold(features[FeaturesStorageKey], FeaturesStorageValueHdfs)
+	}
+	return true
+}
+
+// StorageGi returns the storage of the component in Gi,0 if not specified
+func (cp ComponentProfile) StorageGi() int {
+	if cp.Storage == "" {
+		return 0
+	}
+	q := resource.MustParse(cp.Storage)
+	return int(q.Value() / GiMultiplier)
+}
+
+// Apply sizes the components of the cluster set enabled by the features
+func (p *ClusterProfile) Apply(clusterSet []nineinfrav1alpha1.ClusterInfo, features map[string]string) []nineinfrav1alpha1.ClusterInfo {
+	for _, component := range SortedKeys(p.Components) {
+		if !clusterComponentEnabled(component, features) {
+			continue
+		}
+		cp := p.Components[component]
+		var i int
+		clusterSet, i = ensureClusterInfo(clusterSet, features, nineinfrav1alpha1.ClusterType(component))
+		res := &clusterSet[i].Resource
+		if cp.Replicas > 0 {
+			res.Replicas = cp.Replicas
+		}
+		if res.ResourceRequirements.Requests == nil {
+			res.ResourceRequirements.Requests = corev1.ResourceList{}
+		}
+		if res.ResourceRequirements.Limits == nil {
+			res.ResourceRequirements.Limits = corev1.ResourceList{}
+		}
+		if cp.CPU != "" {
+			res.ResourceRequirements.Requests[corev1.ResourceCPU] = resource.MustParse(cp.CPU)
+			res.ResourceRequirements.Limits[corev1.ResourceCPU] = resource.MustParse(cp.CPU)
+		}
+		if cp.Memory != "" {
+			res.ResourceRequirements.Requests[corev1.ResourceMemory] = resource.MustParse(cp.Memory)
+			res.ResourceRequirements.Limits[corev1.ResourceMemory] = resource.MustParse(cp.Memory)
+		}
+		if cp.Storage != "" {
+			res.ResourceRequirements.Requests[corev1.ResourceStorage] = resource.MustParse(cp.Storage)
+		}
+	}
+	return clusterSet
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type ChartState string
+
+const (
+	ChartStatePending      ChartState = "pending"
+	ChartStateInstalling   ChartState = "installing"
+	ChartStateWaitingReady ChartState = "waiting-ready"
+	ChartStateDone         ChartState = "done"
+	ChartStateFailed       ChartState = "failed"
+	ChartStateSkipped      ChartState = "skipped"
+)
+
+const progressRefreshInterval = 500 * time.Millisecond
+
+// ChartProgress is the progress of a chart installation
+type ChartProgress struct {
+	Name  string
+	State ChartState
+	Start time.Time
+	End   time.Time
+	Err   error
+}
+
+func (c *ChartProgress) finished() bool {
+	return c.State == ChartStateDone || c.State == ChartStateFailed || c.State == ChartStateSkipped
+}
+
+func (c *ChartProgress) elapsed() time.Duration {
+	if c.Start.IsZero() {
+		return 0
+	}
+	if c.finished() {
+		return c.End.Sub(c.Start).Round(time.Second)
+	}
+	return time.Since(c.Start).Round(time.Second)
+}
+
+// ProgressView shows the progress of the charts,it refreshes the lines in place on a terminal
+// and falls back to plain logs otherwise
+type ProgressView struct {
+	mu     sync.Mutex
+	out    io.Writer
+	live   bool
+	charts []*ChartProgress
+	index  map[string]*ChartProgress
+	lines  int
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewProgressView returns a progress view of the charts,the live view is used only when out is a terminal
+func NewProgressView(out io.Writer, charts []string) *ProgressView {
+	p := &ProgressView{
+		out:   out,
+		index: make(map[string]*ChartProgress),
+	}
+	if f, ok := out.(*os.File); ok && !DEBUG {
+		p.live = term.IsTerminal(int(f.Fd()))
+	}
+	for _, c := range charts {
+		cp := &ChartProgress{Name: c, State: ChartStatePending}
+		p.charts = append(p.charts, cp)
+		p.index[c] = cp
+	}
+	return p
+}
+
+// Start starts refreshing the elapsed time of the live view
+func (p *ProgressView) Start() {
+	if !p.live {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	p.render()
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(progressRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.render()
+			case <-p.stop:
+				p.render()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the live view
+func (p *ProgressView) Stop() {
+	if !p.live || p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// Set changes the state of the chart
+func (p *ProgressView) Set(chart string, state ChartState, err error) {
+	p.mu.Lock()
+	cp, ok := p.index[chart]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if cp.Start.IsZero() && state != ChartStatePending {
+		cp.Start = now
+	}
+	cp.State = state
+	cp.Err = err
+	if cp.finished() {
+		cp.End = now
+	}
+	p.mu.Unlock()
+
+	if p.live {
+		p.render()
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(p.out, "%s %s: %s,err:%v\n", now.Format(time.TimeOnly), chart, state, err)
+	} else {
+		fmt.Fprintf(p.out, "%s %s: %s\n", now.Format(time.TimeOnly), chart, state)
+	}
+}
+
+func (p *ProgressView) render() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lines > 0 {
+		fmt.Fprintf(p.out, "\033[%dA", p.lines)
+	}
+	for _, cp := range p.charts {
+		elapsed := ""
+		if !cp.Start.IsZero() {
+			elapsed = cp.elapsed().String()
+		}
+		fmt.Fprintf(p.out, "\033[2K"+PrintFmtStrChartProgress, cp.Name, cp.State, elapsed)
+	}
+	p.lines = len(p.charts)
+}
+
+// PrintSummary prints the summary table of the charts
+func (p *ProgressView) PrintSummary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	table := tablewriter.NewWriter(p.out)
+	table.SetHeader([]string{"CHART", "STATE", "ELAPSED", "ERROR"})
+	for _, cp := range p.charts {
+		errMsg := ""
+		if cp.Err != nil {
+			errMsg = cp.Err.Error()
+		}
+		table.Append([]string{cp.Name, string(cp.State), cp.elapsed().String(), errMsg})
+	}
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+}
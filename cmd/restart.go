@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	cnpgv1 "github.com/cloudnative-pg/cloudnative-pg/api/v1"
+	"github.com/spf13/cobra"
+	"io"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	restartDesc = `
+'restart' command does a rolling restart of the components of a NineCluster,one component after another in the
+dependency order.The pods of a component are restarted one by one and each is waited to be ready before the next,
+so the NameNodes and the ZooKeeper quorum are never restarted at once.`
+	restartExample = `1. Restart the kyuubi and the metastore
+   $ kubectl nine restart c1 --component kyuubi,metastore -n c1-ns
+
+2. Restart all the components
+   $ kubectl nine restart c1 --component all -n c1-ns
+
+3. Show the workloads will be restarted
+   $ kubectl nine restart c1 --component hdfs -n c1-ns --dry-run`
+)
+
+const (
+	RestartedAtAnnoKey    = "kubectl.kubernetes.io/restartedAt"
+	DefaultRestartTimeout = 10 * time.Minute
+	RestartComponentAll   = "all"
+)
+
+// NineClusterRestartOrder are the projects could be restarted,in the dependency order
+var NineClusterRestartOrder = []string{
+	"postgresql",
+	"zookeeper",
+	"journalnode",
+	"namenode",
+	"datanode",
+	"minio",
+	"metastore",
+	"kyuubi",
+	"doris-fe",
+	"doris-be",
+}
+
+// restartComponentAliases are the components consisting of several projects
+var restartComponentAliases = map[string][]string{
+	"hdfs":  {"journalnode", "namenode", "datanode"},
+	"doris": {"doris-fe", "doris-be"},
+}
+
+var restartComponentsSupported = "all,postgresql,zookeeper,hdfs,journalnode,namenode,datanode,minio,metastore,kyuubi,doris,doris-fe,doris-be"
+
+type restartCmd struct {
+	out        io.Writer
+	errOut     io.Writer
+	name       string
+	ns         string
+	components []string
+	dryRun     bool
+	yes        bool
+	timeout    time.Duration
+}
+
+func newClusterRestartCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &restartCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "restart <NINECLUSTERNAME>",
+		Short:   "Rolling restart the components of a NineCluster",
+		Long:    restartDesc,
+		Example: restartExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return c.validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace scope for this request")
+	f.StringSliceVar(&c.components, "component", nil, fmt.Sprintf("components to restart,support [%s]", restartComponentsSupported))
+	f.BoolVar(&c.dryRun, "dry-run", false, "only print the workloads will be restarted")
+	f.BoolVarP(&c.yes, "yes", "y", false, "skip the confirmation prompt")
+	f.DurationVar(&c.timeout, "timeout", DefaultRestartTimeout, "time to wait for each component to be restarted")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	cmd.MarkFlagRequired("component")
+	return cmd
+}
+
+func (c *restartCmd) validate(args []string) error {
+	if err := ValidateClusterArgs("restart", args); err != nil {
+		return err
+	}
+	c.name = args[0]
+	for _, component := range c.components {
+		if !strings.Contains(","+restartComponentsSupported+",", ","+component+",") {
+			return fmt.Errorf("unsupported component %s,support [%s]", component, restartComponentsSupported)
+		}
+	}
+	return nil
+}
+
+// restartProjects returns the projects of the components in the restart order
+func restartProjects(components []string) []string {
+	selected := make(map[string]bool)
+	for _, component := range components {
+		switch {
+		case component == RestartComponentAll:
+			for _, project := range NineClusterRestartOrder {
+				selected[project] = true
+			}
+		case restartComponentAliases[component] != nil:
+			for _, project := range restartComponentAliases[component] {
+				selected[project] = true
+			}
+		default:
+			selected[component] = true
+		}
+	}
+	projects := make([]string, 0, len(selected))
+	for _, project := range NineClusterRestartOrder {
+		if selected[project] {
+			projects = append(projects, project)
+		}
+	}
+	return projects
+}
+
+// podOrdinal returns the ordinal of the pod of the statefulset,-1 if the pod is not named by the statefulset
+func podOrdinal(stsName string, podName string) int {
+	suffix, found := strings.CutPrefix(podName, stsName+"-")
+	if !found {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(suffix)
+	if err != nil || ordinal < 0 {
+		return -1
+	}
+	return ordinal
+}
+
+// restartPodsOneByOne deletes the pods of the statefulset with the OnDelete strategy one by one,
+// waiting for each to be recreated and ready
+func restartPodsOneByOne(sts *appsv1.StatefulSet, timeout time.Duration) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	pods, err := client.CoreV1().Pods(sts.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	// the highest ordinal first as the statefulset controller does
+	sort.Slice(pods.Items, func(i, j int) bool {
+		oi, oj := podOrdinal(sts.Name, pods.Items[i].Name), podOrdinal(sts.Name, pods.Items[j].Name)
+		if oi == oj {
+			return pods.Items[i].Name > pods.Items[j].Name
+		}
+		return oi > oj
+	})
+	for _, pod := range pods.Items {
+		if err := client.CoreV1().Pods(sts.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+		deadline := time.Now().Add(timeout)
+		for {
+			p, err := client.CoreV1().Pods(sts.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return err
+			}
+			if err == nil && p.UID != pod.UID && podReady(p) {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("pod %s in namespace %s is not ready in %s", pod.Name, sts.Namespace, timeout)
+			}
+			time.Sleep(3 * time.Second)
+		}
+		fmt.Printf("Pod %s is restarted\n", pod.Name)
+	}
+	return nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// restartPG triggers a rolling restart of the postgresql by the cnpg,the replicas go first and then the primary
+func (c *restartCmd) restartPG(name string, restartedAt string) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetPGOperatorClient(path)
+	if err != nil {
+		return err
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{"%s":"%s"}}}`, RestartedAtAnnoKey, restartedAt)
+	_, err = client.PostgresqlV1().Clusters(c.ns).Patch(context.TODO(), name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	begin := time.Now()
+	deadline := begin.Add(c.timeout)
+	started := false
+	for {
+		pg, err := client.PostgresqlV1().Clusters(c.ns).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		healthy := pg.Status.Phase == cnpgv1.PhaseHealthy && IfPGReady(pg)
+		started = started || !healthy
+		// the cnpg may finish a quick restart between two polls
+		if healthy && (started || time.Since(begin) > 30*time.Second) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgresql %s in namespace %s is not restarted in %s", name, c.ns, c.timeout)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// restartWorkload restarts the statefulset or the deployment of the project
+func (c *restartCmd) restartWorkload(name string, restartedAt string) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"%s":"%s"}}}}}`, RestartedAtAnnoKey, restartedAt))
+	sts, err := client.AppsV1().StatefulSets(c.ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err == nil {
+		if sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+			return restartPodsOneByOne(sts, c.timeout)
+		}
+		// the rolling update restarts one pod at a time and waits for it to be ready
+		if _, err := client.AppsV1().StatefulSets(c.ns).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return err
+		}
+		return WaitStsReady(name, c.ns, *sts.Spec.Replicas, c.timeout)
+	}
+	if !k8serrors.IsNotFound(err) {
+		return err
+	}
+	deploy, err := client.AppsV1().Deployments(c.ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if _, err := client.AppsV1().Deployments(c.ns).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	return WaitDeployReady(name, c.ns, *deploy.Spec.Replicas, c.timeout)
+}
+
+func (c *restartCmd) run() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	nc, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	cluster, err := nc.NineinfraV1alpha1().NineClusters(c.ns).Get(context.TODO(), c.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if IsNineClusterPaused(cluster) {
+		return errors.New("NineCluster:" + c.name + " in namespace:" + c.ns + " is paused,resume it first")
+	}
+
+	workloads, err := NineClusterWorkloads(c.name, c.ns)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, w := range workloads {
+		existing[w.Name] = true
+	}
+	var projects []string
+	for _, project := range restartProjects(c.components) {
+		if existing[NineWorkLoadName(c.name, project)] {
+			projects = append(projects, project)
+		} else if DEBUG {
+			fmt.Printf("Skip %s,it is not a part of the NineCluster\n", project)
+		}
+	}
+	if len(projects) == 0 {
+		return fmt.Errorf("none of the components %s found in NineCluster:%s", strings.Join(c.components, ","), c.name)
+	}
+	fmt.Println("The following workloads will be restarted in order:")
+	for _, project := range projects {
+		fmt.Printf("  %s(%s)\n", NineWorkLoadName(c.name, project), project)
+	}
+	if c.dryRun {
+		return nil
+	}
+	if !c.yes && !Ask("The workloads above will be restarted, are you sure you want to continue") {
+		return errors.New("aborting NineCluster restart")
+	}
+
+	restartedAt := time.Now().Format(time.RFC3339)
+	for _, project := range projects {
+		name := NineWorkLoadName(c.name, project)
+		fmt.Printf("Restarting %s\n", name)
+		if project == "postgresql" {
+			err = c.restartPG(name, restartedAt)
+		} else {
+			err = c.restartWorkload(name, restartedAt)
+		}
+		if err != nil {
+			return fmt.Errorf("restart %s failed,the following components are not restarted,err:%v", name, err)
+		}
+		fmt.Printf("Workload %s is restarted\n", name)
+	}
+	fmt.Println("NineCluster:" + c.name + " in namespace:" + c.ns + " is restarted successfully!")
+	return nil
+}
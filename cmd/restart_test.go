@@ -0,0 +1,24 @@
+package cmd
+
+import "testing"
+
+func TestPodOrdinal(t *testing.T) {
+	tests := []struct {
+		pod  string
+		want int
+	}{
+		{"c1-nine-doris-be-0", 0},
+		{"c1-nine-doris-be-9", 9},
+		{"c1-nine-doris-be-10", 10},
+		{"c1-nine-doris-be-x", -1},
+		{"c1-nine-doris-be--1", -1},
+		{"c1-nine-doris-be-1-0", -1},
+		{"c1-nine-doris-fe-0", -1},
+		{"c1-nine-doris-be", -1},
+	}
+	for _, tt := range tests {
+		if got := podOrdinal("c1-nine-doris-be", tt.pod); got != tt.want {
+			t.Errorf("podOrdinal(%q) = %d, want %d", tt.pod, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"strconv"
+)
+
+const (
+	rollbackDesc = `
+'rollback' command re-applies the spec of a revision recorded in the history of a NineCluster after showing the
+diff.The changes the operator can not handle in place,such as the storage pools and the volumes of the deployed
+components,are refused.`
+	rollbackExample = `1. Roll back a NineCluster to the previous revision
+   $ kubectl nine rollback c1 -n c1-ns
+
+2. Roll back a NineCluster to the revision 3
+   $ kubectl nine rollback c1 --to-revision 3 -n c1-ns
+
+3. Show the diff without rolling back
+   $ kubectl nine rollback c1 --to-revision 3 -n c1-ns --dry-run`
+)
+
+type rollbackCmd struct {
+	out        io.Writer
+	errOut     io.Writer
+	name       string
+	ns         string
+	toRevision int
+	dryRun     bool
+	yes        bool
+}
+
+func newClusterRollbackCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &rollbackCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "rollback <NINECLUSTERNAME>",
+		Short:   "Roll back a NineCluster to a revision of its spec",
+		Long:    rollbackDesc,
+		Example: rollbackExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := ValidateClusterArgs("rollback", args); err != nil {
+				return err
+			}
+			c.name = args[0]
+			if c.toRevision < 0 {
+				return errors.New("--to-revision should not be negative")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace scope for this request")
+	f.IntVar(&c.toRevision, "to-revision", 0, "the revision to roll back to,defaults to the previous one")
+	f.BoolVar(&c.dryRun, "dry-run", false, "only print the diff of the NineCluster")
+	f.BoolVarP(&c.yes, "yes", "y", false, "skip the confirmation prompt")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	return cmd
+}
+
+// CheckInPlaceChange checks the operator could change the NineCluster into the spec in place
+func CheckInPlaceChange(nc *nineinfrav1alpha1.NineCluster, spec *nineinfrav1alpha1.NineClusterSpec) error {
+	desired := &nineinfrav1alpha1.NineCluster{ObjectMeta: nc.ObjectMeta, Spec: *spec}
+	currentStorage, desiredStorage := nc.Spec.Features[FeaturesStorageKey], spec.Features[FeaturesStorageKey]
+	if currentStorage != desiredStorage {
+		return fmt.Errorf("switching the main storage from %q to %q is not supported in place", currentStorage, desiredStorage)
+	}
+	for _, ci := range spec.ClusterSet {
+		sc, deployed := deployedStorageClass(nc, ci.Type)
+		if !deployed {
+			continue
+		}
+		if desiredSC := clusterStorageClass(desired, ci.Type); desiredSC != sc {
+			return fmt.Errorf("changing the storage pool of %s from %s to %s is not supported in place", ci.Type, sc, desiredSC)
+		}
+		i := FindClusterInfo(nc.Spec.ClusterSet, ci.Type)
+		if i < 0 || ci.Type == nineinfrav1alpha1.MinioClusterType {
+			continue
+		}
+		current, ok := nc.Spec.ClusterSet[i].Resource.ResourceRequirements.Requests["storage"]
+		wanted, wantedOk := ci.Resource.ResourceRequirements.Requests["storage"]
+		if ok && wantedOk && wanted.Cmp(current) < 0 {
+			return fmt.Errorf("shrinking the volume of %s from %s to %s is not supported", ci.Type, current.String(), wanted.String())
+		}
+	}
+	if spec.DataVolume < nc.Spec.DataVolume {
+		return fmt.Errorf("shrinking the data volume from %dGi to %dGi is not supported", nc.Spec.DataVolume, spec.DataVolume)
+	}
+	return nil
+}
+
+func (c *rollbackCmd) run() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	nc, err := client.NineinfraV1alpha1().NineClusters(c.ns).Get(context.TODO(), c.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	revisions, err := LoadRevisions(c.name, c.ns)
+	if err != nil {
+		return err
+	}
+	if c.toRevision == 0 {
+		if len(revisions) < 2 {
+			return errors.New("NineCluster:" + c.name + " in namespace:" + c.ns + " has no previous revision")
+		}
+		c.toRevision = revisions[len(revisions)-2].Revision
+	}
+	target, err := FindRevision(c.name, c.ns, c.toRevision)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("NineCluster:" + c.name + " in namespace:" + c.ns + " will be rolled back to revision " + strconv.Itoa(target.Revision) + ":")
+	changed, err := PrintSpecDiff(&nc.Spec, &target.Spec)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Println("NineCluster:" + c.name + " in namespace:" + c.ns + " is already at revision " + strconv.Itoa(target.Revision))
+		return nil
+	}
+	if err := CheckInPlaceChange(nc, &target.Spec); err != nil {
+		return err
+	}
+	if c.dryRun {
+		return nil
+	}
+	if !c.yes && !Ask("The NineCluster will be rolled back as above, are you sure you want to continue") {
+		return errors.New("aborting NineCluster rollback")
+	}
+
+	previous := nc.Spec.DeepCopy()
+	nc.Spec = *target.Spec.DeepCopy()
+	result, err := client.NineinfraV1alpha1().NineClusters(c.ns).Update(context.TODO(), nc, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	RecordRevision(result, previous, "rollback")
+	fmt.Println("NineCluster:" + c.name + " in namespace:" + c.ns + " is rolled back to revision " + strconv.Itoa(target.Revision) + " successfully!")
+	fmt.Println("It may take a few minutes for the changes to be ready")
+	fmt.Println("You can check its status using the following command：")
+	fmt.Println("kubectl nine show " + c.name + " -n " + c.ns)
+	return nil
+}
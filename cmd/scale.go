@@ -0,0 +1,440 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"strconv"
+	"time"
+)
+
+const (
+	scaleDesc = `
+'scale' command resizes the components of a NineCluster.`
+	scaleExample = `1. Grow the data volume of a NineCluster
+   $ kubectl nine scale c1 --data-volume 64 -n c1-ns
+
+2. Scale the olap executors and the kyuubi replicas
+   $ kubectl nine scale c1 --olap-executors 5 --kyuubi-replicas 3 -n c1-ns
+
+3. Show the plan without scaling
+   $ kubectl nine scale c1 --hdfs-datanodes 6 -n c1-ns --dry-run`
+
+	PrintFmtStrScalePlan = "%-12s\t%-16s\t%-10s\t%-10s\n"
+
+	DefaultScaleTimeout = 10 * time.Minute
+)
+
+type ScaleOptions struct {
+	Name           string
+	NS             string
+	DataVolume     int
+	OlapExecutors  int32
+	KyuubiReplicas int32
+	HdfsDataNodes  int32
+	yes            bool
+	dryRun         bool
+	force          bool
+	timeout        time.Duration
+}
+
+// ScaleChange is a change of a component of the NineCluster
+type ScaleChange struct {
+	Component string
+	Field     string
+	Before    string
+	After     string
+	// Workload is the project of the statefulset rolled by the change
+	Workload string
+	// Replicas are the desired replicas of the workload,-1 means not changed
+	Replicas int32
+	// VolumeGrown is true if the volumes of the workload are grown
+	VolumeGrown bool
+}
+
+type scaleCmd struct {
+	out       io.Writer
+	errOut    io.Writer
+	scaleOpts ScaleOptions
+}
+
+func newClusterScaleCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &scaleCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "scale <NINECLUSTERNAME>",
+		Short:   "Scale the components of a NineCluster",
+		Long:    scaleDesc,
+		Example: scaleExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return c.validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.scaleOpts.NS, "namespace", "n", "", "namespace scope for this request")
+	f.IntVarP(&c.scaleOpts.DataVolume, "data-volume", "v", 0, "total raw data volumes of the ninecluster,the unit is Gi, e.g. 64")
+	f.Int32VarP(&c.scaleOpts.OlapExecutors, "olap-executors", "r", 0, "num of the olap executors")
+	f.Int32Var(&c.scaleOpts.KyuubiReplicas, "kyuubi-replicas", 0, "num of the kyuubi replicas")
+	f.Int32Var(&c.scaleOpts.HdfsDataNodes, "hdfs-datanodes", 0, "num of the hdfs datanodes")
+	f.BoolVar(&c.scaleOpts.dryRun, "dry-run", false, "only print the plan of the scaling")
+	f.BoolVarP(&c.scaleOpts.yes, "yes", "y", false, "skip the confirmation prompt")
+	f.BoolVar(&c.scaleOpts.force, "force", false, "only warn if the storage pools could not fit the scaled components")
+	f.DurationVar(&c.scaleOpts.timeout, "timeout", DefaultScaleTimeout, "time to wait for the affected workloads to roll")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	return cmd
+}
+
+func (c *scaleCmd) validate(args []string) error {
+	if err := ValidateClusterArgs("scale", args); err != nil {
+		return err
+	}
+	c.scaleOpts.Name = args[0]
+	if c.scaleOpts.DataVolume < 0 || c.scaleOpts.OlapExecutors < 0 || c.scaleOpts.KyuubiReplicas < 0 || c.scaleOpts.HdfsDataNodes < 0 {
+		return errors.New("the sizes to scale should not be negative")
+	}
+	if c.scaleOpts.DataVolume == 0 && c.scaleOpts.OlapExecutors == 0 && c.scaleOpts.KyuubiReplicas == 0 && c.scaleOpts.HdfsDataNodes == 0 {
+		return errors.New("at least one of --data-volume,--olap-executors,--kyuubi-replicas and --hdfs-datanodes is required")
+	}
+	return nil
+}
+
+// FindClusterInfo returns the index of the cluster type in the cluster set,-1 if not found
+func FindClusterInfo(clusterSet []nineinfrav1alpha1.ClusterInfo, clusterType nineinfrav1alpha1.ClusterType) int {
+	for i, ci := range clusterSet {
+		if ci.Type == clusterType {
+			return i
+		}
+	}
+	return -1
+}
+
+// EnsureClusterInfo returns the index of the cluster type in the cluster set of the NineCluster,
+// the default cluster info of the type is added if not found
+func EnsureClusterInfo(nc *nineinfrav1alpha1.NineCluster, clusterType nineinfrav1alpha1.ClusterType) int {
+	var i int
+	nc.Spec.ClusterSet, i = ensureClusterInfo(nc.Spec.ClusterSet, nc.Spec.Features, clusterType)
+	return i
+}
+
+func ensureClusterInfo(clusterSet []nineinfrav1alpha1.ClusterInfo, features map[string]string, clusterType nineinfrav1alpha1.ClusterType) ([]nineinfrav1alpha1.ClusterInfo, int) {
+	if i := FindClusterInfo(clusterSet, clusterType); i >= 0 {
+		return clusterSet, i
+	}
+	ci := nineinfrav1alpha1.ClusterInfo{Type: clusterType}
+	defaults := nineinfrav1alpha1.NineDatahouseClusterset
+	if _, ok := features[FeaturesOlapKey]; ok {
+		defaults = nineinfrav1alpha1.NineDatahouseWithOLAPClusterset
+	}
+	if i := FindClusterInfo(defaults, clusterType); i >= 0 {
+		defaults[i].DeepCopyInto(&ci)
+	}
+	clusterSet = append(clusterSet, ci)
+	return clusterSet, len(clusterSet) - 1
+}
+
+// currentReplicas returns the replicas of the cluster type in the cluster set,
+// or the replicas of the workload if not specified
+func currentReplicas(nc *nineinfrav1alpha1.NineCluster, clusterType nineinfrav1alpha1.ClusterType, project string) string {
+	if i := FindClusterInfo(nc.Spec.ClusterSet, clusterType); i >= 0 && nc.Spec.ClusterSet[i].Resource.Replicas > 0 {
+		return strconv.Itoa(int(nc.Spec.ClusterSet[i].Resource.Replicas))
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return "-"
+	}
+	sts, err := client.AppsV1().StatefulSets(nc.Namespace).Get(context.TODO(), NineWorkLoadName(nc.Name, project), metav1.GetOptions{})
+	if err != nil {
+		return "-"
+	}
+	return strconv.Itoa(int(*sts.Spec.Replicas))
+}
+
+// clusterStorageClass returns the storage class of the cluster type in the cluster set
+func clusterStorageClass(nc *nineinfrav1alpha1.NineCluster, clusterType nineinfrav1alpha1.ClusterType) string {
+	if i := FindClusterInfo(nc.Spec.ClusterSet, clusterType); i >= 0 && nc.Spec.ClusterSet[i].Resource.StorageClass != "" {
+		return nc.Spec.ClusterSet[i].Resource.StorageClass
+	}
+	return DefaultStorageClass
+}
+
+// plan applies the options to the NineCluster and returns the changes and the storage required
+func (c *scaleCmd) plan(nc *nineinfrav1alpha1.NineCluster) ([]ScaleChange, []StorageDemand, error) {
+	changes := make([]ScaleChange, 0)
+	var demands []StorageDemand
+	storageType := FeaturesStorageValueMinio
+	if v, ok := nc.Spec.Features[FeaturesStorageKey]; ok {
+		storageType = v
+	}
+
+	if c.scaleOpts.DataVolume != 0 && c.scaleOpts.DataVolume != nc.Spec.DataVolume {
+		if c.scaleOpts.DataVolume < nc.Spec.DataVolume {
+			return nil, nil, fmt.Errorf("the data volume can not be shrunk from %dGi to %dGi", nc.Spec.DataVolume, c.scaleOpts.DataVolume)
+		}
+		workload := "minio"
+		if storageType == FeaturesStorageValueHdfs {
+			workload = "datanode"
+		}
+		previous := nc.Spec.DataVolume
+		// the growth of the data volume is spread over the servers of the main storage as the creation does
+		grown := nc.DeepCopy()
+		grown.Spec.DataVolume = c.scaleOpts.DataVolume - nc.Spec.DataVolume
+		for _, d := range NineClusterStorageDemands(grown) {
+			if d.Component == workload {
+				demands = append(demands, d)
+			}
+		}
+		nc.Spec.DataVolume = c.scaleOpts.DataVolume
+		change := ScaleChange{
+			Component:   "ninecluster",
+			Field:       "dataVolume",
+			Before:      fmt.Sprintf("%dGi", previous),
+			After:       fmt.Sprintf("%dGi", c.scaleOpts.DataVolume),
+			Workload:    workload,
+			Replicas:    -1,
+			VolumeGrown: true,
+		}
+		changes = append(changes, change)
+	}
+
+	if c.scaleOpts.OlapExecutors != 0 {
+		if _, ok := nc.Spec.Features[FeaturesOlapKey]; !ok {
+			return nil, nil, fmt.Errorf("NineCluster %s has no olap,--olap-executors is not supported", nc.Name)
+		}
+		before := currentReplicas(nc, nineinfrav1alpha1.DorisBEClusterType, "doris-be")
+		if before != strconv.Itoa(int(c.scaleOpts.OlapExecutors)) {
+			i := EnsureClusterInfo(nc, nineinfrav1alpha1.DorisBEClusterType)
+			if n, err := strconv.Atoi(before); err == nil && int32(n) < c.scaleOpts.OlapExecutors {
+				volume := int64(DefaultDorisBEStoragePVSize * GiMultiplier)
+				if q, ok := nc.Spec.ClusterSet[i].Resource.ResourceRequirements.Requests["storage"]; ok {
+					volume = q.Value()
+				}
+				demands = append(demands, StorageDemand{
+					Component:    string(nineinfrav1alpha1.DorisBEClusterType),
+					StorageClass: clusterStorageClass(nc, nineinfrav1alpha1.DorisBEClusterType),
+					Replicas:     c.scaleOpts.OlapExecutors - int32(n),
+					Size:         volume,
+				})
+			}
+			changes = append(changes, ScaleChange{
+				Component: string(nineinfrav1alpha1.DorisBEClusterType),
+				Field:     "replicas",
+				Before:    before,
+				After:     strconv.Itoa(int(c.scaleOpts.OlapExecutors)),
+				Workload:  "doris-be",
+				Replicas:  c.scaleOpts.OlapExecutors,
+			})
+			nc.Spec.ClusterSet[i].Resource.Replicas = c.scaleOpts.OlapExecutors
+		}
+	}
+
+	if c.scaleOpts.KyuubiReplicas != 0 {
+		before := currentReplicas(nc, nineinfrav1alpha1.KyuubiClusterType, "kyuubi")
+		if before != strconv.Itoa(int(c.scaleOpts.KyuubiReplicas)) {
+			i := EnsureClusterInfo(nc, nineinfrav1alpha1.KyuubiClusterType)
+			changes = append(changes, ScaleChange{
+				Component: string(nineinfrav1alpha1.KyuubiClusterType),
+				Field:     "replicas",
+				Before:    before,
+				After:     strconv.Itoa(int(c.scaleOpts.KyuubiReplicas)),
+				Workload:  "kyuubi",
+				Replicas:  c.scaleOpts.KyuubiReplicas,
+			})
+			nc.Spec.ClusterSet[i].Resource.Replicas = c.scaleOpts.KyuubiReplicas
+		}
+	}
+
+	if c.scaleOpts.HdfsDataNodes != 0 {
+		if storageType != FeaturesStorageValueHdfs {
+			return nil, nil, fmt.Errorf("the main storage of NineCluster %s is %s,--hdfs-datanodes is not supported", nc.Name, storageType)
+		}
+		before := currentReplicas(nc, nineinfrav1alpha1.HdfsClusterType, "datanode")
+		if before != strconv.Itoa(int(c.scaleOpts.HdfsDataNodes)) {
+			i := EnsureClusterInfo(nc, nineinfrav1alpha1.HdfsClusterType)
+			changes = append(changes, ScaleChange{
+				Component: string(nineinfrav1alpha1.HdfsClusterType),
+				Field:     "datanodes",
+				Before:    before,
+				After:     strconv.Itoa(int(c.scaleOpts.HdfsDataNodes)),
+				Workload:  "datanode",
+				Replicas:  c.scaleOpts.HdfsDataNodes,
+			})
+			nc.Spec.ClusterSet[i].Resource.Replicas = c.scaleOpts.HdfsDataNodes
+			if n, err := strconv.Atoi(before); err == nil && int32(n) < c.scaleOpts.HdfsDataNodes {
+				for _, d := range NineClusterStorageDemands(nc) {
+					if d.Component == "datanode" {
+						d.Replicas = c.scaleOpts.HdfsDataNodes - int32(n)
+						demands = append(demands, d)
+					}
+				}
+			}
+		}
+	}
+	return changes, demands, nil
+}
+
+// stsVolumeSize returns the smallest storage request of the volume claim templates of the statefulset or of the
+// claims of its replicas,whichever is larger,the operator may resize either of them
+func stsVolumeSize(sts *appsv1.StatefulSet, pvcs []corev1.PersistentVolumeClaim) int64 {
+	if len(sts.Spec.VolumeClaimTemplates) == 0 {
+		return 0
+	}
+	templateSize := int64(-1)
+	for _, tmpl := range sts.Spec.VolumeClaimTemplates {
+		q := tmpl.Spec.Resources.Requests[corev1.ResourceStorage]
+		if templateSize < 0 || q.Value() < templateSize {
+			templateSize = q.Value()
+		}
+	}
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	requests := make(map[string]int64)
+	for _, pvc := range pvcs {
+		q := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		requests[pvc.Name] = q.Value()
+	}
+	claimSize := int64(-1)
+	for _, tmpl := range sts.Spec.VolumeClaimTemplates {
+		for i := int32(0); i < replicas; i++ {
+			size := requests[fmt.Sprintf("%s-%s-%d", tmpl.Name, sts.Name, i)]
+			if claimSize < 0 || size < claimSize {
+				claimSize = size
+			}
+		}
+	}
+	if claimSize > templateSize {
+		return claimSize
+	}
+	return templateSize
+}
+
+// StsVolumeSize returns the volume size of the statefulset,0 if it is not found
+func StsVolumeSize(name string, namespace string) (int64, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return 0, err
+	}
+	sts, err := client.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	pvcs, err := client.CoreV1().PersistentVolumeClaims(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return stsVolumeSize(sts, pvcs.Items), nil
+}
+
+// WaitStsVolumeGrown waits for the operator to grow the volumes of the statefulset beyond the size captured before
+func WaitStsVolumeGrown(name string, namespace string, before int64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		size, err := StsVolumeSize(name, namespace)
+		if err != nil {
+			return err
+		}
+		if size > before {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the volumes of statefulset %s in namespace %s are not grown from %s in %s", name, namespace,
+				resource.NewQuantity(before, resource.BinarySI).String(), timeout)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+func printScalePlan(changes []ScaleChange) {
+	fmt.Printf(PrintFmtStrScalePlan, "COMPONENT", "FIELD", "BEFORE", "AFTER")
+	for _, change := range changes {
+		fmt.Printf(PrintFmtStrScalePlan, change.Component, change.Field, change.Before, change.After)
+	}
+}
+
+// run scales the components of the NineCluster and waits for the affected workloads to roll
+func (c *scaleCmd) run() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	nc, err := client.NineinfraV1alpha1().NineClusters(c.scaleOpts.NS).Get(context.TODO(), c.scaleOpts.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	previous := nc.Spec.DeepCopy()
+	changes, demands, err := c.plan(nc)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("NineCluster:" + c.scaleOpts.Name + " in namespace:" + c.scaleOpts.NS + " is already at the desired size")
+		return nil
+	}
+	printScalePlan(changes)
+	if err := CheckCapacity(demands, c.scaleOpts.force); err != nil {
+		return err
+	}
+	if c.scaleOpts.dryRun {
+		return nil
+	}
+	if !c.scaleOpts.yes && !Ask("The NineCluster will be scaled as above, are you sure you want to continue") {
+		return errors.New("aborting NineCluster scaling")
+	}
+
+	volumeSizes := make(map[string]int64)
+	for _, change := range changes {
+		if change.VolumeGrown {
+			name := NineWorkLoadName(c.scaleOpts.Name, change.Workload)
+			if volumeSizes[name], err = StsVolumeSize(name, c.scaleOpts.NS); err != nil {
+				return err
+			}
+		}
+	}
+	result, err := client.NineinfraV1alpha1().NineClusters(c.scaleOpts.NS).Update(context.TODO(), nc, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	RecordRevision(result, previous, "scale")
+	fmt.Println("NineCluster:" + c.scaleOpts.Name + " in namespace:" + c.scaleOpts.NS + " is scaled,waiting for the workloads to roll")
+
+	for _, change := range changes {
+		name := NineWorkLoadName(c.scaleOpts.Name, change.Workload)
+		if change.VolumeGrown {
+			if err := WaitStsVolumeGrown(name, c.scaleOpts.NS, volumeSizes[name], c.scaleOpts.timeout); err != nil {
+				return err
+			}
+		}
+		if err := WaitStsReady(name, c.scaleOpts.NS, change.Replicas, c.scaleOpts.timeout); err != nil {
+			return err
+		}
+		fmt.Printf("Workload %s is ready\n", name)
+	}
+	return nil
+}
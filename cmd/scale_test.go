@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStsVolumeSize(t *testing.T) {
+	requests := func(size string) corev1.ResourceList {
+		return corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)}
+	}
+	sts := func(template string) *appsv1.StatefulSet {
+		replicas := int32(2)
+		s := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "c1-nine-minio"}, Spec: appsv1.StatefulSetSpec{Replicas: &replicas}}
+		if template != "" {
+			s.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "data"},
+				Spec:       corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{Requests: requests(template)}},
+			}}
+		}
+		return s
+	}
+	pvc := func(name string, size string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{Requests: requests(size)}},
+		}
+	}
+	tests := []struct {
+		name string
+		sts  *appsv1.StatefulSet
+		pvcs []corev1.PersistentVolumeClaim
+		want string
+	}{
+		{"no templates", sts(""), nil, "0"},
+		{"template only", sts("8Gi"), nil, "8Gi"},
+		{"claims not resized", sts("8Gi"), []corev1.PersistentVolumeClaim{pvc("data-c1-nine-minio-0", "8Gi"), pvc("data-c1-nine-minio-1", "8Gi")}, "8Gi"},
+		{"claims resized", sts("8Gi"), []corev1.PersistentVolumeClaim{pvc("data-c1-nine-minio-0", "16Gi"), pvc("data-c1-nine-minio-1", "16Gi")}, "16Gi"},
+		{"claims partially resized", sts("8Gi"), []corev1.PersistentVolumeClaim{pvc("data-c1-nine-minio-0", "16Gi"), pvc("data-c1-nine-minio-1", "8Gi")}, "8Gi"},
+		{"claims of other statefulsets ignored", sts("8Gi"), []corev1.PersistentVolumeClaim{pvc("data-c2-nine-minio-0", "16Gi"), pvc("data-c2-nine-minio-1", "16Gi")}, "8Gi"},
+		{"template resized", sts("16Gi"), []corev1.PersistentVolumeClaim{pvc("data-c1-nine-minio-0", "8Gi")}, "16Gi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := resource.MustParse(tt.want)
+			if got := stsVolumeSize(tt.sts, tt.pvcs); got != want.Value() {
+				t.Errorf("stsVolumeSize() = %d, want %d", got, want.Value())
+			}
+		})
+	}
+}
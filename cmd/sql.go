@@ -96,11 +96,11 @@ func (s *sqlCmd) interactiveSQLByKubectl() error {
 	if thriftIP == "" || thriftPort == 0 {
 		return errors.New("invalid Thrift Access Info")
 	}
-	err = runCommandWithOSIO("kubectl", "exec", "-it", podName[0], "-n", s.sqlOpts.NS, "--", "/opt/kyuubi/bin/beeline",
+	err = runCommandWithOSIO("kubectl", kubectlArgs("exec", "-it", podName[0], "-n", s.sqlOpts.NS, "--", "/opt/kyuubi/bin/beeline",
 		"-u", fmt.Sprintf("jdbc:hive2://%s:%d", thriftIP, thriftPort),
 		"-n", s.sqlOpts.UserName,
 		"-p", s.sqlOpts.Password,
-		"--silent", fmt.Sprintf("%v", s.sqlOpts.Silent))
+		"--silent", fmt.Sprintf("%v", s.sqlOpts.Silent))...)
 	if err != nil {
 		return err
 	}
@@ -2,17 +2,47 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 	"io"
-	v1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
+	"sort"
+	"strings"
 )
 
 const (
-	statusDesc = `'status' command displays the NineInfra's status information`
+	statusDesc = `'status' command displays the NineInfra's status information,
+including the helm releases,the operators,the crds and the webhooks.
+It exits with a non-zero code if the NineInfra is not healthy.
+With --all-contexts or --contexts,the NineInfra in the kubeconfig contexts are checked concurrently,the
+unreachable contexts are reported as unhealthy.`
+	statusExample = `1. Display the NineInfra's status
+   $ kubectl nine status
+
+2. Display the NineInfra's status in json
+   $ kubectl nine status --json
+
+3. Display the NineInfra's health in all the contexts of the kubeconfig
+   $ kubectl nine status --all-contexts
+
+4. Display the NineInfra's health in some contexts with a timeout of 10 seconds for each
+   $ kubectl nine status --contexts prod-a,prod-b --context-timeout 10s`
+)
+
+const (
+	PrintFmtStrChartStatus   = "%-20s\t%-10s\t%-8s\t%-10s\t%-10s\t%-10s\t%-8s\n"
+	PrintFmtStrCrdStatus     = "%-45s\t%-8s\t%-10s\n"
+	PrintFmtStrWebhookStatus = "%-45s\t%-45s\t%-8s\n"
+	PrintFmtStrFleetStatus   = "%-20s\t%-20s\t%-12s\t%-s\n"
+	HelmReleaseStatusMissing = "missing"
+	HelmReleaseStatusShared  = "shared"
+	HelmReleaseNameAnnoKey   = "meta.helm.sh/release-name"
 )
 
 type statusCmd struct {
@@ -21,6 +51,58 @@ type statusCmd struct {
 	ns         string
 	yamlOutput bool
 	jsonOutput bool
+	platformNS string
+	fleet      FleetOptions
+}
+
+// ChartStatus is the status of a chart of the NineInfra platform
+type ChartStatus struct {
+	Chart           string   `json:"chart" yaml:"chart"`
+	ReleaseStatus   string   `json:"releaseStatus" yaml:"releaseStatus"`
+	Revision        string   `json:"revision" yaml:"revision"`
+	Version         string   `json:"version" yaml:"version"`
+	ExpectedVersion string   `json:"expectedVersion" yaml:"expectedVersion"`
+	Workloads       []string `json:"workloads" yaml:"workloads"`
+	Ready           bool     `json:"ready" yaml:"ready"`
+}
+
+// CrdStatus is the status of a crd required by the NineInfra platform
+type CrdStatus struct {
+	Name           string   `json:"name" yaml:"name"`
+	Present        bool     `json:"present" yaml:"present"`
+	ServedVersions []string `json:"servedVersions" yaml:"servedVersions"`
+}
+
+// WebhookStatus is the status of a webhook served by the NineInfra platform
+type WebhookStatus struct {
+	Name    string `json:"name" yaml:"name"`
+	Service string `json:"service" yaml:"service"`
+	Ready   bool   `json:"ready" yaml:"ready"`
+}
+
+// ContextPlatformStatus is the status of the NineInfra platform in a kubeconfig context
+type ContextPlatformStatus struct {
+	Context string          `json:"context" yaml:"context"`
+	Error   string          `json:"error,omitempty" yaml:"error,omitempty"`
+	Status  *PlatformStatus `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// PlatformStatus is the status of the NineInfra platform
+type PlatformStatus struct {
+	Namespace string          `json:"namespace" yaml:"namespace"`
+	Healthy   bool            `json:"healthy" yaml:"healthy"`
+	Charts    []ChartStatus   `json:"charts" yaml:"charts"`
+	Crds      []CrdStatus     `json:"crds" yaml:"crds"`
+	Webhooks  []WebhookStatus `json:"webhooks" yaml:"webhooks"`
+}
+
+type helmRelease struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Revision   string `json:"revision"`
+	Status     string `json:"status"`
+	Chart      string `json:"chart"`
+	AppVersion string `json:"app_version"`
 }
 
 func newNineStatusCmd(out io.Writer, errOut io.Writer) *cobra.Command {
@@ -30,8 +112,13 @@ func newNineStatusCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 		Use:     "status",
 		Short:   "Display the NineInfra's status",
 		Long:    statusDesc,
-		Example: `  kubectl nine status`,
-		Args:    cobra.MaximumNArgs(0),
+		Example: statusExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("status command takes no arguments")
+			}
+			return c.fleet.Validate()
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := c.run()
 			if err != nil {
@@ -45,28 +132,334 @@ func newNineStatusCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	f := cmd.Flags()
 	f.BoolVarP(&c.yamlOutput, "yaml", "y", false, "yaml output")
 	f.BoolVarP(&c.jsonOutput, "json", "j", false, "json output")
+	f.StringVar(&c.platformNS, "platform-namespace", "", "k8s namespace of the NineInfra platform,discovered automatically if not specified")
+	c.fleet.AddFlags(f)
 	return cmd
 }
 
 func (d *statusCmd) run() error {
-	path, _ := rootCmd.Flags().GetString(kubeconfig)
-	client, err := GetKubeClient(path)
+	if d.fleet.Enabled() {
+		return d.runInContexts()
+	}
+	ns, err := ResolvePlatformNamespace(d.platformNS)
+	if err != nil {
+		return err
+	}
+	status, err := GetPlatformStatus(ns)
 	if err != nil {
 		return err
 	}
-	deploys, err := client.AppsV1().Deployments(DefaultNamespace).List(context.TODO(), metav1.ListOptions{})
+	switch {
+	case d.jsonOutput:
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case d.yamlOutput:
+		data, err := yaml.Marshal(status)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	default:
+		printPlatformStatus(status)
+	}
+	if !status.Healthy {
+		return errors.New("NineInfra in namespace:" + ns + " is not healthy")
+	}
+	return nil
+}
+
+// runInContexts checks the NineInfra in the contexts,failing if any of them is unhealthy or unreachable
+func (d *statusCmd) runInContexts() error {
+	contexts, err := d.fleet.ResolveContexts()
 	if err != nil {
 		return err
 	}
-	printNineInfra(deploys)
+	args := []string{"status", "--json"}
+	if d.platformNS != "" {
+		args = append(args, "--platform-namespace", d.platformNS)
+	}
+	statuses := make([]ContextPlatformStatus, 0, len(contexts))
+	healthy := true
+	for _, r := range RunInContexts(contexts, d.fleet.Timeout, args...) {
+		cs := ContextPlatformStatus{Context: r.Context}
+		// the status is printed even if the NineInfra is not healthy
+		var status PlatformStatus
+		if len(r.Output) != 0 && json.Unmarshal(r.Output, &status) == nil {
+			cs.Status = &status
+		} else if r.Err != nil {
+			cs.Error = r.Err.Error()
+		} else {
+			cs.Error = "no status returned"
+		}
+		if cs.Status == nil || !cs.Status.Healthy {
+			healthy = false
+		}
+		statuses = append(statuses, cs)
+	}
+	switch {
+	case d.jsonOutput:
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case d.yamlOutput:
+		data, err := yaml.Marshal(statuses)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	default:
+		printFleetStatus(statuses)
+	}
+	if !healthy {
+		return errors.New("NineInfra is not healthy in some contexts")
+	}
 	return nil
 }
 
-func printNineInfra(deploys *v1.DeploymentList) {
-	fmt.Printf("%-20s\t%-10s\t%-10s\n", "NAME", "READY", "AGE")
+// platformProblems returns the charts,crds and webhooks not ready
+func platformProblems(status *PlatformStatus) []string {
+	problems := make([]string, 0)
+	for _, cs := range status.Charts {
+		if !cs.Ready {
+			problems = append(problems, fmt.Sprintf("chart %s(%s)", cs.Chart, cs.ReleaseStatus))
+		}
+	}
+	for _, cs := range status.Crds {
+		if !cs.Present {
+			problems = append(problems, "crd "+cs.Name+"(missing)")
+		}
+	}
+	for _, wh := range status.Webhooks {
+		if !wh.Ready {
+			problems = append(problems, "webhook "+wh.Name+"(not ready)")
+		}
+	}
+	return problems
+}
+
+func printFleetStatus(statuses []ContextPlatformStatus) {
+	fmt.Printf(PrintFmtStrFleetStatus, "CONTEXT", "NAMESPACE", "HEALTHY", "PROBLEMS")
+	for _, cs := range statuses {
+		if cs.Status == nil {
+			fmt.Printf(PrintFmtStrFleetStatus, cs.Context, "-", ContextStateUnreachable, cs.Error)
+			continue
+		}
+		fmt.Printf(PrintFmtStrFleetStatus, cs.Context, cs.Status.Namespace, fmt.Sprintf("%t", cs.Status.Healthy),
+			strings.Join(platformProblems(cs.Status), ","))
+	}
+}
+
+func listHelmReleases(namespace string) (map[string]helmRelease, error) {
+	output, errput, err := runCommand("helm", append([]string{"list", "-a", "-n", namespace, "-o", "json"}, kubeconfigArgs()...)...)
+	if err != nil {
+		return nil, errors.New(errput)
+	}
+	var releases []helmRelease
+	if err := json.Unmarshal([]byte(output), &releases); err != nil {
+		return nil, err
+	}
+	releaseMap := make(map[string]helmRelease)
+	for _, r := range releases {
+		releaseMap[r.Name] = r
+	}
+	return releaseMap, nil
+}
+
+// workloadStatus returns the readiness of the deployments and statefulsets of the helm releases
+func workloadStatus(namespace string) (map[string][]string, map[string]bool, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	workloads := make(map[string][]string)
+	ready := make(map[string]bool)
+	deploys, err := client.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
 	for _, deploy := range deploys.Items {
-		ready := fmt.Sprintf("%t", deploy.Status.ReadyReplicas == *deploy.Spec.Replicas)
-		age := fmt.Sprintf("%s", duration.HumanDuration(metav1.Now().Sub(deploy.CreationTimestamp.Time)))
-		fmt.Printf("%-20s\t%-10s\t%-10s\n", NineInfraDeploymentAlias[deploy.Name], ready, age)
+		release := deploy.Annotations[HelmReleaseNameAnnoKey]
+		if _, ok := ready[release]; !ok {
+			ready[release] = true
+		}
+		name := deploy.Name
+		if alias, ok := NineInfraDeploymentAlias[deploy.Name]; ok {
+			name = alias
+		}
+		workloads[release] = append(workloads[release], fmt.Sprintf("%s(%d/%d)", name, deploy.Status.ReadyReplicas, *deploy.Spec.Replicas))
+		ready[release] = ready[release] && deploy.Status.ReadyReplicas == *deploy.Spec.Replicas
+	}
+	stsList, err := client.AppsV1().StatefulSets(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, sts := range stsList.Items {
+		release := sts.Annotations[HelmReleaseNameAnnoKey]
+		if _, ok := ready[release]; !ok {
+			ready[release] = true
+		}
+		workloads[release] = append(workloads[release], fmt.Sprintf("%s(%d/%d)", sts.Name, sts.Status.ReadyReplicas, *sts.Spec.Replicas))
+		ready[release] = ready[release] && sts.Status.ReadyReplicas == *sts.Spec.Replicas
+	}
+	return workloads, ready, nil
+}
+
+func getCrdStatus(crd string) CrdStatus {
+	status := CrdStatus{Name: crd, ServedVersions: []string{}}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	c, err := GetKubeDynamicClient(path)
+	if err != nil {
+		return status
+	}
+	crdResource := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	obj, err := c.Resource(crdResource).Get(context.TODO(), crd, metav1.GetOptions{})
+	if err != nil {
+		return status
+	}
+	status.Present = true
+	versions, _, _ := unstructured.NestedSlice(obj.Object, "spec", "versions")
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if served, _ := version["served"].(bool); served {
+			status.ServedVersions = append(status.ServedVersions, fmt.Sprintf("%v", version["name"]))
+		}
+	}
+	return status
+}
+
+func getWebhookStatus(namespace string) ([]WebhookStatus, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	services := make(map[string]string)
+	vwcList, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, vwc := range vwcList.Items {
+		for _, wh := range vwc.Webhooks {
+			if wh.ClientConfig.Service != nil && wh.ClientConfig.Service.Namespace == namespace {
+				services[wh.Name] = wh.ClientConfig.Service.Name
+			}
+		}
+	}
+	mwcList, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, mwc := range mwcList.Items {
+		for _, wh := range mwc.Webhooks {
+			if wh.ClientConfig.Service != nil && wh.ClientConfig.Service.Namespace == namespace {
+				services[wh.Name] = wh.ClientConfig.Service.Name
+			}
+		}
+	}
+	webhooks := make([]WebhookStatus, 0)
+	for name, svc := range services {
+		_, ready, _ := CheckEndpointsReady(svc, namespace, 1)
+		webhooks = append(webhooks, WebhookStatus{Name: name, Service: svc, Ready: ready})
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].Name < webhooks[j].Name
+	})
+	return webhooks, nil
+}
+
+// GetPlatformStatus collects the status of the NineInfra platform in the namespace
+func GetPlatformStatus(namespace string) (*PlatformStatus, error) {
+	status := &PlatformStatus{Namespace: namespace, Healthy: true}
+	releases, err := listHelmReleases(namespace)
+	if err != nil {
+		return nil, err
+	}
+	workloads, ready, err := workloadStatus(namespace)
+	if err != nil {
+		return nil, err
+	}
+	others, err := OtherPlatformNamespaces(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range SortedKeys(DefaultChartList) {
+		cs := ChartStatus{Chart: c, ExpectedVersion: DefaultChartList[c], Workloads: workloads[c]}
+		r, ok := releases[c]
+		switch {
+		case ok:
+			cs.ReleaseStatus = r.Status
+			cs.Revision = r.Revision
+			cs.Version = strings.TrimPrefix(r.Chart, c+"-")
+			cs.Ready = r.Status == "deployed" && ready[c]
+		case IsClusterScopedChart(c) && len(others) != 0:
+			cs.ReleaseStatus = HelmReleaseStatusShared
+			cs.Ready = true
+		default:
+			cs.ReleaseStatus = HelmReleaseStatusMissing
+		}
+		if !cs.Ready {
+			status.Healthy = false
+		}
+		status.Charts = append(status.Charts, cs)
+	}
+
+	for _, crd := range NineInfraCrdList {
+		cs := getCrdStatus(crd)
+		if !cs.Present {
+			status.Healthy = false
+		}
+		status.Crds = append(status.Crds, cs)
+	}
+
+	status.Webhooks, err = getWebhookStatus(namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, wh := range status.Webhooks {
+		if !wh.Ready {
+			status.Healthy = false
+		}
+	}
+	return status, nil
+}
+
+func printPlatformStatus(status *PlatformStatus) {
+	drift := false
+	fmt.Printf(PrintFmtStrChartStatus, "CHART", "STATUS", "REVISION", "VERSION", "EXPECTED", "READY", "WORKLOADS")
+	for _, cs := range status.Charts {
+		version := cs.Version
+		if version != "" && version != cs.ExpectedVersion {
+			version = version + "*"
+			drift = true
+		}
+		fmt.Printf(PrintFmtStrChartStatus, cs.Chart, cs.ReleaseStatus, cs.Revision, version, cs.ExpectedVersion,
+			fmt.Sprintf("%t", cs.Ready), strings.Join(cs.Workloads, ","))
+	}
+	fmt.Println()
+	fmt.Printf(PrintFmtStrCrdStatus, "CRD", "PRESENT", "SERVED")
+	for _, cs := range status.Crds {
+		fmt.Printf(PrintFmtStrCrdStatus, cs.Name, fmt.Sprintf("%t", cs.Present), strings.Join(cs.ServedVersions, ","))
+	}
+	if len(status.Webhooks) != 0 {
+		fmt.Println()
+		fmt.Printf(PrintFmtStrWebhookStatus, "WEBHOOK", "SERVICE", "READY")
+		for _, wh := range status.Webhooks {
+			fmt.Printf(PrintFmtStrWebhookStatus, wh.Name, wh.Service, fmt.Sprintf("%t", wh.Ready))
+		}
+	}
+	fmt.Println()
+	fmt.Printf("NineInfra in namespace:%s healthy:%t\n", status.Namespace, status.Healthy)
+	if drift {
+		fmt.Println("The versions marked with * differ from the expected ones,you can upgrade them by 'kubectl nine upgrade'")
 	}
 }
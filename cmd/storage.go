@@ -3,8 +3,10 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 	"io"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
@@ -16,15 +18,26 @@ import (
 )
 
 const (
-	storageDesc    = `'storage' command manages the physical storages on the k8s for the NineCluster`
+	storageDesc = `'storage' command manages the physical storages on the k8s for the NineCluster.
+The 'create' command discovers,initializes and labels the drives through the directpv apis natively,
+the unknown commands are passed to the kubectl-directpv.`
 	storageExample = `1. Create storage pool
    $ kubectl nine storage --command=create --nodes=node{1...4} --drives=sd{a...f} --storage-pool=nineinfra-high --dangerous
 
-2. Delete storage pool
+2. Show the drives to be initialized into a storage pool without changing anything
+   $ kubectl nine storage --command=create --nodes=node{1...4} --drives=sd{a...f} --storage-pool=nineinfra-high --dry-run
+
+3. Delete storage pool
    $ kubectl nine storage -c=delete --storage-pool=nineinfra-high
 
-3. List storage pools
-   $ kubectl nine storage -c=list`
+4. List storage pools
+   $ kubectl nine storage -c=list
+
+5. List storage pools with their drives
+   $ kubectl nine storage -c=list --wide
+
+6. Cordon a drive and list the volumes to be moved
+   $ kubectl nine storage drive drain node1/sdb`
 )
 
 // DriveStatus denotes drive status
@@ -82,7 +95,9 @@ type storageCmd struct {
 	allFlag         bool     // --all flag
 	dangerousFlag   bool     // --dangerous flag
 	dryRunFlag      bool     // --dry-run flag
+	forceFlag       bool     // --force flag
 	noHeaders       bool     // --no-headers flag
+	wideFlag        bool     // --wide flag
 	storagePool     string
 }
 
@@ -106,6 +121,7 @@ func newStorageCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 		},
 	}
 	cmd = DisableHelp(cmd)
+	cmd.AddCommand(newStorageDriveCmd(out, errOut))
 	f := cmd.Flags()
 	f.StringSliceVar(&c.nodesArgs, "nodes", c.nodesArgs, "discover drives from given nodes; supports ellipses pattern e.g. node{1...10}")
 	f.StringSliceVar(&c.drivesArgs, "drives", c.drivesArgs, "discover drives by given names; supports ellipses pattern e.g. sd{a...z}")
@@ -117,9 +133,11 @@ func newStorageCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	f.BoolVar(&c.dangerousFlag, "dangerous", c.dangerousFlag, "Perform initialization of drives which will permanently erase existing data")
 	f.StringSliceVar(&c.driveStatusArgs, "status", c.driveStatusArgs, fmt.Sprintf("%v; one of: %v", "If present, select drives by drive status", strings.Join(driveStatusValues, "|")))
 	f.BoolVar(&c.dryRunFlag, "dry-run", c.dryRunFlag, "Run in dry run mode")
+	f.BoolVar(&c.forceFlag, "force", c.forceFlag, "Label the initialized drives even if they are in another storage pool or hold volumes")
 	f.StringVarP(&c.outputFormat, "output", "o", c.outputFormat, "Output format. One of: json|yaml|wide")
 	f.StringVarP(&c.ns, "namespace", "n", "", "k8s namespace for storage pvcs")
 	f.StringVar(&c.nineName, "ninecluster-name", "", "the name of the ninecluster")
+	f.BoolVar(&c.wideFlag, "wide", c.wideFlag, "If present, list the drives of the storage pools")
 	f.BoolVar(&c.noHeaders, "no-headers", c.noHeaders, "When using the default or custom-column output format, don't print headers (default print headers)")
 	return cmd
 }
@@ -145,8 +163,7 @@ func (d *storageCmd) validate(args []string) error {
 	return nil
 }
 
-func (d *storageCmd) addFlags(parameters []string, subCommand string) []string {
-	path, _ := rootCmd.Flags().GetString(kubeconfig)
+func (d *storageCmd) addFlags(parameters []string, subCommand string, path string) []string {
 	if path != "" {
 		parameters = append(parameters, []string{"--kubeconfig", path}...)
 	}
@@ -203,12 +220,7 @@ func (d *storageCmd) executeKubectlCommand(parameters []string) error {
 	return nil
 }
 
-func (d *storageCmd) executeDiskCmd(parameters []string, subCommand string) error {
-	parameters = d.addFlags(parameters, subCommand)
-	return d.executeKubectlCommand(parameters)
-}
-
-func (d *storageCmd) createStorageClass() error {
+func (d *storageCmd) createStorageClass(drives map[string]bool) error {
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
 	client, err := GetKubeClient(path)
 	if err != nil {
@@ -227,11 +239,11 @@ func (d *storageCmd) createStorageClass() error {
 			ObjectMeta: metav1.ObjectMeta{
 				Name: d.storagePool,
 				Labels: map[string]string{
-					"app.kubernetes.io/managed-by": "NineInfra",
-					"application-name":             "directpv.min.io",
-					"application-type":             "CSIDriver",
-					"directpv.min.io/created-by":   "kubectl-nine",
-					"directpv.min.io/version":      "v1beta1",
+					"app.kubernetes.io/managed-by":   "NineInfra",
+					"application-name":               "directpv.min.io",
+					"application-type":               "CSIDriver",
+					DefaultDirectPVCreatedByLabelKey: DefaultDirectPVCreatedByValue,
+					"directpv.min.io/version":        "v1beta1",
 				},
 			},
 			AllowVolumeExpansion: &allowVolumeExpansion,
@@ -246,50 +258,70 @@ func (d *storageCmd) createStorageClass() error {
 				},
 			},
 			Parameters: map[string]string{
-				"fstype":                       "xfs",
-				"directpv.min.io/storage-pool": d.storagePool,
+				"fstype":                   "xfs",
+				DefaultStoragePoolLabelKey: d.storagePool,
 			},
 			Provisioner:       "directpv-min-io",
 			ReclaimPolicy:     &reclaimPolicy,
 			VolumeBindingMode: &volumeBindingMode,
 		}
-		_, err = client.StorageV1().StorageClasses().Create(context.TODO(), desiredSC, metav1.CreateOptions{})
-		if err != nil {
-			return err
+		if d.dryRunFlag {
+			fmt.Printf("Create the storage class %s (dry run)\n", d.storagePool)
+		} else {
+			_, err = client.StorageV1().StorageClasses().Create(context.TODO(), desiredSC, metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	var parameters []string
-	parameters = []string{"directpv", DiskSubCommandLabel, "drives", fmt.Sprintf("storage-pool=%s", d.storagePool)}
-	err = d.executeDiskCmd(parameters, DiskSubCommandLabel)
-	if err != nil {
-		return err
-	}
-	return nil
+	_, err = LabelDrives(drives, d.storagePool, d.forceFlag, d.dryRunFlag)
+	return err
 }
 
+// runCreateCmd discovers the drives through the directpv nodes,initializes the available ones and labels the
+// initialized ones with the storage pool,the kubectl-directpv is not required
 func (d *storageCmd) runCreateCmd() error {
-	var parameters []string
-
-	parameters = []string{"directpv", DiskSubCommandDiscover}
-
-	err := d.executeDiskCmd(parameters, DiskSubCommandDiscover)
+	devices, err := DiscoverDevices(d.nodesArgs, d.drivesArgs, d.allFlag, nodeListTimeout)
 	if err != nil {
 		return err
 	}
-
-	parameters = []string{"directpv", DiskSubCommandInit, outputFile}
-	err = d.executeDiskCmd(parameters, DiskSubCommandInit)
-	if err != nil {
+	PrintDiscoveredDevices(devices, d.noHeaders)
+	available := 0
+	for _, device := range devices {
+		if device.Available() {
+			available++
+		}
+	}
+	if available == 0 {
+		return errors.New("no available drives found,check the --nodes and the --drives")
+	}
+	if err := WriteInitConfig(outputFile, devices); err != nil {
 		return err
 	}
+	fmt.Printf("Generated the init config %s\n", outputFile)
 
-	err = d.createStorageClass()
-	if err != nil {
-		return err
+	drives := AvailableDrives(devices)
+	if d.dryRunFlag {
+		fmt.Printf("%d drives will be initialized (dry run)\n", available)
+	} else {
+		if !d.dangerousFlag {
+			return errors.New("initializing the drives will permanently erase the existing data,please review the drives and use --dangerous")
+		}
+		results, err := InitDevices(devices, nodeListTimeout)
+		if err != nil {
+			return err
+		}
+		PrintInitResults(results)
+		for _, r := range results {
+			if r.Error != "" {
+				return errors.New("some drives are not initialized,please check the messages above")
+			}
+		}
+		drives = InitializedDrives(results)
 	}
 
-	return nil
+	return d.createStorageClass(drives)
 }
 
 func (d *storageCmd) runCleanCmd() error {
@@ -349,14 +381,24 @@ func (d *storageCmd) runDeleteCmd() error {
 	return nil
 }
 
+// runListCmd lists the storage pools from the directpv drives,the drives of the pools are listed with --wide
 func (d *storageCmd) runListCmd() error {
-	var parameters []string
-
-	parameters = []string{"get", "storageclass"}
-	err := d.executeKubectlCommand(parameters)
+	pools, err := GetStoragePools()
 	if err != nil {
 		return err
 	}
+	switch d.outputFormat {
+	case OutputJson:
+		return printJson(pools)
+	case OutputYaml:
+		data, err := yaml.Marshal(pools)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	default:
+		PrintStoragePools(pools, d.wideFlag || d.outputFormat == "wide", d.noHeaders)
+	}
 	return nil
 }
 
@@ -395,9 +437,16 @@ func (d *storageCmd) run(_ []string) error {
 			parameters = []string{"directpv", d.subCommand}
 		}
 
-		parameters = d.addFlags(parameters, d.subCommand)
+		// the directpv plugin has no --context,the context is passed by its kubeconfig
+		path, _ := rootCmd.Flags().GetString(kubeconfig)
+		path, cleanup, err := ContextKubeconfig(path)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		parameters = d.addFlags(parameters, d.subCommand, path)
 
-		err := d.executeKubectlCommand(parameters)
+		err = d.executeKubectlCommand(parameters)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	StoragePoolNone            = "-"
+	PrintFmtStrStoragePool     = "%-20s\t%-20s\t%-6s\t%-6s\t%-10s\t%-10s\t%-10s\t%-20s\t%-8s\t%-s\n"
+	PrintFmtStrStoragePoolWide = "%-20s\t%-15s\t%-8s\t%-8s\t%-12s\t%-10s\t%-10s\t%-10s\t%-8s\n"
+)
+
+// StoragePoolDrive is a directpv drive of a storage pool
+type StoragePoolDrive struct {
+	Node          string `json:"node" yaml:"node"`
+	Drive         string `json:"drive" yaml:"drive"`
+	Status        string `json:"status" yaml:"status"`
+	Unschedulable bool   `json:"unschedulable" yaml:"unschedulable"`
+	Total         int64  `json:"total" yaml:"total"`
+	Allocated     int64  `json:"allocated" yaml:"allocated"`
+	Free          int64  `json:"free" yaml:"free"`
+	Volumes       int    `json:"volumes" yaml:"volumes"`
+}
+
+// StoragePoolInfo is a storage pool summed from its directpv drives
+type StoragePoolInfo struct {
+	Pool         string             `json:"pool" yaml:"pool"`
+	StorageClass string             `json:"storageClass" yaml:"storageClass"`
+	Nodes        int                `json:"nodes" yaml:"nodes"`
+	Total        int64              `json:"total" yaml:"total"`
+	Allocated    int64              `json:"allocated" yaml:"allocated"`
+	Free         int64              `json:"free" yaml:"free"`
+	Unhealthy    map[string]int     `json:"unhealthy" yaml:"unhealthy"`
+	Volumes      int                `json:"volumes" yaml:"volumes"`
+	NineClusters []string           `json:"nineClusters" yaml:"nineClusters"`
+	Drives       []StoragePoolDrive `json:"drives" yaml:"drives"`
+}
+
+// unhealthyDriveStatus are the drive states reported as unhealthy
+var unhealthyDriveStatus = []directpvv1beta1.TypeDriveStatus{
+	directpvv1beta1.DriveStatusLost,
+	directpvv1beta1.DriveStatusError,
+	directpvv1beta1.DriveStatusMoving,
+}
+
+// volumeNineCluster returns the NineCluster the pod of the volume belongs to,empty if none
+func volumeNineCluster(volume *directpvv1beta1.DirectPVVolume, clusters map[string][]string) string {
+	ns := volume.Labels[string(directpvv1beta1.PodNSLabelKey)]
+	podName := volume.Labels[string(directpvv1beta1.PodNameLabelKey)]
+	for _, name := range clusters[ns] {
+		if strings.HasPrefix(podName, NineResourceName(name)+"-") {
+			return ns + "/" + name
+		}
+	}
+	return ""
+}
+
+// GetStoragePools returns the storage pools of the storage classes and the directpv drives,the drives without a
+// storage pool label are in the pool "-"
+func GetStoragePools() ([]StoragePoolInfo, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return nil, err
+	}
+	nclient, err := GetNineInfraClient(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make(map[string]*StoragePoolInfo)
+	getPool := func(name string) *StoragePoolInfo {
+		if _, ok := pools[name]; !ok {
+			pools[name] = &StoragePoolInfo{Pool: name, Unhealthy: make(map[string]int), NineClusters: make([]string, 0), Drives: make([]StoragePoolDrive, 0)}
+		}
+		return pools[name]
+	}
+	scList, err := client.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range scList.Items {
+		if pool, ok := sc.Parameters[DefaultStoragePoolLabelKey]; ok && sc.Provisioner == directpvv1beta1.Identity {
+			getPool(pool).StorageClass = sc.Name
+		}
+	}
+
+	drives, err := dpclient.DirectPVDrives().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	drivePools := make(map[string]string)
+	poolNodes := make(map[string]map[string]bool)
+	for _, drive := range drives.Items {
+		name := drive.Labels[DefaultStoragePoolLabelKey]
+		if name == "" {
+			name = StoragePoolNone
+		}
+		drivePools[drive.Name] = name
+		pool := getPool(name)
+		volumes := drive.GetVolumeCount()
+		if volumes < 0 {
+			volumes = 0
+		}
+		pool.Drives = append(pool.Drives, StoragePoolDrive{
+			Node:          string(drive.GetNodeID()),
+			Drive:         string(drive.GetDriveName()),
+			Status:        string(drive.Status.Status),
+			Unschedulable: drive.IsUnschedulable(),
+			Total:         drive.Status.TotalCapacity,
+			Allocated:     drive.Status.AllocatedCapacity,
+			Free:          drive.Status.FreeCapacity,
+			Volumes:       volumes,
+		})
+		pool.Total += drive.Status.TotalCapacity
+		pool.Allocated += drive.Status.AllocatedCapacity
+		pool.Free += drive.Status.FreeCapacity
+		pool.Volumes += volumes
+		for _, status := range unhealthyDriveStatus {
+			if drive.Status.Status == status {
+				pool.Unhealthy[strings.ToLower(string(status))]++
+			}
+		}
+		if _, ok := poolNodes[name]; !ok {
+			poolNodes[name] = make(map[string]bool)
+		}
+		poolNodes[name][string(drive.GetNodeID())] = true
+	}
+
+	ncList, err := nclient.NineinfraV1alpha1().NineClusters("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusters := make(map[string][]string)
+	for _, nc := range ncList.Items {
+		clusters[nc.Namespace] = append(clusters[nc.Namespace], nc.Name)
+	}
+	volumes, err := dpclient.DirectPVVolumes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	poolClusters := make(map[string]map[string]bool)
+	for i := range volumes.Items {
+		name, ok := drivePools[string(volumes.Items[i].GetDriveID())]
+		cluster := volumeNineCluster(&volumes.Items[i], clusters)
+		if !ok || cluster == "" {
+			continue
+		}
+		if _, ok := poolClusters[name]; !ok {
+			poolClusters[name] = make(map[string]bool)
+		}
+		poolClusters[name][cluster] = true
+	}
+
+	result := make([]StoragePoolInfo, 0, len(pools))
+	for _, name := range SortedKeys(pools) {
+		pool := pools[name]
+		pool.Nodes = len(poolNodes[name])
+		pool.NineClusters = append(pool.NineClusters, SortedKeys(poolClusters[name])...)
+		sort.Slice(pool.Drives, func(i, j int) bool {
+			if pool.Drives[i].Node == pool.Drives[j].Node {
+				return pool.Drives[i].Drive < pool.Drives[j].Drive
+			}
+			return pool.Drives[i].Node < pool.Drives[j].Node
+		})
+		result = append(result, *pool)
+	}
+	return result, nil
+}
+
+// formatUnhealthy returns the counts of the unhealthy drives,e.g. lost:1,error:2
+func formatUnhealthy(unhealthy map[string]int) string {
+	var counts []string
+	for _, status := range unhealthyDriveStatus {
+		if n := unhealthy[strings.ToLower(string(status))]; n != 0 {
+			counts = append(counts, fmt.Sprintf("%s:%d", strings.ToLower(string(status)), n))
+		}
+	}
+	if len(counts) == 0 {
+		return "-"
+	}
+	return strings.Join(counts, ",")
+}
+
+// PrintStoragePools prints the storage pools,the drives of the pools are printed if wide is true
+func PrintStoragePools(pools []StoragePoolInfo, wide bool, noHeaders bool) {
+	if !noHeaders {
+		fmt.Printf(PrintFmtStrStoragePool, "STORAGEPOOL", "STORAGECLASS", "NODES", "DRIVES", "TOTAL", "ALLOCATED", "FREE", "UNHEALTHY", "VOLUMES", "NINECLUSTERS")
+	}
+	for _, p := range pools {
+		sc := p.StorageClass
+		if sc == "" {
+			sc = "-"
+		}
+		ncs := strings.Join(p.NineClusters, ",")
+		if ncs == "" {
+			ncs = "-"
+		}
+		fmt.Printf(PrintFmtStrStoragePool, p.Pool, sc, strconv.Itoa(p.Nodes), strconv.Itoa(len(p.Drives)), formatBytes(p.Total),
+			formatBytes(p.Allocated), formatBytes(p.Free), formatUnhealthy(p.Unhealthy), strconv.Itoa(p.Volumes), ncs)
+	}
+	if !wide {
+		return
+	}
+	fmt.Println()
+	if !noHeaders {
+		fmt.Printf(PrintFmtStrStoragePoolWide, "STORAGEPOOL", "NODE", "DRIVE", "STATUS", "SCHEDULABLE", "TOTAL", "ALLOCATED", "FREE", "VOLUMES")
+	}
+	for _, p := range pools {
+		for _, d := range p.Drives {
+			fmt.Printf(PrintFmtStrStoragePoolWide, p.Pool, d.Node, d.Drive, d.Status, strconv.FormatBool(!d.Unschedulable),
+				formatBytes(d.Total), formatBytes(d.Allocated), formatBytes(d.Free), strconv.Itoa(d.Volumes))
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package cmd
+
+// pinnedToolChecksums are the sha256 digests of the release artifacts of the ToolManifest keyed by os/arch,
+// they are pinned by 'make tool-checksums' from the checksum files of the upstream releases
+var pinnedToolChecksums = map[string]map[string]string{
+	DefaultCMDHelm:     {},
+	DefaultCMDDirectPV: {},
+}
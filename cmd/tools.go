@@ -31,7 +31,13 @@ const (
    $ kubectl nine tools --command=uninstall --toolkit=superset,airflow --namespace=ns
 
 5. List tools
-   $ kubectl nine tools -c=list -n=ns`
+   $ kubectl nine tools -c=list -n=ns
+
+6. Install tools with custom values of the charts
+   $ kubectl nine tools -c=install -n=ns --values superset=superset.yaml --set airflow.workers.replicas=2
+
+7. Print the merged values of the tools without installing them
+   $ kubectl nine tools -c=install -n=ns --toolkit=superset --show-values`
 )
 
 var (
@@ -63,6 +69,10 @@ type toolsCmd struct {
 	airflowRepository   string
 	airflowTag          string
 	supersetSvcType     string
+	valuesArgs          []string
+	setArgs             []string
+	showValues          bool
+	values              *ChartValues
 }
 
 type DatabasesConnection struct {
@@ -114,6 +124,9 @@ func newToolsCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	f.BoolVar(&c.force, "force", false, "force to delete the ninecluster tools")
 	f.StringVarP(&c.chartPath, "chart-path", "p", "", "local path of the charts")
 	f.StringVarP(&c.ns, "namespace", "n", "", "k8s namespace for tools")
+	f.StringArrayVar(&c.valuesArgs, "values", nil, "values file of a tool chart,e.g. superset=superset.yaml")
+	f.StringArrayVar(&c.setArgs, "set", nil, "value of a tool chart,e.g. airflow.workers.replicas=2")
+	f.BoolVar(&c.showValues, "show-values", false, "only print the merged values of the tool charts")
 	f.BoolVar(&DEBUG, "debug", false, "print debug information")
 	return cmd
 }
@@ -141,6 +154,11 @@ func (t *toolsCmd) validate(args []string) error {
 			return errors.New(fmt.Sprintf("tools storage pool %s may be not exist", t.storagepool))
 		}
 	}
+	values, err := NewChartValues(t.valuesArgs, t.setArgs, t.showValues, DefaultToolsChartList)
+	if err != nil {
+		return err
+	}
+	t.values = values
 	return nil
 }
 
@@ -407,6 +425,9 @@ func (t *toolsCmd) createDatabase(name, user, pwd string) error {
 }
 
 func (t *toolsCmd) createToolDatabase(tool string) error {
+	if t.values.ShowOnly() {
+		return nil
+	}
 	var dbUser, dbName, dbPWD string
 	switch tool {
 	case DefaultToolAirflowName:
@@ -422,9 +443,22 @@ func (t *toolsCmd) createToolDatabase(tool string) error {
 	return t.createDatabase(dbName, dbUser, dbPWD)
 }
 
+// helmInstall installs the tool chart with the user values merged,only prints the values in the show values mode
+func (t *toolsCmd) helmInstall(relName string, tool string, parameters []string) error {
+	params, cleanup, err := t.values.Apply(tool, relName, parameters)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if t.values.ShowOnly() {
+		return nil
+	}
+	return HelmInstallWithParameters(relName, "", t.chartPath, tool, DefaultToolsChartList[tool], t.ns, params...)
+}
+
 func (t *toolsCmd) installRedis(parameters []string) error {
 	relName := NineResourceName(t.nineName, DefaultToolRedisName)
-	err := HelmInstallWithParameters(relName, "", t.chartPath, DefaultToolRedisName, DefaultToolsChartList[DefaultToolRedisName], t.ns, t.genRedisParameters(relName, parameters)...)
+	err := t.helmInstall(relName, DefaultToolRedisName, t.genRedisParameters(relName, parameters))
 	if err != nil {
 		return err
 	}
@@ -441,7 +475,7 @@ func (t *toolsCmd) installAirflow(parameters []string) error {
 		return err
 	}
 	relName := NineResourceName(t.nineName, DefaultToolAirflowName)
-	err = HelmInstallWithParameters(relName, "", t.chartPath, DefaultToolAirflowName, DefaultToolsChartList[DefaultToolAirflowName], t.ns, t.genAirflowParameters(relName, parameters)...)
+	err = t.helmInstall(relName, DefaultToolAirflowName, t.genAirflowParameters(relName, parameters))
 	if err != nil {
 		return err
 	}
@@ -458,7 +492,7 @@ func (t *toolsCmd) installSuperset(parameters []string) error {
 		return err
 	}
 	relName := NineResourceName(t.nineName, DefaultToolSupersetName)
-	err = HelmInstallWithParameters(relName, "", t.chartPath, DefaultToolSupersetName, DefaultToolsChartList[DefaultToolSupersetName], t.ns, t.genSupersetParameters(relName, parameters)...)
+	err = t.helmInstall(relName, DefaultToolSupersetName, t.genSupersetParameters(relName, parameters))
 	if err != nil {
 		return err
 	}
@@ -494,7 +528,7 @@ func (t *toolsCmd) installZookeeper(parameters []string) error {
 		return nil
 	}
 	relName := NineResourceName(t.nineName, DefaultToolZookeeperName)
-	err := HelmInstallWithParameters(relName, "", t.chartPath, DefaultToolZookeeperName, DefaultToolsChartList[DefaultToolZookeeperName], t.ns, t.genZookeeperParameters(relName, parameters)...)
+	err := t.helmInstall(relName, DefaultToolZookeeperName, t.genZookeeperParameters(relName, parameters))
 	if err != nil {
 		return err
 	}
@@ -509,7 +543,7 @@ func (t *toolsCmd) installNifi(parameters []string) error {
 		return err
 	}
 	relName := NineResourceName(t.nineName, DefaultToolNifiName)
-	err = HelmInstallWithParameters(relName, "", t.chartPath, DefaultToolNifiName, DefaultToolsChartList[DefaultToolNifiName], t.ns, t.genNifiParameters(relName, parameters)...)
+	err = t.helmInstall(relName, DefaultToolNifiName, t.genNifiParameters(relName, parameters))
 	if err != nil {
 		return err
 	}
@@ -522,9 +556,11 @@ func (t *toolsCmd) install(parameters []string) error {
 		return err
 	}
 	t.nineName = listClusters.Items[0].Name
-	err = t.createDatabase(DefaultNineInfraDBName, DefaultNineInfraDBUser, DefaultNineInfraDBPwd)
-	if err != nil {
-		return err
+	if !t.values.ShowOnly() {
+		err = t.createDatabase(DefaultNineInfraDBName, DefaultNineInfraDBUser, DefaultNineInfraDBPwd)
+		if err != nil {
+			return err
+		}
 	}
 
 	for _, v := range t.toolkitArgs {
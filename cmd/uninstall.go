@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
 	"github.com/spf13/cobra"
 	"io"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -11,19 +12,103 @@ import (
 	"k8s.io/klog/v2"
 	"os"
 	"strings"
+	"time"
 )
 
 const (
 	operatorUninstallDesc = `
  'uninstall' command deletes the NineInfra platform along with all the dependencies.`
-	operatorUninstallExample = `  kubectl nine uninstall`
+	operatorUninstallExample = `1. Uninstall the NineInfra
+   $ kubectl nine uninstall
+
+2. Show everything that would be removed by a full cleanup
+   $ kubectl nine uninstall --purge --dry-run
+
+3. Remove everything but keep the storage pools and the DirectPV drives
+   $ kubectl nine uninstall --purge --keep-storage`
+)
+
+const (
+	PrintFmtStrUninstallPlan = "%-16s\t%-32s\t%-60s\t%-10s\n"
+	DefaultUninstallTimeout  = 5 * time.Minute
 )
 
+// Categories of the uninstall plan,listed in the order they are removed
+const (
+	UninstallCategoryStorage  = "storage"
+	UninstallCategoryRelease  = "helm-release"
+	UninstallCategoryWebhook  = "webhook"
+	UninstallCategoryCrd      = "crd"
+	UninstallCategoryNS       = "namespace"
+	UninstallKindPV           = "persistentvolume"
+	UninstallKindDirectPVVol  = "directpvvolume"
+	UninstallKindDirectPVDrv  = "directpvdrive"
+	UninstallKindSC           = "storageclass"
+	UninstallKindRelease      = "release"
+	UninstallKindHelmRepo     = "helmrepo"
+	UninstallKindValidatingWH = "validatingwebhookconfiguration"
+	UninstallKindMutatingWH   = "mutatingwebhookconfiguration"
+	UninstallKindCrd          = "customresourcedefinition"
+	UninstallKindNS           = "namespace"
+)
+
+var uninstallCategoryOrder = []string{
+	UninstallCategoryStorage,
+	UninstallCategoryRelease,
+	UninstallCategoryWebhook,
+	UninstallCategoryCrd,
+	UninstallCategoryNS,
+}
+
 type operatorUninstallCmd struct {
-	out       io.Writer
-	errOut    io.Writer
-	output    bool
-	deleteCrd bool
+	out         io.Writer
+	errOut      io.Writer
+	output      bool
+	deleteCrd   bool
+	purge       bool
+	keepStorage bool
+	dryRun      bool
+	yes         bool
+	platformNS  string
+	timeout     time.Duration
+	// directpv volumes or drives in use are kept by the purge
+	storageInUse bool
+	// other NineInfra platforms sharing the crds and the storage with this one
+	otherPlatforms []string
+}
+
+// UninstallPlanItem is an object which will be removed by the uninstall
+type UninstallPlanItem struct {
+	Category  string
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// UninstallPlan holds all the objects which will be removed by the uninstall,grouped by category
+type UninstallPlan struct {
+	Items map[string][]UninstallPlanItem
+}
+
+func (p *UninstallPlan) add(category string, kind string, name string, namespace string) {
+	if p.Items == nil {
+		p.Items = make(map[string][]UninstallPlanItem)
+	}
+	p.Items[category] = append(p.Items[category], UninstallPlanItem{
+		Category:  category,
+		Kind:      kind,
+		Name:      name,
+		Namespace: namespace,
+	})
+}
+
+func (p *UninstallPlan) Print() {
+	fmt.Printf(PrintFmtStrUninstallPlan, "CATEGORY", "KIND", "NAME", "NAMESPACE")
+	for _, category := range uninstallCategoryOrder {
+		for _, item := range p.Items[category] {
+			fmt.Printf(PrintFmtStrUninstallPlan, item.Category, item.Kind, item.Name, item.Namespace)
+		}
+	}
 }
 
 func deleteCrd(crd string) error {
@@ -41,6 +126,17 @@ func deleteCrd(crd string) error {
 	return nil
 }
 
+func checkCrdExist(crd string) bool {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	c, err := GetKubeDynamicClient(path)
+	if err != nil {
+		return false
+	}
+	crdResource := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	_, err = c.Resource(crdResource).Get(context.TODO(), crd, metav1.GetOptions{})
+	return err == nil
+}
+
 func newUninstallCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	o := &operatorUninstallCmd{out: out, errOut: errOut}
 
@@ -63,47 +159,310 @@ func newUninstallCmd(out io.Writer, errOut io.Writer) *cobra.Command {
 	f := cmd.Flags()
 	f.BoolVar(&DEBUG, "debug", false, "print debug infomation")
 	f.BoolVar(&o.deleteCrd, "delete-crd", false, "delete crd")
+	f.BoolVar(&o.purge, "purge", false, "remove everything left by the NineInfra,including crds,webhooks,storage pools,directpv drives and volumes and the namespace")
+	f.BoolVar(&o.keepStorage, "keep-storage", false, "keep the storage pools,directpv drives and volumes and the released pvs when purging")
+	f.BoolVar(&o.dryRun, "dry-run", false, "only print the objects which would be removed")
+	f.BoolVarP(&o.yes, "yes", "y", false, "skip the confirmation prompts")
+	f.DurationVar(&o.timeout, "timeout", DefaultUninstallTimeout, "time to wait for each directpv volume and drive to be released when purging")
+	f.StringVar(&o.platformNS, "platform-namespace", "", "k8s namespace of the NineInfra platform,discovered automatically if not specified")
 	return cmd
 }
 
-// run deletes the Nineinfra to Kubernetes cluster.
-func (o *operatorUninstallCmd) run() error {
-	exist, cl := CheckNineClusterExist("", "")
-	if exist {
-		fmt.Printf("Error: NineClusters Exists! Please delete these NineClusters firstly!\n")
-		PrintClusterList(cl)
-		os.Exit(1)
+// planStorage collects the released pvs,the directpv volumes released or to be released by them and the drives
+// holding none of the other volumes.The storage classes are removed only if all the volumes and drives are removed
+func (o *operatorUninstallCmd) planStorage(plan *UninstallPlan) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	scList, err := client.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", DefaultDirectPVCreatedByLabelKey, DefaultDirectPVCreatedByValue),
+	})
+	if err != nil {
+		return err
+	}
+	releasedPVs := make(map[string]bool)
+	for _, sc := range scList.Items {
+		pvList, err := GetReleasedAndDeletePolicyPVListByStorageClass(client, sc.Name)
+		if err != nil {
+			return err
+		}
+		for _, pv := range pvList.Items {
+			releasedPVs[pv.Name] = true
+			plan.add(UninstallCategoryStorage, UninstallKindPV, pv.Name, "")
+		}
+	}
+
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return err
+	}
+	volumes, err := dpclient.DirectPVVolumes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	removedVolumes := make(map[string]bool)
+	if err == nil {
+		for _, v := range volumes.Items {
+			if !v.IsReleased() && !releasedPVs[v.Name] {
+				o.storageInUse = true
+				continue
+			}
+			removedVolumes[v.Name] = true
+			plan.add(UninstallCategoryStorage, UninstallKindDirectPVVol, v.Name, "")
+		}
+	}
+	drives, err := dpclient.DirectPVDrives().List(context.TODO(), metav1.ListOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		for _, d := range drives.Items {
+			if !driveVolumesRemoved(d.GetVolumes(), removedVolumes) {
+				o.storageInUse = true
+				continue
+			}
+			plan.add(UninstallCategoryStorage, UninstallKindDirectPVDrv, d.Name, "")
+		}
+	}
+
+	if o.storageInUse {
+		fmt.Println("The directpv volumes in use and their drives are kept,so are the storage classes and the directpv crds")
+		return nil
+	}
+	for _, sc := range scList.Items {
+		plan.add(UninstallCategoryStorage, UninstallKindSC, sc.Name, "")
+	}
+	return nil
+}
+
+// driveVolumesRemoved returns true if all the volumes of the drive are removed
+func driveVolumesRemoved(volumes []string, removed map[string]bool) bool {
+	for _, v := range volumes {
+		if !removed[v] {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *operatorUninstallCmd) planWebhooks(plan *UninstallPlan) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	vwcList, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, vwc := range vwcList.Items {
+		for _, wh := range vwc.Webhooks {
+			if wh.ClientConfig.Service != nil && wh.ClientConfig.Service.Namespace == o.platformNS {
+				plan.add(UninstallCategoryWebhook, UninstallKindValidatingWH, vwc.Name, "")
+				break
+			}
+		}
+	}
+	mwcList, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, mwc := range mwcList.Items {
+		for _, wh := range mwc.Webhooks {
+			if wh.ClientConfig.Service != nil && wh.ClientConfig.Service.Namespace == o.platformNS {
+				plan.add(UninstallCategoryWebhook, UninstallKindMutatingWH, mwc.Name, "")
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// buildPlan collects all the objects which will be removed
+func (o *operatorUninstallCmd) buildPlan() (*UninstallPlan, error) {
+	plan := &UninstallPlan{}
+	shared := len(o.otherPlatforms) != 0
+	if o.purge && !o.keepStorage && !shared {
+		if err := o.planStorage(plan); err != nil {
+			return nil, err
+		}
 	}
 
+	for _, c := range SortedKeys(DefaultChartList) {
+		if !o.purge || CheckHelmReleaseExist(c, o.platformNS) {
+			plan.add(UninstallCategoryRelease, UninstallKindRelease, c, o.platformNS)
+		}
+	}
+	if !shared {
+		plan.add(UninstallCategoryRelease, UninstallKindHelmRepo, DefaultHelmRepoName, "")
+	}
+
+	if o.purge {
+		if err := o.planWebhooks(plan); err != nil {
+			return nil, err
+		}
+	}
+	if (o.purge || o.deleteCrd) && !shared {
+		for _, crd := range NineInfraCrdList {
+			if (o.keepStorage || o.storageInUse) && strings.HasSuffix(crd, directpvv1beta1.Group) {
+				continue
+			}
+			if !o.purge || checkCrdExist(crd) {
+				plan.add(UninstallCategoryCrd, UninstallKindCrd, crd, "")
+			}
+		}
+	}
+	if o.purge {
+		plan.add(UninstallCategoryNS, UninstallKindNS, o.platformNS, "")
+	}
+	return plan, nil
+}
+
+// removeDirectPVVolume deletes the volume and waits for the directpv to release it from its drive,the finalizers
+// are kept for the directpv to clean up the data
+func (o *operatorUninstallCmd) removeDirectPVVolume(name string) error {
 	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return err
+	}
+	err = dpclient.DirectPVVolumes().Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return waitGone(func() error {
+		_, err := dpclient.DirectPVVolumes().Get(context.TODO(), name, metav1.GetOptions{})
+		return err
+	}, o.timeout)
+}
 
-	parameters := []string{}
-	if path != "" {
-		parameters = append(parameters, []string{"--kubeconfig", path}...)
+// removeDirectPVDrive deletes the drive and waits for the directpv to release it,the drive should hold no volumes
+func (o *operatorUninstallCmd) removeDirectPVDrive(name string) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return err
 	}
-	flags := strings.Join(parameters, " ")
-	for c := range DefaultChartList {
-		err := HelmUnInstall(c, DefaultNamespace, flags)
+	drive, err := dpclient.DirectPVDrives().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if volumes := drive.GetVolumes(); len(volumes) != 0 {
+		return fmt.Errorf("directpv drive %s still holds the volumes %s", name, strings.Join(volumes, ","))
+	}
+	err = dpclient.DirectPVDrives().Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return waitGone(func() error {
+		_, err := dpclient.DirectPVDrives().Get(context.TODO(), name, metav1.GetOptions{})
+		return err
+	}, o.timeout)
+}
+
+func (o *operatorUninstallCmd) removeItem(item UninstallPlanItem, flags string) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	switch item.Kind {
+	case UninstallKindPV:
+		err = client.CoreV1().PersistentVolumes().Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+	case UninstallKindDirectPVVol:
+		err = o.removeDirectPVVolume(item.Name)
+	case UninstallKindDirectPVDrv:
+		err = o.removeDirectPVDrive(item.Name)
+	case UninstallKindSC:
+		err = client.StorageV1().StorageClasses().Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+	case UninstallKindRelease:
+		return HelmUnInstall(item.Name, item.Namespace, flags)
+	case UninstallKindHelmRepo:
+		return RemoveHelmRepo(DefaultHelmRepo)
+	case UninstallKindValidatingWH:
+		err = client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+	case UninstallKindMutatingWH:
+		err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+	case UninstallKindCrd:
+		return deleteCrd(item.Name)
+	case UninstallKindNS:
+		err = client.CoreV1().Namespaces().Delete(context.TODO(), item.Name, metav1.DeleteOptions{})
+	}
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	fmt.Printf("Delete %s %s successfully!\n", item.Kind, item.Name)
+	return nil
+}
+
+// run deletes the Nineinfra to Kubernetes cluster.
+func (o *operatorUninstallCmd) run() error {
+	ns, err := ResolvePlatformNamespace(o.platformNS)
+	if err != nil {
+		return err
+	}
+	o.platformNS = ns
+	o.otherPlatforms, err = OtherPlatformNamespaces(o.platformNS)
+	if err != nil {
+		return err
+	}
+	if len(o.otherPlatforms) != 0 {
+		fmt.Printf("NineInfra platforms found in [%s],the crds,storage and helm repo shared with them are kept\n", strings.Join(o.otherPlatforms, ","))
+	}
+	// the NineClusters of the other platforms are kept,only the ones watched by this platform block the uninstall
+	watched := []string{""}
+	if len(o.otherPlatforms) != 0 {
+		namespaces, err := PlatformWatchNamespaces(o.platformNS)
 		if err != nil {
-			fmt.Printf("Error: %v \n", err)
 			return err
 		}
+		if namespaces != nil {
+			watched = namespaces
+		}
+	}
+	for _, ns := range watched {
+		exist, cl := CheckNineClusterExist("", ns)
+		if exist {
+			fmt.Printf("Error: NineClusters Exists! Please delete these NineClusters firstly!\n")
+			PrintClusterList(cl)
+			os.Exit(1)
+		}
 	}
 
-	if err := RemoveHelmRepo(DefaultHelmRepo); err != nil {
-		fmt.Printf("Error: %v \n", err)
+	plan, err := o.buildPlan()
+	if err != nil {
 		return err
 	}
+	if o.dryRun || o.purge {
+		plan.Print()
+	}
+	if o.dryRun {
+		return nil
+	}
+	if o.purge && !o.yes {
+		if !Ask("All the objects above will be removed, are you sure you want to continue") {
+			fmt.Println("Aborting NineInfra uninstallation")
+			return nil
+		}
+		if len(plan.Items[UninstallCategoryStorage]) != 0 &&
+			!Ask("The storage pools, directpv drives and volumes will be removed and this is irreversible, are you sure") {
+			fmt.Println("Aborting NineInfra uninstallation")
+			return nil
+		}
+	}
 
-	//if err := DeleteIfExist(DefaultNamespace, "namespace", flags); err != nil {
-	//	fmt.Printf("Error: %v \n", err)
-	//	os.Exit(1)
-	//}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
 
-	if o.deleteCrd {
-		for _, crd := range NineInfraCrdList {
-			err := deleteCrd(crd)
-			if err != nil {
+	parameters := []string{}
+	if path != "" {
+		parameters = append(parameters, []string{"--kubeconfig", path}...)
+	}
+	flags := strings.Join(parameters, " ")
+	for _, category := range uninstallCategoryOrder {
+		for _, item := range plan.Items[category] {
+			if err := o.removeItem(item, flags); err != nil {
 				fmt.Printf("Error: %v \n", err)
 				return err
 			}
@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+const (
+	updateDesc = `
+'update' command changes the features of an existing NineCluster.
+The storage pool of the metastore is not changed,the postgresql is deployed with the NineCluster and its volumes
+can not be moved in place.`
+	updateExample = `1. Add the olap to a NineCluster
+   $ kubectl nine update c1 --olap doris --olap-storage-pool nineinfra-high -n c1-ns
+
+2. Enable the kyuubi high availability and the postgresql cdc
+   $ kubectl nine update c1 --enable-kyuubi-ha --enable-postgresql-cdc -n c1-ns
+
+3. Show the diff without updating
+   $ kubectl nine update c1 --olap doris -n c1-ns --dry-run`
+)
+
+// updateFeatureFlags are the flags of the features could be updated
+var updateFeatureFlags = []string{"main-storage", "olap", "olap-volume", "storage-pool", "olap-storage-pool", "olap-executors",
+	"enable-kyuubi-ha", "enable-postgresql-cdc"}
+
+type updateCmd struct {
+	out         io.Writer
+	errOut      io.Writer
+	clusterOpts ClusterOptions
+	changed     map[string]bool
+	dryRun      bool
+	yes         bool
+}
+
+func newClusterUpdateCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &updateCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "update <NINECLUSTERNAME>",
+		Short:   "Update the features of a NineCluster",
+		Long:    updateDesc,
+		Example: updateExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			c.changed = make(map[string]bool)
+			for _, flag := range updateFeatureFlags {
+				c.changed[flag] = cmd.Flags().Changed(flag)
+			}
+			return c.validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.clusterOpts.NS, "namespace", "n", "", "namespace scope for this request")
+	f.StringVar(&c.clusterOpts.MainStorage, "main-storage", "", fmt.Sprintf("main storage for the ninecluster,support [%s]", strings.Join(MainStorageSupported, ",")))
+	f.StringVarP(&c.clusterOpts.Olap, "olap", "a", "", fmt.Sprintf("add olap to the ninecluster,support [%s]", OlapsSupported))
+	f.IntVar(&c.clusterOpts.OlapVolume, "olap-volume", 100, "olap storage volume size")
+	f.StringVarP(&c.clusterOpts.StoragePool, "storage-pool", "s", "", "storage pool for the ninecluster")
+	f.StringVarP(&c.clusterOpts.OlapStoragePool, "olap-storage-pool", "o", "", "storage pool for olap")
+	f.Int32VarP(&c.clusterOpts.OlapExecutors, "olap-executors", "r", 3, "num of the olap executors")
+	f.BoolVar(&c.clusterOpts.EnableKyuubiHA, "enable-kyuubi-ha", false, "enable kyuubi with high availability")
+	f.BoolVar(&c.clusterOpts.EnablePostgresqlCDC, "enable-postgresql-cdc", false, "enable the cdc of the postgresql")
+	f.BoolVar(&c.dryRun, "dry-run", false, "only print the diff of the NineCluster")
+	f.BoolVarP(&c.yes, "yes", "y", false, "skip the confirmation prompt")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	return cmd
+}
+
+func (c *updateCmd) validate(args []string) error {
+	if err := ValidateClusterArgs("update", args); err != nil {
+		return err
+	}
+	c.clusterOpts.Name = args[0]
+	for _, flag := range updateFeatureFlags {
+		if c.changed[flag] {
+			return nil
+		}
+	}
+	return errors.New("nothing to update,specify at least one feature to change")
+}
+
+// deployedStorageClass returns the storage class of the component if it has been deployed
+func deployedStorageClass(nc *nineinfrav1alpha1.NineCluster, clusterType nineinfrav1alpha1.ClusterType) (string, bool) {
+	deployed := false
+	switch clusterType {
+	case nineinfrav1alpha1.DorisBEClusterType, nineinfrav1alpha1.DorisFEClusterType:
+		_, deployed = nc.Spec.Features[FeaturesOlapKey]
+	case nineinfrav1alpha1.MinioClusterType:
+		deployed = nc.Spec.Features[FeaturesStorageKey] == "" || nc.Spec.Features[FeaturesStorageKey] == FeaturesStorageValueMinio
+	case nineinfrav1alpha1.DatabaseClusterType:
+		deployed = true
+	}
+	if !deployed {
+		return "", false
+	}
+	return clusterStorageClass(nc, clusterType), true
+}
+
+// desiredSpec returns the spec of the NineCluster with the changed features,
+// the changes the operator can not handle in place are refused
+func (c *updateCmd) desiredSpec(nc *nineinfrav1alpha1.NineCluster) (*nineinfrav1alpha1.NineClusterSpec, error) {
+	opts := c.clusterOpts
+	opts.DataVolume = nc.Spec.DataVolume
+	currentOlap, hasOlap := nc.Spec.Features[FeaturesOlapKey]
+	if !c.changed["olap"] && hasOlap && (c.changed["olap-volume"] || c.changed["olap-storage-pool"] || c.changed["olap-executors"]) {
+		opts.Olap = currentOlap
+	}
+	if (c.changed["olap-volume"] || c.changed["olap-storage-pool"] || c.changed["olap-executors"]) && opts.Olap == "" {
+		return nil, fmt.Errorf("NineCluster %s has no olap,add it with --olap", nc.Name)
+	}
+	if hasOlap {
+		// keep the olap storage unchanged unless specified
+		if i := FindClusterInfo(nc.Spec.ClusterSet, nineinfrav1alpha1.DorisBEClusterType); i >= 0 {
+			be := nc.Spec.ClusterSet[i]
+			if !c.changed["olap-storage-pool"] {
+				opts.OlapStoragePool = be.Resource.StorageClass
+			}
+			if q, ok := be.Resource.ResourceRequirements.Requests["storage"]; ok && !c.changed["olap-volume"] {
+				opts.OlapVolume = int(q.Value() / GiMultiplier)
+			}
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	currentStorage := nc.Spec.Features[FeaturesStorageKey]
+	if currentStorage == "" {
+		currentStorage = FeaturesStorageValueMinio
+	}
+	if opts.MainStorage != "" && !strings.EqualFold(opts.MainStorage, currentStorage) {
+		return nil, fmt.Errorf("switching the main storage from %s to %s is not supported in place", currentStorage, opts.MainStorage)
+	}
+	if hasOlap && opts.Olap != "" && opts.Olap != currentOlap {
+		return nil, fmt.Errorf("switching the olap from %s to %s is not supported in place", currentOlap, opts.Olap)
+	}
+
+	features, clusterSet, err := opts.GenFeaturesAndClusterSet()
+	if err != nil {
+		return nil, err
+	}
+	spec := nc.Spec.DeepCopy()
+	if spec.Features == nil {
+		spec.Features = make(map[string]string)
+	}
+	for k, v := range features {
+		spec.Features[k] = v
+	}
+	if c.changed["enable-kyuubi-ha"] && !opts.EnableKyuubiHA {
+		delete(spec.Features, FeaturesKyuubiHAKey)
+	}
+	if c.changed["enable-postgresql-cdc"] && !opts.EnablePostgresqlCDC {
+		delete(spec.Features, FeaturesPostgresqlCDCKey)
+	}
+
+	for _, ci := range clusterSet {
+		if sc, deployed := deployedStorageClass(nc, ci.Type); deployed {
+			desiredSC := ci.Resource.StorageClass
+			if desiredSC == "" {
+				desiredSC = DefaultStorageClass
+			}
+			if desiredSC != sc {
+				return nil, fmt.Errorf("changing the storage pool of %s from %s to %s is not supported in place", ci.Type, sc, desiredSC)
+			}
+			i := FindClusterInfo(spec.ClusterSet, ci.Type)
+			if i < 0 {
+				continue
+			}
+			current, ok := spec.ClusterSet[i].Resource.ResourceRequirements.Requests["storage"]
+			desired, desiredOk := ci.Resource.ResourceRequirements.Requests["storage"]
+			if ok && desiredOk && !current.Equal(desired) {
+				return nil, fmt.Errorf("changing the volume of %s from %s to %s is not supported in place", ci.Type, current.String(), desired.String())
+			}
+			if ci.Type == nineinfrav1alpha1.DorisBEClusterType && c.changed["olap-executors"] {
+				spec.ClusterSet[i].Resource.Replicas = ci.Resource.Replicas
+			}
+			continue
+		}
+		if i := FindClusterInfo(spec.ClusterSet, ci.Type); i >= 0 {
+			spec.ClusterSet[i] = ci
+		} else {
+			spec.ClusterSet = append(spec.ClusterSet, ci)
+		}
+	}
+	return spec, nil
+}
+
+// specMergePatch returns the merge patch from the current spec to the desired spec,
+// the removed features are set to null
+func specMergePatch(current *nineinfrav1alpha1.NineClusterSpec, desired *nineinfrav1alpha1.NineClusterSpec) ([]byte, error) {
+	features := make(map[string]interface{})
+	for k, v := range desired.Features {
+		features[k] = v
+	}
+	for k := range current.Features {
+		if _, ok := desired.Features[k]; !ok {
+			features[k] = nil
+		}
+	}
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"features":   features,
+			"clusterSet": desired.ClusterSet,
+		},
+	}
+	return json.Marshal(patch)
+}
+
+// PrintSpecDiff prints the diff between the specs of the NineCluster
+func PrintSpecDiff(current *nineinfrav1alpha1.NineClusterSpec, desired *nineinfrav1alpha1.NineClusterSpec) (bool, error) {
+	before, err := yaml.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	after, err := yaml.Marshal(desired)
+	if err != nil {
+		return false, err
+	}
+	if string(before) == string(after) {
+		return false, nil
+	}
+	for _, line := range DiffLines(string(before), string(after)) {
+		fmt.Println(line)
+	}
+	return true, nil
+}
+
+// run updates the features of the NineCluster by a merge patch
+func (c *updateCmd) run() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	nc, err := client.NineinfraV1alpha1().NineClusters(c.clusterOpts.NS).Get(context.TODO(), c.clusterOpts.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	desired, err := c.desiredSpec(nc)
+	if err != nil {
+		return err
+	}
+	changed, err := PrintSpecDiff(&nc.Spec, desired)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Println("NineCluster:" + c.clusterOpts.Name + " in namespace:" + c.clusterOpts.NS + " is already up to date")
+		return nil
+	}
+	if c.dryRun {
+		return nil
+	}
+	if !c.yes && !Ask("The NineCluster will be updated as above, are you sure you want to continue") {
+		return errors.New("aborting NineCluster update")
+	}
+
+	patch, err := specMergePatch(&nc.Spec, desired)
+	if err != nil {
+		return err
+	}
+	if DEBUG {
+		fmt.Printf("Patch the ninecluster with:%s\n", string(patch))
+	}
+	result, err := client.NineinfraV1alpha1().NineClusters(c.clusterOpts.NS).Patch(context.TODO(), c.clusterOpts.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	RecordRevision(result, &nc.Spec, "update")
+
+	fmt.Println("NineCluster:" + c.clusterOpts.Name + " in namespace:" + c.clusterOpts.NS + " is updated successfully!")
+	fmt.Println("It may take a few minutes for the changes to be ready")
+	fmt.Println("You can check its status using the following command：")
+	fmt.Println("kubectl nine show " + c.clusterOpts.Name + " -n " + c.clusterOpts.NS)
+	return nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"k8s.io/klog/v2"
+)
+
+const (
+	operatorUpgradeDesc = `
+ 'upgrade' command upgrades the charts of the NineInfra platform to the versions supported by this nine.`
+	operatorUpgradeExample = `1. Upgrade the NineInfra
+   $ kubectl nine upgrade
+
+2. Upgrade the NineInfra platform in a specified namespace
+   $ kubectl nine upgrade --platform-namespace nineinfra-staging
+
+3. Upgrade the NineInfra with custom values of the charts
+   $ kubectl nine upgrade --values doris-operator=doris.yaml --set nineinfra.replicaCount=2`
+)
+
+type operatorUpgradeCmd struct {
+	out        io.Writer
+	errOut     io.Writer
+	chartPath  string
+	platformNS string
+	valuesArgs []string
+	setArgs    []string
+	showValues bool
+	values     *ChartValues
+}
+
+func newUpgradeCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	o := &operatorUpgradeCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "upgrade",
+		Short:   "Upgrade the NineInfra",
+		Long:    operatorUpgradeDesc,
+		Example: operatorUpgradeExample,
+		Args:    cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values, err := NewChartValues(o.valuesArgs, o.setArgs, o.showValues, DefaultChartList)
+			if err != nil {
+				return err
+			}
+			o.values = values
+			err = o.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	f.StringVarP(&o.chartPath, "chart-path", "p", "", "local path of the charts")
+	f.StringVar(&o.platformNS, "platform-namespace", "", "k8s namespace of the NineInfra platform,discovered automatically if not specified")
+	f.StringArrayVar(&o.valuesArgs, "values", nil, "values file of a chart,e.g. doris-operator=doris.yaml")
+	f.StringArrayVar(&o.setArgs, "set", nil, "value of a chart,e.g. nineinfra.replicaCount=2")
+	f.BoolVar(&o.showValues, "show-values", false, "only print the merged values of the charts")
+	return cmd
+}
+
+// run upgrades the charts of the Nineinfra in the Kubernetes cluster.
+func (o *operatorUpgradeCmd) run() error {
+	if o.values.ShowOnly() {
+		for _, c := range SortedKeys(DefaultChartList) {
+			if _, _, err := o.values.Apply(c, c, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ns, err := ResolvePlatformNamespace(o.platformNS)
+	if err != nil {
+		return err
+	}
+	o.platformNS = ns
+
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	var parameters []string
+	if path != "" {
+		parameters = append(parameters, []string{"--kubeconfig", path}...)
+	}
+
+	if err := InitHelm(); err != nil {
+		return err
+	}
+	if o.chartPath == "" {
+		if _, _, err := runCommand("helm", "repo", "update", DefaultHelmRepoName); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range SortedKeys(DefaultChartList) {
+		if !CheckHelmReleaseExist(c, o.platformNS) {
+			continue
+		}
+		params, cleanup, err := o.values.Apply(c, c, parameters)
+		if err != nil {
+			return err
+		}
+		err = HelmUpgradeChart(c, o.chartPath, c, DefaultChartList[c], o.platformNS, params...)
+		cleanup()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := MarkPlatformNamespace(o.platformNS); err != nil {
+		return err
+	}
+
+	fmt.Println("NineInfra in namespace:" + o.platformNS + " is upgraded successfully!")
+	fmt.Println("You can check its status using the following command")
+	fmt.Println("kubectl nine status --platform-namespace " + o.platformNS)
+	return nil
+}
@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	cnpgv1 "github.com/cloudnative-pg/cloudnative-pg/api/v1"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+const (
+	upgradeClusterDesc = `
+'upgrade-cluster' command upgrades the version of a component of a NineCluster.`
+	upgradeClusterExample = `1. Upgrade the doris of a NineCluster
+   $ kubectl nine upgrade-cluster c1 --component doris --version 2.1.0 -n c1-ns
+
+2. Show the plan without upgrading
+   $ kubectl nine upgrade-cluster c1 --component minio --version RELEASE.2023-11-20T22-40-07Z -n c1-ns --dry-run`
+
+	PrintFmtStrUpgradeClusterPlan = "%-12s\t%-30s\t%-30s\n"
+
+	DefaultUpgradeClusterTimeout = 15 * time.Minute
+)
+
+// ComponentWorkload is a workload of a component of the NineCluster
+type ComponentWorkload struct {
+	Type           nineinfrav1alpha1.ClusterType
+	Project        string
+	DefaultVersion string
+}
+
+// NineClusterComponentList are the workloads of the components could be upgraded,in the upgrade order
+var NineClusterComponentList = map[string][]ComponentWorkload{
+	"doris": {
+		{Type: nineinfrav1alpha1.DorisBEClusterType, Project: "doris-be", DefaultVersion: DefaultDorisBEVersion},
+		{Type: nineinfrav1alpha1.DorisFEClusterType, Project: "doris-fe", DefaultVersion: DefaultDorisFEVersion},
+	},
+	"minio": {
+		{Type: nineinfrav1alpha1.MinioClusterType, Project: "minio", DefaultVersion: DefaultMinioVersion},
+	},
+	"postgresql": {
+		{Type: nineinfrav1alpha1.DatabaseClusterType, Project: "postgresql", DefaultVersion: DefaultDataBaseVersion},
+	},
+}
+
+var componentsSupported = "doris,minio,postgresql"
+
+// the waiting reasons of the containers which will never be ready without changing the spec
+var rolloutFailedReasons = []string{"ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CrashLoopBackOff"}
+
+type upgradeClusterCmd struct {
+	out       io.Writer
+	errOut    io.Writer
+	name      string
+	ns        string
+	component string
+	version   string
+	dryRun    bool
+	yes       bool
+	timeout   time.Duration
+}
+
+func newClusterUpgradeCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &upgradeClusterCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "upgrade-cluster <NINECLUSTERNAME>",
+		Short:   "Upgrade a component of a NineCluster",
+		Long:    upgradeClusterDesc,
+		Example: upgradeClusterExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return c.validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace scope for this request")
+	f.StringVar(&c.component, "component", "", fmt.Sprintf("component to upgrade,support [%s]", componentsSupported))
+	f.StringVar(&c.version, "version", "", "version to upgrade the component to")
+	f.BoolVar(&c.dryRun, "dry-run", false, "only print the plan of the upgrade")
+	f.BoolVarP(&c.yes, "yes", "y", false, "skip the confirmation prompt")
+	f.DurationVar(&c.timeout, "timeout", DefaultUpgradeClusterTimeout, "time to wait for the rolling update")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	cmd.MarkFlagRequired("namespace")
+	cmd.MarkFlagRequired("component")
+	cmd.MarkFlagRequired("version")
+	return cmd
+}
+
+func (c *upgradeClusterCmd) validate(args []string) error {
+	if err := ValidateClusterArgs("upgrade-cluster", args); err != nil {
+		return err
+	}
+	c.name = args[0]
+	if _, ok := NineClusterComponentList[c.component]; !ok {
+		return fmt.Errorf("unsupported component %s,support [%s]", c.component, componentsSupported)
+	}
+	return nil
+}
+
+func versionIndex(versions []string, version string) int {
+	for i, v := range versions {
+		if strings.TrimPrefix(v, "v") == strings.TrimPrefix(version, "v") {
+			return i
+		}
+	}
+	return -1
+}
+
+func majorVersion(version string) string {
+	return strings.SplitN(strings.TrimPrefix(version, "v"), ".", 2)[0]
+}
+
+// CheckComponentUpgrade checks the component can be upgraded from the current version to the target version
+func CheckComponentUpgrade(component string, current string, target string) error {
+	versions := NineClusterComponentVersions[component]
+	to := versionIndex(versions, target)
+	if to < 0 {
+		return fmt.Errorf("version %s of %s is not supported,support [%s]", target, component, strings.Join(versions, ","))
+	}
+	from := versionIndex(versions, current)
+	if from < 0 {
+		return fmt.Errorf("the current version %s of %s is unknown,can not upgrade it", current, component)
+	}
+	if to == from {
+		return fmt.Errorf("%s is already at version %s", component, current)
+	}
+	if to < from {
+		return fmt.Errorf("downgrading %s from %s to %s is not supported", component, current, target)
+	}
+	if component == "postgresql" && majorVersion(current) != majorVersion(target) {
+		return fmt.Errorf("upgrading the major version of %s from %s to %s is not supported in place", component, current, target)
+	}
+	return nil
+}
+
+// componentVersion returns the version of the workload of the NineCluster
+func componentVersion(nc *nineinfrav1alpha1.NineCluster, workload ComponentWorkload) string {
+	if i := FindClusterInfo(nc.Spec.ClusterSet, workload.Type); i >= 0 && nc.Spec.ClusterSet[i].Version != "" {
+		return nc.Spec.ClusterSet[i].Version
+	}
+	return workload.DefaultVersion
+}
+
+// RolloutBaseline is the state of a workload before the upgrade,the generation of the statefulset or the image of
+// the postgresql cluster
+type RolloutBaseline struct {
+	Generation int64
+	Image      string
+}
+
+// workloadSelector returns the label selector of the pods of the workload
+func workloadSelector(name string, namespace string, workload ComponentWorkload) (string, error) {
+	workloadName := NineWorkLoadName(name, workload.Project)
+	if NineClusterProjectWorkloadList[workload.Project] == "cluster" {
+		return "cnpg.io/cluster=" + workloadName, nil
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return "", err
+	}
+	sts, err := client.AppsV1().StatefulSets(namespace).Get(context.TODO(), workloadName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return metav1.FormatLabelSelector(sts.Spec.Selector), nil
+}
+
+// podFailedReason returns the reason if the pod will never be ready without changing the spec
+func podFailedReason(pod corev1.Pod) string {
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+		for _, reason := range rolloutFailedReasons {
+			if cs.State.Waiting.Reason == reason {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// stsRolledOut returns whether the statefulset is changed after the generation and all its replicas are updated
+// and ready
+func stsRolledOut(sts *appsv1.StatefulSet, generation int64) bool {
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+	return sts.Generation > generation && sts.Status.ObservedGeneration >= sts.Generation &&
+		sts.Status.UpdateRevision != "" && sts.Status.CurrentRevision == sts.Status.UpdateRevision &&
+		sts.Status.UpdatedReplicas == replicas && sts.Status.ReadyReplicas == replicas
+}
+
+// pgRolledOut returns whether the image of the postgresql cluster is changed from the image,the cluster is healthy
+// and the primary and the other instances run the new image
+func pgRolledOut(pg *cnpgv1.Cluster, image string, pods []corev1.Pod) bool {
+	if pg.Spec.ImageName == "" || pg.Spec.ImageName == image || pg.Status.Phase != cnpgv1.PhaseHealthy || !IfPGReady(pg) ||
+		pg.Status.CurrentPrimary == "" || pg.Status.CurrentPrimary != pg.Status.TargetPrimary {
+		return false
+	}
+	primary := false
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if container.Name == "postgres" && container.Image != pg.Spec.ImageName {
+				return false
+			}
+		}
+		primary = primary || pod.Name == pg.Status.CurrentPrimary
+	}
+	return primary
+}
+
+// CaptureRollout returns the state of the workloads before the upgrade
+func CaptureRollout(name string, namespace string, workloads []ComponentWorkload) (map[string]RolloutBaseline, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	pgClient, err := GetPGOperatorClient(path)
+	if err != nil {
+		return nil, err
+	}
+	baselines := make(map[string]RolloutBaseline)
+	for _, workload := range workloads {
+		workloadName := NineWorkLoadName(name, workload.Project)
+		if NineClusterProjectWorkloadList[workload.Project] == "cluster" {
+			pg, err := pgClient.PostgresqlV1().Clusters(namespace).Get(context.TODO(), workloadName, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			baselines[workload.Project] = RolloutBaseline{Image: pg.Spec.ImageName}
+			continue
+		}
+		sts, err := client.AppsV1().StatefulSets(namespace).Get(context.TODO(), workloadName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		baselines[workload.Project] = RolloutBaseline{Generation: sts.Generation}
+	}
+	return baselines, nil
+}
+
+// workloadRollout returns whether the workload is rolled out from the baseline and its progress
+func workloadRollout(name string, namespace string, workload ComponentWorkload, baseline RolloutBaseline, pods []corev1.Pod) (bool, string, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	workloadName := NineWorkLoadName(name, workload.Project)
+	if NineClusterProjectWorkloadList[workload.Project] == "cluster" {
+		pgClient, err := GetPGOperatorClient(path)
+		if err != nil {
+			return false, "", err
+		}
+		pg, err := pgClient.PostgresqlV1().Clusters(namespace).Get(context.TODO(), workloadName, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return pgRolledOut(pg, baseline.Image, pods), fmt.Sprintf("image:%s phase:%s ready:%d/%d", pg.Spec.ImageName,
+			pg.Status.Phase, pg.Status.ReadyInstances, pg.Spec.Instances), nil
+	}
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return false, "", err
+	}
+	sts, err := client.AppsV1().StatefulSets(namespace).Get(context.TODO(), workloadName, metav1.GetOptions{})
+	if err != nil {
+		return false, "", err
+	}
+	return stsRolledOut(sts, baseline.Generation), fmt.Sprintf("generation:%d observed:%d updated:%d ready:%d",
+		sts.Generation, sts.Status.ObservedGeneration, sts.Status.UpdatedReplicas, sts.Status.ReadyReplicas), nil
+}
+
+// WatchRollout waits for the operators to roll the workloads out from the baselines,the progress of the workloads
+// is printed and the pods which will never be ready fail the rollout
+func WatchRollout(name string, namespace string, workloads []ComponentWorkload, baselines map[string]RolloutBaseline, timeout time.Duration) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	progress := make(map[string]string)
+	for {
+		done := true
+		for _, workload := range workloads {
+			selector, err := workloadSelector(name, namespace, workload)
+			if err != nil {
+				return err
+			}
+			pods, err := client.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return err
+			}
+			for _, pod := range pods.Items {
+				if reason := podFailedReason(pod); reason != "" {
+					return fmt.Errorf("pod %s of %s failed to roll with %s", pod.Name, workload.Project, reason)
+				}
+			}
+			rolledOut, state, err := workloadRollout(name, namespace, workload, baselines[workload.Project], pods.Items)
+			if err != nil {
+				return err
+			}
+			if progress[workload.Project] != state {
+				progress[workload.Project] = state
+				fmt.Printf("%s %s %s\n", time.Now().Format(time.TimeOnly), workload.Project, state)
+			}
+			done = done && rolledOut
+		}
+		if done {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the rolling update is not finished in %s", timeout)
+		}
+		time.Sleep(3 * time.Second)
+	}
+}
+
+// revert restores the spec of the NineCluster
+func (c *upgradeClusterCmd) revert(spec *nineinfrav1alpha1.NineClusterSpec) error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	nc, err := client.NineinfraV1alpha1().NineClusters(c.ns).Get(context.TODO(), c.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	current := nc.Spec.DeepCopy()
+	nc.Spec = *spec
+	result, err := client.NineinfraV1alpha1().NineClusters(c.ns).Update(context.TODO(), nc, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	RecordRevision(result, current, "upgrade-cluster")
+	return nil
+}
+
+// run upgrades the component of the NineCluster and reverts it if the rolling update fails
+func (c *upgradeClusterCmd) run() error {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return err
+	}
+	nc, err := client.NineinfraV1alpha1().NineClusters(c.ns).Get(context.TODO(), c.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if c.component == "doris" {
+		if _, ok := nc.Spec.Features[FeaturesOlapKey]; !ok {
+			return fmt.Errorf("NineCluster %s has no olap", c.name)
+		}
+	}
+	if c.component == "minio" && nc.Spec.Features[FeaturesStorageKey] == FeaturesStorageValueHdfs {
+		return fmt.Errorf("the main storage of NineCluster %s is hdfs", c.name)
+	}
+
+	previous := nc.Spec.DeepCopy()
+	workloads := NineClusterComponentList[c.component]
+	fmt.Printf(PrintFmtStrUpgradeClusterPlan, "COMPONENT", "FROM", "TO")
+	for _, workload := range workloads {
+		current := componentVersion(nc, workload)
+		if err := CheckComponentUpgrade(c.component, current, c.version); err != nil {
+			return err
+		}
+		fmt.Printf(PrintFmtStrUpgradeClusterPlan, workload.Type, current, c.version)
+		i := EnsureClusterInfo(nc, workload.Type)
+		nc.Spec.ClusterSet[i].Version = c.version
+		// the image of the component without a repository is chosen by the operator
+		if nc.Spec.ClusterSet[i].Configs.Image.Repository != "" {
+			nc.Spec.ClusterSet[i].Configs.Image.Tag = c.version
+		}
+	}
+	if c.dryRun {
+		return nil
+	}
+	if !c.yes && !Ask("The NineCluster will be upgraded as above, are you sure you want to continue") {
+		return errors.New("aborting NineCluster upgrade")
+	}
+
+	baselines, err := CaptureRollout(c.name, c.ns, workloads)
+	if err != nil {
+		return err
+	}
+	result, err := client.NineinfraV1alpha1().NineClusters(c.ns).Update(context.TODO(), nc, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	RecordRevision(result, previous, "upgrade-cluster")
+	fmt.Printf("Upgrading %s of NineCluster:%s in namespace:%s to %s\n", c.component, c.name, c.ns, c.version)
+
+	if err := WatchRollout(c.name, c.ns, workloads, baselines, c.timeout); err != nil {
+		fmt.Printf("Error: %v,reverting NineCluster:%s to the previous spec\n", err, c.name)
+		if rerr := c.revert(previous); rerr != nil {
+			return fmt.Errorf("%v,and failed to revert:%v", err, rerr)
+		}
+		return fmt.Errorf("upgrading %s failed and NineCluster:%s is reverted:%v", c.component, c.name, err)
+	}
+
+	fmt.Printf("Upgrade %s of NineCluster:%s to %s successfully!\n", c.component, c.name, c.version)
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"testing"
+
+	cnpgv1 "github.com/cloudnative-pg/cloudnative-pg/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStsRolledOut(t *testing.T) {
+	sts := func(generation int64, observed int64, current string, update string, updated int32, ready int32) *appsv1.StatefulSet {
+		replicas := int32(3)
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Generation: generation},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status: appsv1.StatefulSetStatus{ObservedGeneration: observed, CurrentRevision: current, UpdateRevision: update,
+				UpdatedReplicas: updated, ReadyReplicas: ready},
+		}
+	}
+	tests := []struct {
+		name string
+		sts  *appsv1.StatefulSet
+		want bool
+	}{
+		{"not changed by the operator", sts(1, 1, "r1", "r1", 3, 3), false},
+		{"not observed", sts(2, 1, "r1", "r1", 3, 3), false},
+		{"rolling", sts(2, 2, "r1", "r2", 1, 3), false},
+		{"updated but not ready", sts(2, 2, "r2", "r2", 3, 2), false},
+		{"rolled out", sts(2, 2, "r2", "r2", 3, 3), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stsRolledOut(tt.sts, 1); got != tt.want {
+				t.Errorf("stsRolledOut() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPgRolledOut(t *testing.T) {
+	const oldImage, newImage = "postgresql:16.0", "postgresql:16.1"
+	pg := func(image string, phase string, ready int, primary string, target string) *cnpgv1.Cluster {
+		return &cnpgv1.Cluster{
+			Spec:   cnpgv1.ClusterSpec{ImageName: image, Instances: 2},
+			Status: cnpgv1.ClusterStatus{Phase: phase, ReadyInstances: ready, CurrentPrimary: primary, TargetPrimary: target},
+		}
+	}
+	pod := func(name string, image string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "postgres", Image: image}}},
+		}
+	}
+	tests := []struct {
+		name string
+		pg   *cnpgv1.Cluster
+		pods []corev1.Pod
+		want bool
+	}{
+		{"image not changed", pg(oldImage, cnpgv1.PhaseHealthy, 2, "pg-1", "pg-1"),
+			[]corev1.Pod{pod("pg-1", oldImage), pod("pg-2", oldImage)}, false},
+		{"upgrading", pg(newImage, "Upgrading cluster", 1, "pg-1", "pg-1"),
+			[]corev1.Pod{pod("pg-1", oldImage), pod("pg-2", newImage)}, false},
+		{"switching over", pg(newImage, cnpgv1.PhaseHealthy, 2, "pg-1", "pg-2"),
+			[]corev1.Pod{pod("pg-1", newImage), pod("pg-2", newImage)}, false},
+		{"primary on the old image", pg(newImage, cnpgv1.PhaseHealthy, 2, "pg-1", "pg-1"),
+			[]corev1.Pod{pod("pg-1", oldImage), pod("pg-2", newImage)}, false},
+		{"primary pod missing", pg(newImage, cnpgv1.PhaseHealthy, 2, "pg-3", "pg-3"),
+			[]corev1.Pod{pod("pg-1", newImage), pod("pg-2", newImage)}, false},
+		{"rolled out", pg(newImage, cnpgv1.PhaseHealthy, 2, "pg-2", "pg-2"),
+			[]corev1.Pod{pod("pg-1", newImage), pod("pg-2", newImage)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pgRolledOut(tt.pg, oldImage, tt.pods); got != tt.want {
+				t.Errorf("pgRolledOut() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	directpvv1beta1 "github.com/minio/directpv/apis/directpv.min.io/v1beta1"
+	nineinfrav1alpha1 "github.com/nineinfra/nineinfra/api/v1alpha1"
+	"github.com/spf13/cobra"
+	"io"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	usageDesc = `
+'usage' command reports the storage consumption of the NineClusters per component,the capacity requested by the
+pvcs,the bytes used and available in the directpv volumes,and the totals of the directpv storage pools holding
+them.The components above the threshold are flagged.`
+	usageExample = `1. Report the storage usage of a NineCluster
+   $ kubectl nine usage c1 -n c1-ns
+
+2. Report the storage usage of all the NineClusters
+   $ kubectl nine usage
+
+3. Report the storage usage in csv and flag the components above 90% full
+   $ kubectl nine usage -n c1-ns --output csv --threshold 90`
+)
+
+const (
+	OutputCsv                   = "csv"
+	OutputTable                 = "table"
+	DefaultUsageThreshold       = 80
+	UsageComponentOther         = "other"
+	PrintFmtStrComponentUsage   = "%-20s\t%-15s\t%-15s\t%-8s\t%-10s\t%-10s\t%-10s\t%-8s\t%-20s\t%-s\n"
+	PrintFmtStrStoragePoolUsage = "%-20s\t%-8s\t%-10s\t%-10s\t%-10s\t%-8s\n"
+)
+
+type usageCmd struct {
+	out       io.Writer
+	errOut    io.Writer
+	name      string
+	ns        string
+	output    string
+	threshold float64
+}
+
+// ComponentUsage is the storage usage of a component of a NineCluster
+type ComponentUsage struct {
+	Cluster        string   `json:"cluster"`
+	Namespace      string   `json:"namespace"`
+	Component      string   `json:"component"`
+	Volumes        int      `json:"volumes"`
+	Requested      int64    `json:"requested"`
+	Used           int64    `json:"used"`
+	Available      int64    `json:"available"`
+	UsedPercent    float64  `json:"usedPercent"`
+	AboveThreshold bool     `json:"aboveThreshold"`
+	Pools          []string `json:"pools"`
+}
+
+// StoragePoolUsage is the capacity of the directpv drives of a storage pool
+type StoragePoolUsage struct {
+	Pool             string  `json:"pool"`
+	Drives           int     `json:"drives"`
+	Total            int64   `json:"total"`
+	Allocated        int64   `json:"allocated"`
+	Free             int64   `json:"free"`
+	AllocatedPercent float64 `json:"allocatedPercent"`
+}
+
+// UsageReport is the storage usage of the NineClusters and their storage pools
+type UsageReport struct {
+	Threshold  float64            `json:"threshold"`
+	Components []ComponentUsage   `json:"components"`
+	Pools      []StoragePoolUsage `json:"pools"`
+}
+
+func newClusterUsageCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &usageCmd{out: out, errOut: errOut}
+
+	cmd := &cobra.Command{
+		Use:     "usage [<NINECLUSTERNAME>]",
+		Short:   "Report the storage usage of NineClusters",
+		Long:    usageDesc,
+		Example: usageExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			return c.validate(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.run()
+			if err != nil {
+				klog.Warning(err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd = DisableHelp(cmd)
+	f := cmd.Flags()
+	f.StringVarP(&c.ns, "namespace", "n", "", "namespace scope for this request,all namespaces if not specified without a NineCluster")
+	f.StringVar(&c.output, "output", OutputTable, "output format of the report,support [table,json,csv]")
+	f.Float64Var(&c.threshold, "threshold", DefaultUsageThreshold, "percentage of the used capacity above which a component is flagged")
+	f.BoolVar(&DEBUG, "debug", false, "print debug information")
+	return cmd
+}
+
+func (c *usageCmd) validate(args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage command takes one NineCluster at most")
+	}
+	if len(args) == 1 {
+		if err := ValidateClusterArgs("usage", args); err != nil {
+			return err
+		}
+		c.name = args[0]
+		if c.ns == "" {
+			return errors.New("--namespace is required with a NineCluster")
+		}
+	}
+	switch c.output {
+	case OutputTable, OutputJson, OutputCsv:
+	default:
+		return errors.New("output format " + c.output + " is not supported,support [table,json,csv]")
+	}
+	if c.threshold <= 0 || c.threshold > 100 {
+		return errors.New("--threshold should be in (0,100]")
+	}
+	return nil
+}
+
+// usageComponent returns the component of the NineCluster the pod belongs to,the longest matched workload name wins
+func usageComponent(name string, podName string) string {
+	component, matched := UsageComponentOther, ""
+	candidates := make(map[string]string)
+	for k, suffix := range NineClusterProjectNameSuffix {
+		candidates[name+suffix] = k
+	}
+	for tool := range NineToolList {
+		candidates[NineResourceName(name, tool)] = tool
+	}
+	for prefix, k := range candidates {
+		if strings.HasPrefix(podName, prefix) && len(prefix) > len(matched) {
+			component, matched = k, prefix
+		}
+	}
+	return component
+}
+
+// usagePercent returns the percentage of the part in the whole,0 if the whole is empty
+func usagePercent(part int64, whole int64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(whole)
+}
+
+func (c *usageCmd) nineClusters() ([]nineinfrav1alpha1.NineCluster, error) {
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetNineInfraClient(path)
+	if err != nil {
+		return nil, err
+	}
+	if c.name != "" {
+		nc, err := client.NineinfraV1alpha1().NineClusters(c.ns).Get(context.TODO(), c.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return []nineinfrav1alpha1.NineCluster{*nc}, nil
+	}
+	clusters, err := client.NineinfraV1alpha1().NineClusters(c.ns).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return clusters.Items, nil
+}
+
+// Report collects the storage usage of the NineClusters from their directpv volumes,the requested capacity is
+// taken from the pvcs bound to the volumes
+func (c *usageCmd) Report() (*UsageReport, error) {
+	clusters, err := c.nineClusters()
+	if err != nil {
+		return nil, err
+	}
+	path, _ := rootCmd.Flags().GetString(kubeconfig)
+	client, err := GetKubeClient(path)
+	if err != nil {
+		return nil, err
+	}
+	dpclient, err := GetDirectPVClient(path)
+	if err != nil {
+		return nil, err
+	}
+	drives, err := dpclient.DirectPVDrives().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	drivePools := make(map[string]string)
+	for _, drive := range drives.Items {
+		drivePools[drive.Name] = drive.Labels[DefaultStoragePoolLabelKey]
+	}
+
+	report := &UsageReport{Threshold: c.threshold, Components: make([]ComponentUsage, 0), Pools: make([]StoragePoolUsage, 0)}
+	usedPools := make(map[string]bool)
+	requests := make(map[string]map[string]int64)
+	for _, nc := range clusters {
+		if _, ok := requests[nc.Namespace]; !ok {
+			pvcs, err := client.CoreV1().PersistentVolumeClaims(nc.Namespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			requests[nc.Namespace] = make(map[string]int64)
+			for _, pvc := range pvcs.Items {
+				if q, ok := pvc.Spec.Resources.Requests["storage"]; ok && pvc.Spec.VolumeName != "" {
+					requests[nc.Namespace][pvc.Spec.VolumeName] = q.Value()
+				}
+			}
+		}
+		volumes, err := GetReadyDirectPVVolumes(dpclient, nc.Namespace, NineResourceName(nc.Name))
+		if err != nil {
+			return nil, err
+		}
+		components := make(map[string]*ComponentUsage)
+		pools := make(map[string]map[string]bool)
+		for _, volume := range volumes.Items {
+			component := usageComponent(nc.Name, volume.Labels[string(directpvv1beta1.PodNameLabelKey)])
+			cu, ok := components[component]
+			if !ok {
+				cu = &ComponentUsage{Cluster: nc.Name, Namespace: nc.Namespace, Component: component}
+				components[component] = cu
+				pools[component] = make(map[string]bool)
+			}
+			requested, ok := requests[nc.Namespace][volume.Name]
+			if !ok {
+				requested = volume.Status.TotalCapacity
+			}
+			cu.Volumes++
+			cu.Requested += requested
+			cu.Used += volume.Status.UsedCapacity
+			cu.Available += volume.Status.AvailableCapacity
+			if pool, ok := drivePools[string(volume.GetDriveID())]; ok {
+				pools[component][pool] = true
+				usedPools[pool] = true
+			}
+		}
+		for _, component := range SortedKeys(components) {
+			cu := components[component]
+			cu.UsedPercent = usagePercent(cu.Used, cu.Used+cu.Available)
+			cu.AboveThreshold = cu.UsedPercent >= c.threshold
+			cu.Pools = SortedKeys(pools[component])
+			report.Components = append(report.Components, *cu)
+		}
+	}
+
+	poolUsages := make(map[string]*StoragePoolUsage)
+	for _, drive := range drives.Items {
+		pool := drive.Labels[DefaultStoragePoolLabelKey]
+		if !usedPools[pool] {
+			continue
+		}
+		pu, ok := poolUsages[pool]
+		if !ok {
+			pu = &StoragePoolUsage{Pool: pool}
+			poolUsages[pool] = pu
+		}
+		pu.Drives++
+		pu.Total += drive.Status.TotalCapacity
+		pu.Allocated += drive.Status.AllocatedCapacity
+		pu.Free += drive.Status.FreeCapacity
+	}
+	for _, pool := range SortedKeys(poolUsages) {
+		pu := poolUsages[pool]
+		pu.AllocatedPercent = usagePercent(pu.Allocated, pu.Total)
+		report.Pools = append(report.Pools, *pu)
+	}
+	return report, nil
+}
+
+func formatBytes(size int64) string {
+	return resource.NewQuantity(size, resource.BinarySI).String()
+}
+
+func formatPercent(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', 1, 64) + "%"
+}
+
+func printUsageReport(report *UsageReport) {
+	flagged := 0
+	fmt.Printf(PrintFmtStrComponentUsage, "NINECLUSTER", "NAMESPACE", "COMPONENT", "VOLUMES", "REQUESTED", "USED", "AVAILABLE", "USE%", "POOLS", "ALERT")
+	for _, cu := range report.Components {
+		alert := ""
+		if cu.AboveThreshold {
+			alert = "above " + formatPercent(report.Threshold)
+			flagged++
+		}
+		fmt.Printf(PrintFmtStrComponentUsage, cu.Cluster, cu.Namespace, cu.Component, strconv.Itoa(cu.Volumes),
+			formatBytes(cu.Requested), formatBytes(cu.Used), formatBytes(cu.Available), formatPercent(cu.UsedPercent),
+			strings.Join(cu.Pools, ","), alert)
+	}
+	fmt.Println()
+	fmt.Printf(PrintFmtStrStoragePoolUsage, "STORAGEPOOL", "DRIVES", "TOTAL", "ALLOCATED", "FREE", "ALLOC%")
+	for _, pu := range report.Pools {
+		fmt.Printf(PrintFmtStrStoragePoolUsage, pu.Pool, strconv.Itoa(pu.Drives), formatBytes(pu.Total),
+			formatBytes(pu.Allocated), formatBytes(pu.Free), formatPercent(pu.AllocatedPercent))
+	}
+	if flagged != 0 {
+		fmt.Println()
+		fmt.Printf("Components above %s full: %d\n", formatPercent(report.Threshold), flagged)
+	}
+}
+
+// writeUsageCsv writes the components and the storage pools as two csv tables separated by an empty line,
+// the capacities are in bytes
+func writeUsageCsv(out io.Writer, report *UsageReport) error {
+	w := csv.NewWriter(out)
+	records := [][]string{{"ninecluster", "namespace", "component", "volumes", "requested", "used", "available",
+		"used_percent", "above_threshold", "pools"}}
+	for _, cu := range report.Components {
+		records = append(records, []string{cu.Cluster, cu.Namespace, cu.Component, strconv.Itoa(cu.Volumes),
+			strconv.FormatInt(cu.Requested, 10), strconv.FormatInt(cu.Used, 10), strconv.FormatInt(cu.Available, 10),
+			strconv.FormatFloat(cu.UsedPercent, 'f', 1, 64), strconv.FormatBool(cu.AboveThreshold), strings.Join(cu.Pools, ";")})
+	}
+	records = append(records, []string{})
+	records = append(records, []string{"storage_pool", "drives", "total", "allocated", "free", "allocated_percent"})
+	for _, pu := range report.Pools {
+		records = append(records, []string{pu.Pool, strconv.Itoa(pu.Drives), strconv.FormatInt(pu.Total, 10),
+			strconv.FormatInt(pu.Allocated, 10), strconv.FormatInt(pu.Free, 10), strconv.FormatFloat(pu.AllocatedPercent, 'f', 1, 64)})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+func (c *usageCmd) run() error {
+	report, err := c.Report()
+	if err != nil {
+		return err
+	}
+	switch c.output {
+	case OutputJson:
+		return printJson(report)
+	case OutputCsv:
+		return writeUsageCsv(os.Stdout, report)
+	default:
+		printUsageReport(report)
+	}
+	return nil
+}
@@ -24,17 +24,10 @@ func DisableHelp(cmd *cobra.Command) *cobra.Command {
 }
 
 func CreateIfNotExist(resource string, resourceType string, flags string) error {
-	var errput string
-	if flags == "" {
-		_, errput, err := runCommand("kubectl", "create", resourceType, resource)
-		if err != nil && !strings.Contains(errput, "exists") {
-			return err
-		}
-	} else {
-		_, errput, err := runCommand("kubectl", "create", resourceType, resource, flags)
-		if err != nil && !strings.Contains(errput, "exists") {
-			return err
-		}
+	args := kubectlArgs(append([]string{"create", resourceType, resource}, strings.Fields(flags)...)...)
+	_, errput, err := runCommand("kubectl", args...)
+	if err != nil && !strings.Contains(errput, "exists") {
+		return err
 	}
 	if !strings.Contains(errput, "exists") {
 		fmt.Printf("Create %s %s successfully!\n", resourceType, resource)
@@ -88,3 +81,54 @@ func CheckValidClusterName(clustername string) error {
 	}
 	return nil
 }
+
+// DiffLines returns the line diff of the texts,the removed lines start with "-" and the added lines start with "+"
+func DiffLines(before string, after string) []string {
+	lines := func(text string) []string {
+		text = strings.TrimRight(text, "\n")
+		if text == "" {
+			return nil
+		}
+		return strings.Split(text, "\n")
+	}
+	a, b := lines(before), lines(after)
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	diff := make([]string, 0)
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, "+ "+b[j])
+	}
+	return diff
+}
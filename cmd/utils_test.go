@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", []string{"  a", "  b"}},
+		{"trailing newline ignored", "a\nb\n", "a\nb", []string{"  a", "  b"}},
+		{"added line", "a\nc\n", "a\nb\nc\n", []string{"  a", "+ b", "  c"}},
+		{"removed line", "a\nb\nc\n", "a\nc\n", []string{"  a", "- b", "  c"}},
+		{"changed line", "a\nb\nc\n", "a\nx\nc\n", []string{"  a", "- b", "+ x", "  c"}},
+		{"appended lines", "a\n", "a\nb\nc\n", []string{"  a", "+ b", "+ c"}},
+		{"removed tail", "a\nb\nc\n", "a\n", []string{"  a", "- b", "- c"}},
+		{"all changed", "a\nb\n", "x\ny\n", []string{"- a", "- b", "+ x", "+ y"}},
+		{"from empty", "", "a\n", []string{"+ a"}},
+		{"to empty", "a\n", "", []string{"- a"}},
+		{"both empty", "", "", []string{}},
+		{"moved line", "a\nb\nc\n", "b\nc\na\n", []string{"- a", "  b", "  c", "+ a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines(%q, %q) = %q, want %q", tt.before, tt.after, got, tt.want)
+			}
+		})
+	}
+}
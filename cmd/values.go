@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"strings"
+)
+
+// ChartValues holds the user values of the charts given by --values chart=file.yaml and --set chart.key=value,
+// the files are merged on top of the values generated by the nine and the sets are passed to the helm as they are
+type ChartValues struct {
+	files      map[string][]string
+	sets       map[string][]string
+	showValues bool
+}
+
+// NewChartValues parses the --values and --set flags,the charts are the chart names allowed
+func NewChartValues(valuesArgs []string, setArgs []string, showValues bool, charts map[string]string) (*ChartValues, error) {
+	v := &ChartValues{
+		files:      make(map[string][]string),
+		sets:       make(map[string][]string),
+		showValues: showValues,
+	}
+	for _, arg := range valuesArgs {
+		chart, file, found := strings.Cut(arg, "=")
+		if !found || chart == "" || file == "" {
+			return nil, fmt.Errorf("invalid --values %s,should be chart=file.yaml", arg)
+		}
+		if _, ok := charts[chart]; !ok {
+			return nil, fmt.Errorf("unknown chart %s in --values %s", chart, arg)
+		}
+		if _, err := os.Stat(file); err != nil {
+			return nil, err
+		}
+		v.files[chart] = append(v.files[chart], file)
+	}
+	for _, arg := range setArgs {
+		chart, kv, found := strings.Cut(arg, ".")
+		if !found || chart == "" || !strings.Contains(kv, "=") {
+			return nil, fmt.Errorf("invalid --set %s,should be chart.key=value", arg)
+		}
+		if _, ok := charts[chart]; !ok {
+			return nil, fmt.Errorf("unknown chart %s in --set %s", chart, arg)
+		}
+		v.sets[chart] = append(v.sets[chart], kv)
+	}
+	return v, nil
+}
+
+// ShowOnly returns true if the merged values should only be printed
+func (v *ChartValues) ShowOnly() bool {
+	return v != nil && v.showValues
+}
+
+func parseSetValue(value string, forceString bool) interface{} {
+	if forceString {
+		return value
+	}
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil && (value == "0" || !strings.HasPrefix(value, "0")) {
+		return i
+	}
+	return value
+}
+
+func setValue(values map[string]interface{}, key string, value interface{}) {
+	keys := strings.Split(key, ".")
+	current := values
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := current[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[k] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
+
+// unsetValue removes the value of the dotted key,the empty parents are removed too
+func unsetValue(values map[string]interface{}, key string) {
+	first, rest, found := strings.Cut(key, ".")
+	if !found {
+		delete(values, key)
+		return
+	}
+	next, ok := values[first].(map[string]interface{})
+	if !ok {
+		return
+	}
+	unsetValue(next, rest)
+	if len(next) == 0 {
+		delete(values, first)
+	}
+}
+
+func mergeValues(dst map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]interface{}); ok {
+			if dstMap, ok := dst[k].(map[string]interface{}); ok {
+				dst[k] = mergeValues(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// generatedValues turns the --set,--set-string and --set-file parameters generated by the nine into values,
+// the other parameters are returned as they are.Only a single key=value with a dotted key is supported,
+// the lists and the escapes of the helm syntax are refused
+func generatedValues(params []string) (map[string]interface{}, []string, error) {
+	values := make(map[string]interface{})
+	others := make([]string, 0)
+	for i := 0; i < len(params); i++ {
+		switch params[i] {
+		case "--set", "--set-string", "--set-file":
+			if i+1 >= len(params) {
+				return nil, nil, fmt.Errorf("missing value of %s", params[i])
+			}
+			key, value, found := strings.Cut(params[i+1], "=")
+			if !found || key == "" || strings.ContainsAny(key, "[]\\") || strings.HasPrefix(key, ".") ||
+				strings.HasSuffix(key, ".") || strings.Contains(key, "..") {
+				return nil, nil, fmt.Errorf("unsupported key in %s %s", params[i], params[i+1])
+			}
+			if params[i] != "--set-file" && strings.ContainsAny(value, ",\\{}") {
+				return nil, nil, fmt.Errorf("unsupported value in %s %s", params[i], params[i+1])
+			}
+			switch params[i] {
+			case "--set-file":
+				data, err := os.ReadFile(value)
+				if err != nil {
+					return nil, nil, err
+				}
+				setValue(values, key, string(data))
+			default:
+				setValue(values, key, parseSetValue(value, params[i] == "--set-string"))
+			}
+			i++
+		default:
+			others = append(others, params[i])
+		}
+	}
+	return values, others, nil
+}
+
+// MergedValues returns the generated values of the parameters with the user values of the chart merged on top
+func (v *ChartValues) MergedValues(chart string, params []string) (map[string]interface{}, []string, error) {
+	values, others, err := generatedValues(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if v == nil {
+		return values, others, nil
+	}
+	for _, file := range v.files[chart] {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		userValues := make(map[string]interface{})
+		if err := yaml.Unmarshal(data, &userValues); err != nil {
+			return nil, nil, fmt.Errorf("invalid values file %s,err:%v", file, err)
+		}
+		values = mergeValues(values, userValues)
+	}
+	return values, others, nil
+}
+
+// Apply merges the values of the release and returns the helm parameters using the merged values file followed
+// by the user sets,the cleanup removes the values file after the helm is done.In the show values mode the merged
+// values and the user sets are printed
+func (v *ChartValues) Apply(chart string, release string, params []string) ([]string, func(), error) {
+	cleanup := func() {}
+	values, others, err := v.MergedValues(chart, params)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	var sets []string
+	if v != nil {
+		for _, kv := range v.sets[chart] {
+			sets = append(sets, "--set", kv)
+		}
+	}
+	if v.ShowOnly() {
+		fmt.Printf("# Values of the release %s,chart %s\n", release, chart)
+		for _, kv := range v.sets[chart] {
+			fmt.Printf("# --set %s\n", kv)
+		}
+		fmt.Println(string(data))
+		return others, cleanup, nil
+	}
+	if len(values) == 0 {
+		return append(others, sets...), cleanup, nil
+	}
+	valuesFile, cleanup, err := WriteTempValuesFile(release, data)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	return append(append(others, "--values", valuesFile), sets...), cleanup, nil
+}
+
+// WriteTempValuesFile writes the values of the release to a private temporary file as they may contain secrets,
+// the cleanup removes the file
+func WriteTempValuesFile(release string, data []byte) (string, func(), error) {
+	f, err := os.CreateTemp("", fmt.Sprintf("kubectl-nine-%s-*-values.yaml", release))
+	if err != nil {
+		return "", func() {}, err
+	}
+	cleanup := func() { _ = os.Remove(f.Name()) }
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		cleanup()
+		return "", func() {}, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return f.Name(), cleanup, nil
+}
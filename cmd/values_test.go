@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseSetValue(t *testing.T) {
+	tests := []struct {
+		value       string
+		forceString bool
+		want        interface{}
+	}{
+		{"true", false, true},
+		{"false", false, false},
+		{"null", false, nil},
+		{"0", false, int64(0)},
+		{"42", false, int64(42)},
+		{"-3", false, int64(-3)},
+		{"0123", false, "0123"},
+		{"1.5", false, "1.5"},
+		{"True", false, "True"},
+		{"", false, ""},
+		{"abc", false, "abc"},
+		{"true", true, "true"},
+		{"42", true, "42"},
+	}
+	for _, tt := range tests {
+		if got := parseSetValue(tt.value, tt.forceString); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSetValue(%q, %v) = %#v, want %#v", tt.value, tt.forceString, got, tt.want)
+		}
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		key    string
+		value  interface{}
+		want   map[string]interface{}
+	}{
+		{"top level", map[string]interface{}{}, "a", int64(1), map[string]interface{}{"a": int64(1)}},
+		{"nested", map[string]interface{}{}, "a.b.c", "x",
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": "x"}}}},
+		{"keep siblings", map[string]interface{}{"a": map[string]interface{}{"b": 1}}, "a.c", 2,
+			map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}}},
+		{"override", map[string]interface{}{"a": map[string]interface{}{"b": 1}}, "a.b", 2,
+			map[string]interface{}{"a": map[string]interface{}{"b": 2}}},
+		{"replace scalar by map", map[string]interface{}{"a": "x"}, "a.b", 1,
+			map[string]interface{}{"a": map[string]interface{}{"b": 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setValue(tt.values, tt.key, tt.value)
+			if !reflect.DeepEqual(tt.values, tt.want) {
+				t.Errorf("setValue(%q) = %v, want %v", tt.key, tt.values, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratedValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     []string
+		wantValues map[string]interface{}
+		wantOthers []string
+		wantErr    bool
+	}{
+		{"no sets", []string{"--kubeconfig", "k"}, map[string]interface{}{}, []string{"--kubeconfig", "k"}, false},
+		{"set and set-string", []string{"--set", "a.b=1", "--set-string", "a.c=2", "--wait"},
+			map[string]interface{}{"a": map[string]interface{}{"b": int64(1), "c": "2"}}, []string{"--wait"}, false},
+		{"quoted value is kept", []string{"--set", `extraEnv.TALISMAN_ENABLED="False"`},
+			map[string]interface{}{"extraEnv": map[string]interface{}{"TALISMAN_ENABLED": `"False"`}}, []string{}, false},
+		{"value with equal sign", []string{"--set", "a=b=c"}, map[string]interface{}{"a": "b=c"}, []string{}, false},
+		{"missing value", []string{"--set"}, nil, nil, true},
+		{"missing equal sign", []string{"--set", "a"}, nil, nil, true},
+		{"list key", []string{"--set", "a[0]=1"}, nil, nil, true},
+		{"escaped key", []string{"--set", `a\.b=1`}, nil, nil, true},
+		{"empty key segment", []string{"--set", "a..b=1"}, nil, nil, true},
+		{"multiple values", []string{"--set", "a=1,b=2"}, nil, nil, true},
+		{"list value", []string{"--set", "a={1,2}"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, others, err := generatedValues(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generatedValues(%v) err = %v, wantErr %v", tt.params, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(values, tt.wantValues) || !reflect.DeepEqual(others, tt.wantOthers) {
+				t.Errorf("generatedValues(%v) = %v, %v, want %v, %v", tt.params, values, others, tt.wantValues, tt.wantOthers)
+			}
+		})
+	}
+}
+
+func TestChartValuesApply(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "values-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString("a:\n  b: 3\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+	charts := map[string]string{"c1": "0.1.0"}
+	v, err := NewChartValues([]string{"c1=" + file.Name()}, []string{"c1.a.list[0]=x,y", "c1.a.b=4"}, false, charts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, cleanup, err := v.Apply("c1", "r1", []string{"--kubeconfig", "k", "--set", "a.b=1", "--set", "a.c=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 8 || params[2] != "--values" || params[4] != "--set" || params[5] != "a.list[0]=x,y" ||
+		params[6] != "--set" || params[7] != "a.b=4" {
+		t.Fatalf("Apply() = %v, want the user sets passed through after the values file", params)
+	}
+	valuesFile := params[3]
+	info, err := os.Stat(valuesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("values file mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(valuesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a:\n  b: 3\n  c: 2\n" {
+		t.Errorf("values file = %q, want the user file merged on top of the generated values", data)
+	}
+	cleanup()
+	if _, err := os.Stat(valuesFile); !os.IsNotExist(err) {
+		t.Errorf("values file %s is not removed by the cleanup", valuesFile)
+	}
+
+	for _, args := range [][]string{{"c2.a=1"}, {"c1"}, {"c1.a"}} {
+		if _, err := NewChartValues(nil, args, false, charts); err == nil {
+			t.Errorf("NewChartValues(--set %v) should fail", args)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+#!/usr/bin/env bash
+# Pins the sha256 digests of the tool releases in cmd/toolchecksums.go from the checksum files published
+# with the upstream releases,run it whenever a version in the ToolManifest of cmd/bootstrap.go changes
+set -euo pipefail
+
+HELM_VERSION=${HELM_VERSION:-3.13.2}
+DIRECTPV_VERSION=${DIRECTPV_VERSION:-4.0.9}
+PLATFORMS=(linux/amd64 linux/arm64 darwin/amd64 darwin/arm64)
+OUT=$(cd "$(dirname "$0")/.." && pwd)/cmd/toolchecksums.go
+
+digest() {
+  local sum=$1
+  if ! [[ $sum =~ ^[0-9a-f]{64}$ ]]; then
+    echo "invalid sha256 digest '$sum'" >&2
+    exit 1
+  fi
+  echo "$sum"
+}
+
+directpv_sums=$(curl -fsSL "https://github.com/minio/directpv/releases/download/v${DIRECTPV_VERSION}/kubectl-directpv_${DIRECTPV_VERSION}_checksums.txt")
+
+{
+  echo "// Code generated by hack/pin-tool-checksums.sh. DO NOT EDIT."
+  echo
+  echo "package cmd"
+  echo
+  echo "// pinnedToolChecksums are the sha256 digests of the release artifacts of the ToolManifest keyed by os/arch"
+  echo "var pinnedToolChecksums = map[string]map[string]string{"
+  echo "	DefaultCMDHelm: {"
+  for p in "${PLATFORMS[@]}"; do
+    sum=$(curl -fsSL "https://get.helm.sh/helm-v${HELM_VERSION}-${p%/*}-${p#*/}.tar.gz.sha256sum" | awk '{print $1}')
+    echo "		\"$p\": \"$(digest "$sum")\","
+  done
+  echo "	},"
+  echo "	DefaultCMDDirectPV: {"
+  for p in "${PLATFORMS[@]}"; do
+    sum=$(awk -v f="kubectl-directpv_${DIRECTPV_VERSION}_${p%/*}_${p#*/}" '$2 == f || $2 == "*"f {print $1}' <<<"$directpv_sums")
+    echo "		\"$p\": \"$(digest "$sum")\","
+  done
+  echo "	},"
+  echo "}"
+} >"$OUT.tmp"
+gofmt -w "$OUT.tmp" 2>/dev/null || true
+mv "$OUT.tmp" "$OUT"
+echo "Pinned the tool checksums in $OUT"